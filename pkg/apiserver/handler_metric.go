@@ -3,6 +3,7 @@ package apiserver
 import (
 	"fmt"
 	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/tracing"
 	"github.com/gin-gonic/gin"
 	"sync/atomic"
 	"time"
@@ -62,7 +63,7 @@ func CreateMetricHandler(definition Definition) gin.HandlerFunc {
 		status := ginCtx.Writer.Status() / 100
 		statusMetric := fmt.Sprintf("ApiStatus%dXX", status)
 
-		writer.WriteOne(&mon.MetricDatum{
+		writer.WriteOne(tracing.AddMetricExemplar(ginCtx.Request.Context(), &mon.MetricDatum{
 			Priority:   mon.PriorityLow,
 			MetricName: statusMetric,
 			Dimensions: mon.MetricDimensions{
@@ -70,7 +71,7 @@ func CreateMetricHandler(definition Definition) gin.HandlerFunc {
 			},
 			Unit:  mon.UnitCount,
 			Value: 1.0,
-		})
+		}))
 	}
 }
 
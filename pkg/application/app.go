@@ -44,13 +44,19 @@ func Default(options ...Option) kernel.Kernel {
 		WithLoggerApplicationTag,
 		WithLoggerTagsFromConfig,
 		WithLoggerSettingsFromConfig,
+		WithLoggerAsyncOutput,
 		WithLoggerContextFieldsMessageEncoder(),
 		WithLoggerContextFieldsResolver(mon.ContextLoggerFieldsResolver),
+		WithLoggerRedaction,
 		WithLoggerMetricHook,
 		WithLoggerSentryHook(mon.SentryExtraConfigProvider, mon.SentryExtraEcsMetadataProvider),
+		WithLoggerStreamHook,
 		WithKernelSettingsFromConfig,
 		WithApiHealthCheck,
 		WithMetricDaemon,
+		WithProducerDaemon,
+		WithArchiveOutputs,
+		WithSchedulerOutputs,
 		WithTracing,
 	}
 
@@ -65,7 +65,7 @@ func (s *ConfigServer) Run(ctx context.Context) error {
 func (s *ConfigServer) handleRead(writer http.ResponseWriter, request *http.Request) {
 	var err error
 	var bytes []byte
-	var settings = s.config.AllSettings()
+	var settings = cfg.RedactedSettings(s.config)
 	var marshaller = yaml.Marshal
 
 	format := request.URL.Query().Get("format")
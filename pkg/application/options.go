@@ -2,6 +2,7 @@ package application
 
 import (
 	"flag"
+	"fmt"
 	"github.com/applike/gosoline/pkg/apiserver"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/clock"
@@ -27,11 +28,19 @@ type kernelSettings struct {
 	KillTimeout time.Duration `cfg:"killTimeout" default:"10s"`
 }
 
+type redactionSettings struct {
+	Patterns []string `cfg:"patterns"`
+}
+
 type loggerSettings struct {
-	Level           string                 `cfg:"level" default:"info" validate:"required"`
-	Format          string                 `cfg:"format" default:"console" validate:"required"`
-	TimestampFormat string                 `cfg:"timestamp_format" default:"15:04:05.000" validate:"required"`
-	Tags            map[string]interface{} `cfg:"tags"`
+	Level           string                     `cfg:"level" default:"info" validate:"required"`
+	ChannelLevels   map[string]interface{}     `cfg:"channel_levels"`
+	Filters         []mon.LoggerFilterSettings `cfg:"filters"`
+	Format          string                     `cfg:"format" default:"console" validate:"required"`
+	TimestampFormat string                     `cfg:"timestamp_format" default:"15:04:05.000" validate:"required"`
+	Tags            map[string]interface{}     `cfg:"tags"`
+	Async           mon.AsyncOutputSettings    `cfg:"async"`
+	Fingerprints    bool                       `cfg:"fingerprint_metrics" default:"false"`
 }
 
 func WithApiHealthCheck(app *App) {
@@ -41,6 +50,13 @@ func WithApiHealthCheck(app *App) {
 	})
 }
 
+func WithArchiveOutputs(app *App) {
+	app.addKernelOption(func(config cfg.GosoConf, kernel kernel.GosoKernel) error {
+		kernel.AddFactory(stream.ArchiveOutputModuleFactory)
+		return nil
+	})
+}
+
 func WithConfigEnvKeyPrefix(prefix string) Option {
 	return func(app *App) {
 		app.addConfigOption(func(config cfg.GosoConf) error {
@@ -161,6 +177,33 @@ func WithLoggerApplicationTag(app *App) {
 	})
 }
 
+func WithLoggerAsyncOutput(app *App) {
+	var asyncOutput *mon.AsyncOutput
+
+	app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
+		settings := &loggerSettings{}
+		config.UnmarshalKey("mon.logger", settings)
+
+		if !settings.Async.Enabled {
+			return nil
+		}
+
+		asyncOutput = mon.NewAsyncOutput(settings.Async)
+
+		return logger.Option(mon.WithAsyncOutput(asyncOutput))
+	})
+
+	app.addKernelOption(func(config cfg.GosoConf, kernel kernel.GosoKernel) error {
+		if asyncOutput == nil {
+			return nil
+		}
+
+		kernel.Add("logger-async-output", asyncOutput)
+
+		return nil
+	})
+}
+
 func WithLoggerContextFieldsMessageEncoder() Option {
 	return func(app *App) {
 		app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
@@ -204,7 +247,15 @@ func WithLoggerLevel(level string) Option {
 
 func WithLoggerMetricHook(app *App) {
 	app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
+		settings := &loggerSettings{}
+		config.UnmarshalKey("mon.logger", settings)
+
 		metricHook := mon.NewMetricHook()
+
+		if settings.Fingerprints {
+			metricHook = mon.NewMetricHookWithFingerprints()
+		}
+
 		return logger.Option(mon.WithHook(metricHook))
 	})
 }
@@ -217,6 +268,19 @@ func WithLoggerOutput(output io.Writer) Option {
 	}
 }
 
+func WithLoggerRedaction(app *App) {
+	app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
+		settings := &redactionSettings{}
+		config.UnmarshalKey("cfg.redaction", settings)
+
+		if err := config.Option(cfg.WithSensitivePatterns(settings.Patterns...)); err != nil {
+			return fmt.Errorf("can not add sensitive patterns to config: %w", err)
+		}
+
+		return logger.Option(mon.WithRedactor(cfg.GetRedactor(config)))
+	})
+}
+
 func WithLoggerSentryHook(extraProvider ...mon.SentryExtraProvider) Option {
 	return func(app *App) {
 		app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
@@ -247,12 +311,44 @@ func WithLoggerSettingsFromConfig(app *App) {
 			mon.WithLevel(settings.Level),
 			mon.WithFormat(settings.Format),
 			mon.WithTimestampFormat(settings.TimestampFormat),
+			mon.WithFilterSettings(settings.Filters...),
+		}
+
+		for channel, level := range settings.ChannelLevels {
+			loggerOptions = append(loggerOptions, mon.WithChannelLevel(channel, fmt.Sprint(level)))
 		}
 
 		return logger.Option(loggerOptions...)
 	})
 }
 
+func WithLoggerStreamHook(app *App) {
+	var hook *stream.OutputLoggerHook
+
+	app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
+		settings := &stream.OutputLoggerHookSettings{}
+		config.UnmarshalKey("mon.logger.stream_hook", settings)
+
+		if !settings.Enabled {
+			return nil
+		}
+
+		hook = stream.NewOutputLoggerHook(settings)
+
+		return logger.Option(mon.WithHook(hook))
+	})
+
+	app.addKernelOption(func(config cfg.GosoConf, kernel kernel.GosoKernel) error {
+		if hook == nil {
+			return nil
+		}
+
+		kernel.Add("logger-stream-hook", hook)
+
+		return nil
+	})
+}
+
 func WithLoggerTagsFromConfig(app *App) {
 	app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
 		settings := &loggerSettings{}
@@ -269,6 +365,20 @@ func WithMetricDaemon(app *App) {
 	})
 }
 
+func WithProducerDaemon(app *App) {
+	app.addKernelOption(func(config cfg.GosoConf, kernel kernel.GosoKernel) error {
+		kernel.AddFactory(stream.ProducerDaemonFactory)
+		return nil
+	})
+}
+
+func WithSchedulerOutputs(app *App) {
+	app.addKernelOption(func(config cfg.GosoConf, kernel kernel.GosoKernel) error {
+		kernel.AddFactory(stream.SchedulerOutputModuleFactory)
+		return nil
+	})
+}
+
 func WithTracing(app *App) {
 	app.addLoggerOption(func(config cfg.GosoConf, logger mon.GosoLog) error {
 		tracingHook := tracing.NewLoggerErrorHook()
@@ -49,6 +49,7 @@ type config struct {
 	lookupEnv      LookupEnv
 	errorHandlers  []ErrorHandler
 	sanitizers     []Sanitizer
+	redactor       *Redactor
 	settings       *Map
 	envKeyPrefix   string
 	envKeyReplacer *strings.Replacer
@@ -65,6 +66,7 @@ func NewWithInterfaces(lookupEnv LookupEnv) GosoConf {
 		lookupEnv:     lookupEnv,
 		errorHandlers: []ErrorHandler{defaultErrorHandler},
 		sanitizers:    make([]Sanitizer, 0),
+		redactor:      NewDefaultRedactor(),
 		settings:      NewMap(),
 	}
 
@@ -505,6 +507,7 @@ func (c *config) mergeMsi(prefix string, settings map[string]interface{}, option
 }
 
 func (c *config) mergeStruct(prefix string, settings interface{}, options ...MapOption) error {
+	c.redactor.AddStruct(prefix, settings)
 	ms := c.buildMapStruct(settings)
 	msi, err := ms.Read()
 
@@ -608,6 +611,7 @@ func (c *config) unmarshalSlice(key string, output interface{}, defaults []Unmar
 
 func (c *config) unmarshalStruct(key string, output interface{}, additionalDefaults []UnmarshalDefaults) {
 	refl.InitializeMapsAndSlices(output)
+	c.redactor.AddStruct(key, output)
 	finalSettings := make(map[string]interface{})
 
 	ms := c.buildMapStruct(output)
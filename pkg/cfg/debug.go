@@ -8,7 +8,7 @@ import (
 )
 
 func DebugConfig(config Config, logger Logger) error {
-	settings := config.AllSettings()
+	settings := RedactedSettings(config)
 	flattened, err := flatten.Flatten(settings, "", flatten.DotStyle)
 
 	if err != nil {
@@ -65,6 +65,12 @@ func WithErrorHandlers(handlers ...ErrorHandler) Option {
 	}
 }
 
+func WithSensitivePatterns(patterns ...string) Option {
+	return func(cfg *config) error {
+		return cfg.redactor.AddPatterns(patterns...)
+	}
+}
+
 func WithSanitizers(sanitizer ...Sanitizer) Option {
 	return func(cfg *config) error {
 		cfg.sanitizers = append(cfg.sanitizers, sanitizer...)
@@ -0,0 +1,288 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RedactedValue = "***"
+	SensitiveTag  = "sensitive"
+
+	// redactionCacheSize limits the number of cached keys, as log fields might use arbitrary keys
+	redactionCacheSize = 4096
+)
+
+// DefaultSensitivePatterns match every key whose last segment looks like it contains a secret. A token is only
+// sensitive if it is a credential, keys like next_token or token_count are not.
+var DefaultSensitivePatterns = []string{
+	`(?i)(^|\.)[^.]*(password|passwd|secret|api_?key|private_?key|access_?key)[^.]*$`,
+	`(?i)(^|\.)((access|api|auth|bearer|client|id|oauth|refresh|session)_?)?token$`,
+}
+
+// A Redactor masks sensitive values in settings or log fields. A value is sensitive if
+// its key (the path of the value joined by dots) matches one of the patterns or was
+// explicitly marked as sensitive, e.g. by the sensitive struct tag of a settings struct.
+// The result for a key is cached, as the redactor is applied to the fields of every log line.
+type Redactor struct {
+	lck      sync.RWMutex
+	patterns []*regexp.Regexp
+	keys     map[string]bool
+	cache    map[string]bool
+}
+
+func NewRedactor(patterns ...string) (*Redactor, error) {
+	r := &Redactor{
+		patterns: make([]*regexp.Regexp, 0, len(patterns)),
+		keys:     make(map[string]bool),
+		cache:    make(map[string]bool),
+	}
+
+	if err := r.AddPatterns(patterns...); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func NewDefaultRedactor() *Redactor {
+	r, err := NewRedactor(DefaultSensitivePatterns...)
+
+	if err != nil {
+		panic(fmt.Errorf("can not compile default sensitive patterns: %w", err))
+	}
+
+	return r
+}
+
+// GetRedactor returns the redactor of the config. If the config does not provide one,
+// a redactor using the default patterns is returned.
+func GetRedactor(cfg Config) *Redactor {
+	if c, ok := cfg.(*config); ok {
+		return c.redactor
+	}
+
+	return NewDefaultRedactor()
+}
+
+// RedactedSettings returns all settings of the config with every sensitive value masked.
+func RedactedSettings(cfg Config) map[string]interface{} {
+	return GetRedactor(cfg).Redact(cfg.AllSettings())
+}
+
+func (r *Redactor) AddPatterns(patterns ...string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		rx, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return fmt.Errorf("can not compile sensitive pattern %s: %w", pattern, err)
+		}
+
+		compiled = append(compiled, rx)
+	}
+
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	r.patterns = append(r.patterns, compiled...)
+	r.cache = make(map[string]bool)
+
+	return nil
+}
+
+// AddKeys marks the keys as sensitive. The cached results are only reset if one of the keys is new, as the
+// keys of a settings struct are added again every time it is unmarshalled.
+func (r *Redactor) AddKeys(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	added := false
+
+	for _, key := range keys {
+		key = normalizeRedactionKey(key)
+
+		if r.keys[key] {
+			continue
+		}
+
+		r.keys[key] = true
+		added = true
+	}
+
+	if added {
+		r.cache = make(map[string]bool)
+	}
+}
+
+// AddStruct marks every field of the settings struct which has the sensitive tag
+// set to true. The prefix is the key the struct is unmarshalled from.
+func (r *Redactor) AddStruct(prefix string, settings interface{}) {
+	st := reflect.TypeOf(settings)
+
+	for st != nil && st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+
+	if st == nil || st.Kind() != reflect.Struct {
+		return
+	}
+
+	r.AddKeys(sensitiveStructKeys(prefix, st)...)
+}
+
+func (r *Redactor) IsSensitive(key string) bool {
+	key = normalizeRedactionKey(key)
+
+	r.lck.RLock()
+	sensitive, ok := r.cache[key]
+
+	if !ok {
+		sensitive = r.matches(key)
+	}
+
+	r.lck.RUnlock()
+
+	if ok {
+		return sensitive
+	}
+
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	if len(r.cache) >= redactionCacheSize {
+		r.cache = make(map[string]bool)
+	}
+
+	r.cache[key] = sensitive
+
+	return sensitive
+}
+
+func (r *Redactor) matches(key string) bool {
+	if r.keys[key] {
+		return true
+	}
+
+	for _, rx := range r.patterns {
+		if rx.MatchString(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redact returns a deep copy of the settings with every sensitive value replaced by RedactedValue.
+func (r *Redactor) Redact(settings map[string]interface{}) map[string]interface{} {
+	if len(settings) == 0 {
+		return settings
+	}
+
+	return r.redactMsi("", settings)
+}
+
+func (r *Redactor) redactMsi(prefix string, settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+
+	for k, v := range settings {
+		redacted[k] = r.redactValue(joinRedactionKey(prefix, k), v)
+	}
+
+	return redacted
+}
+
+func (r *Redactor) redactValue(key string, value interface{}) interface{} {
+	if r.IsSensitive(key) {
+		return RedactedValue
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.redactMsi(key, v)
+
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+
+		for i, elem := range v {
+			redacted[i] = r.redactValue(fmt.Sprintf("%s[%d]", key, i), elem)
+		}
+
+		return redacted
+
+	default:
+		return value
+	}
+}
+
+func sensitiveStructKeys(prefix string, st reflect.Type) []string {
+	keys := make([]string, 0)
+
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+
+		// skip unexported fields
+		if len(field.PkgPath) != 0 {
+			continue
+		}
+
+		fieldType := field.Type
+
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if fieldType.Kind() == reflect.Struct && field.Anonymous {
+			keys = append(keys, sensitiveStructKeys(prefix, fieldType)...)
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("cfg")
+
+		if !ok {
+			continue
+		}
+
+		key := joinRedactionKey(prefix, tag)
+
+		if field.Tag.Get(SensitiveTag) == "true" {
+			keys = append(keys, key)
+			continue
+		}
+
+		if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+			keys = append(keys, sensitiveStructKeys(key, fieldType)...)
+		}
+	}
+
+	return keys
+}
+
+var redactionIndexRegex = regexp.MustCompile(`\[\d+\]`)
+
+// normalizeRedactionKey removes slice indices, so a key marked as sensitive for
+// one element of a slice is sensitive for all of them.
+func normalizeRedactionKey(key string) string {
+	if strings.IndexByte(key, '[') == -1 {
+		return key
+	}
+
+	return redactionIndexRegex.ReplaceAllString(key, "")
+}
+
+func joinRedactionKey(prefix string, key string) string {
+	if prefix == "" || prefix == "." {
+		return key
+	}
+
+	return strings.Join([]string{prefix, key}, ".")
+}
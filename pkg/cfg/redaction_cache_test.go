@@ -0,0 +1,23 @@
+package cfg
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedactor_AddKeys_KeepsCache(t *testing.T) {
+	redactor := NewDefaultRedactor()
+	redactor.AddKeys("db.default.uri.dsn")
+
+	assert.False(t, redactor.IsSensitive("db.default.uri.host"))
+	assert.Len(t, redactor.cache, 1)
+
+	// no keys or known keys don't change any result, so the cache is kept
+	redactor.AddKeys()
+	redactor.AddKeys("db.default.uri.dsn")
+	assert.Len(t, redactor.cache, 1)
+
+	redactor.AddKeys("db.default.uri.host")
+	assert.Empty(t, redactor.cache)
+	assert.True(t, redactor.IsSensitive("db.default.uri.host"))
+}
@@ -0,0 +1,90 @@
+package cfg_test
+
+import (
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type redactionUri struct {
+	Host string `cfg:"host"`
+	Dsn  string `cfg:"dsn" sensitive:"true"`
+}
+
+type redactionSettings struct {
+	Name string       `cfg:"name"`
+	Uri  redactionUri `cfg:"uri"`
+}
+
+func TestRedactedSettings(t *testing.T) {
+	config := cfg.New()
+	err := config.Option(cfg.WithConfigMap(map[string]interface{}{
+		"db": map[string]interface{}{
+			"default": map[string]interface{}{
+				"name": "main",
+				"uri": map[string]interface{}{
+					"host":     "localhost",
+					"dsn":      "user:pass@localhost",
+					"password": "gosoline",
+				},
+			},
+		},
+		"api_key": "abc",
+		"clients": []interface{}{
+			map[string]interface{}{
+				"name":         "foo",
+				"client_token": "bar",
+			},
+		},
+		"custom": "value",
+	}), cfg.WithSensitivePatterns(`^custom$`))
+	assert.NoError(t, err)
+
+	settings := &redactionSettings{}
+	config.UnmarshalKey("db.default", settings)
+	assert.Equal(t, "user:pass@localhost", settings.Uri.Dsn, "unmarshalled settings should not be redacted")
+
+	assert.Equal(t, map[string]interface{}{
+		"db": map[string]interface{}{
+			"default": map[string]interface{}{
+				"name": "main",
+				"uri": map[string]interface{}{
+					"host":     "localhost",
+					"dsn":      cfg.RedactedValue,
+					"password": cfg.RedactedValue,
+				},
+			},
+		},
+		"api_key": cfg.RedactedValue,
+		"clients": []interface{}{
+			map[string]interface{}{
+				"name":         "foo",
+				"client_token": cfg.RedactedValue,
+			},
+		},
+		"custom": cfg.RedactedValue,
+	}, cfg.RedactedSettings(config))
+}
+
+func TestRedactor_IsSensitive(t *testing.T) {
+	redactor := cfg.NewDefaultRedactor()
+	redactor.AddKeys("stream.input[0].dsn")
+
+	assert.True(t, redactor.IsSensitive("db.default.uri.password"))
+	assert.True(t, redactor.IsSensitive("aws_secret_access_key"))
+	assert.True(t, redactor.IsSensitive("stream.input[3].dsn"))
+	assert.False(t, redactor.IsSensitive("password_policy.min_length"))
+	assert.False(t, redactor.IsSensitive("db.default.uri.host"))
+	assert.True(t, redactor.IsSensitive("github.access_token"))
+	assert.True(t, redactor.IsSensitive("token"))
+	assert.False(t, redactor.IsSensitive("next_token"))
+	assert.False(t, redactor.IsSensitive("token_count"))
+
+	// cached results are reset once new keys are marked as sensitive
+	assert.False(t, redactor.IsSensitive("db.default.uri.user"))
+	redactor.AddKeys("db.default.uri.user")
+	assert.True(t, redactor.IsSensitive("db.default.uri.user"))
+
+	err := redactor.AddPatterns("(")
+	assert.Error(t, err)
+}
@@ -15,7 +15,7 @@ type Uri struct {
 	Host     string `cfg:"host" validation:"required"`
 	Port     int    `cfg:"port" validation:"required"`
 	User     string `cfg:"user" validation:"required"`
-	Password string `cfg:"password" validation:"required"`
+	Password string `cfg:"password" validation:"required" sensitive:"true"`
 	Database string `cfg:"database" validation:"required"`
 }
 
@@ -5,6 +5,7 @@ import (
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mdl"
 	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/tracing"
 	"time"
 )
 
@@ -26,12 +27,12 @@ func NewMetricRepository(_ cfg.Config, _ mon.Logger, repo Repository) *metricRep
 func (r metricRepository) PutItem(ctx context.Context, qb PutItemBuilder, item interface{}) (*PutItemResult, error) {
 	start := time.Time{}
 	saved, err := r.Repository.PutItem(ctx, nil, item)
-	r.writeMetric(OpSave, err, start)
+	r.writeMetric(ctx, OpSave, err, start)
 
 	return saved, err
 }
 
-func (r metricRepository) writeMetric(op string, err error, start time.Time) {
+func (r metricRepository) writeMetric(ctx context.Context, op string, err error, start time.Time) {
 	latencyNano := time.Since(start)
 	modelId := r.Repository.GetModelId()
 	metricName := MetricNameAccessSuccess
@@ -40,7 +41,7 @@ func (r metricRepository) writeMetric(op string, err error, start time.Time) {
 		metricName = MetricNameAccessFailure
 	}
 
-	r.metric.WriteOne(&mon.MetricDatum{
+	r.metric.WriteOne(tracing.AddMetricExemplar(ctx, &mon.MetricDatum{
 		Priority:   mon.PriorityHigh,
 		Timestamp:  time.Now(),
 		MetricName: metricName,
@@ -50,7 +51,7 @@ func (r metricRepository) writeMetric(op string, err error, start time.Time) {
 		},
 		Unit:  mon.UnitCount,
 		Value: 1.0,
-	})
+	}))
 
 	latencyMillisecond := float64(latencyNano) / float64(time.Millisecond)
 
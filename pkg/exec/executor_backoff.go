@@ -18,6 +18,22 @@ type BackoffSettings struct {
 	MaxElapsedTime      time.Duration `cfg:"max_elapsed_time" default:"15m"`
 }
 
+// NewExponentialBackOff creates an exponential backoff following the settings. A blocking backoff never stops retrying.
+func NewExponentialBackOff(settings *BackoffSettings) *backoff.ExponentialBackOff {
+	backoffConfig := backoff.NewExponentialBackOff()
+	backoffConfig.InitialInterval = settings.InitialInterval
+	backoffConfig.RandomizationFactor = settings.RandomizationFactor
+	backoffConfig.Multiplier = settings.Multiplier
+	backoffConfig.MaxInterval = settings.MaxInterval
+	backoffConfig.MaxElapsedTime = settings.MaxElapsedTime
+
+	if settings.Blocking {
+		backoffConfig.MaxElapsedTime = 0
+	}
+
+	return backoffConfig
+}
+
 type BackoffExecutor struct {
 	logger   mon.Logger
 	res      *ExecutableResource
@@ -44,17 +60,7 @@ func (e *BackoffExecutor) Execute(ctx context.Context, f Executable) (interface{
 	var err error
 	var errType ErrorType
 
-	backoffConfig := backoff.NewExponentialBackOff()
-	backoffConfig.InitialInterval = e.settings.InitialInterval
-	backoffConfig.RandomizationFactor = e.settings.RandomizationFactor
-	backoffConfig.Multiplier = e.settings.Multiplier
-	backoffConfig.MaxInterval = e.settings.MaxInterval
-	backoffConfig.MaxElapsedTime = e.settings.MaxElapsedTime
-
-	if e.settings.Blocking {
-		backoffConfig.MaxElapsedTime = 0
-	}
-
+	backoffConfig := NewExponentialBackOff(e.settings)
 	backoffCtx := backoff.WithContext(backoffConfig, ctx)
 
 	retries := 0
@@ -9,11 +9,14 @@ import (
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/refl"
 	"sort"
+	"time"
 )
 
 type DdbItem struct {
 	Key   string `json:"key" ddb:"key=hash"`
 	Value string `json:"value"`
+	// Ttl is the unix timestamp after which ddb deletes the item, it is only set if the store has a ttl
+	Ttl int64 `json:"ttl,omitempty" ddb:"ttl=enabled"`
 }
 
 type DdbKvStore struct {
@@ -69,7 +72,7 @@ func (s *DdbKvStore) Contains(ctx context.Context, key interface{}) (bool, error
 		return false, fmt.Errorf("can not check if ddb store contains the key %s: %w", keyStr, err)
 	}
 
-	return res.IsFound, nil
+	return res.IsFound && !s.isExpired(item), nil
 }
 
 func (s *DdbKvStore) Get(ctx context.Context, key interface{}, value interface{}) (bool, error) {
@@ -88,7 +91,7 @@ func (s *DdbKvStore) Get(ctx context.Context, key interface{}, value interface{}
 		return false, fmt.Errorf("can not get item %s from ddb store: %w", keyStr, err)
 	}
 
-	if !res.IsFound {
+	if !res.IsFound || s.isExpired(item) {
 		return false, nil
 	}
 
@@ -136,6 +139,10 @@ func (s *DdbKvStore) getChunk(ctx context.Context, resultMap *refl.Map, keys []i
 	found := make(map[string]bool)
 
 	for i := 0; i < len(items); i++ {
+		if s.isExpired(&items[i]) {
+			continue
+		}
+
 		found[items[i].Key] = true
 
 		element := resultMap.NewElement()
@@ -178,6 +185,7 @@ func (s *DdbKvStore) Put(ctx context.Context, key interface{}, value interface{}
 	item := &DdbItem{
 		Key:   keyStr,
 		Value: string(bytes),
+		Ttl:   s.ttl(),
 	}
 
 	_, err = s.repository.PutItem(ctx, nil, item)
@@ -226,6 +234,7 @@ func (s *DdbKvStore) PutBatch(ctx context.Context, values interface{}) error {
 		item := DdbItem{
 			Key:   keyStr,
 			Value: string(bytes),
+			Ttl:   s.ttl(),
 		}
 
 		items = append(items, item)
@@ -239,3 +248,16 @@ func (s *DdbKvStore) PutBatch(ctx context.Context, values interface{}) error {
 
 	return nil
 }
+
+func (s *DdbKvStore) ttl() int64 {
+	if s.settings.Ttl <= 0 {
+		return 0
+	}
+
+	return time.Now().Add(s.settings.Ttl).Unix()
+}
+
+// isExpired checks the ttl of the item as ddb deletes expired items only eventually.
+func (s *DdbKvStore) isExpired(item *DdbItem) bool {
+	return item.Ttl > 0 && item.Ttl <= time.Now().Unix()
+}
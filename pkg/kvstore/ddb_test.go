@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func TestDdbKvStore_Contains(t *testing.T) {
@@ -236,7 +237,94 @@ func TestDdbKvStore_PutBatch(t *testing.T) {
 	repo.AssertExpectations(t)
 }
 
+func TestDdbKvStore_PutWithTtl(t *testing.T) {
+	store, repo := buildTestableDdbStoreWithTtl(time.Hour)
+
+	before := time.Now().Add(time.Hour).Unix()
+
+	repo.On("PutItem", mock.Anything, nil, mock.AnythingOfType("*kvstore.DdbItem")).Return(nil, nil)
+	repo.On("BatchPutItems", mock.Anything, mock.AnythingOfType("[]kvstore.DdbItem")).Return(nil, nil)
+
+	err := store.Put(context.Background(), "foo", &Item{Id: "foo", Body: "bar"})
+	assert.NoError(t, err)
+
+	err = store.PutBatch(context.Background(), map[string]Item{"fuu": {Id: "fuu", Body: "baz"}})
+	assert.NoError(t, err)
+
+	after := time.Now().Add(time.Hour).Unix()
+
+	item := repo.Calls[0].Arguments.Get(2).(*kvstore.DdbItem)
+	assert.True(t, item.Ttl >= before && item.Ttl <= after, "the ttl should expire an hour after the put")
+
+	items := repo.Calls[1].Arguments.Get(1).([]kvstore.DdbItem)
+	assert.Len(t, items, 1)
+	assert.True(t, items[0].Ttl >= before && items[0].Ttl <= after, "the ttl should expire an hour after the put")
+
+	repo.AssertExpectations(t)
+}
+
+func TestDdbKvStore_Expired(t *testing.T) {
+	store, repo := buildTestableDdbStoreWithTtl(time.Hour)
+
+	expired := time.Now().Add(-time.Minute).Unix()
+	valid := time.Now().Add(time.Minute).Unix()
+
+	builder := new(ddbMocks.GetItemBuilder)
+	builder.On("WithHash", "foo").Return(builder)
+
+	repo.On("GetItemBuilder").Return(builder)
+	repo.On("GetItem", mock.Anything, builder, mock.AnythingOfType("*kvstore.DdbItem")).Run(func(args mock.Arguments) {
+		ddbItem := args[2].(*kvstore.DdbItem)
+		ddbItem.Key = "foo"
+		ddbItem.Value = `{"id":"foo","body":"bar"}`
+		ddbItem.Ttl = expired
+	}).Return(&ddb.GetItemResult{
+		IsFound: true,
+	}, nil).Twice()
+
+	// ddb deletes expired items only eventually, so they can still be returned
+	exists, err := store.Contains(context.Background(), "foo")
+	assert.NoError(t, err)
+	assert.False(t, exists, "an expired item should not be contained")
+
+	found, err := store.Get(context.Background(), "foo", &Item{})
+	assert.NoError(t, err)
+	assert.False(t, found, "an expired item should not be found")
+
+	batchBuilder := new(ddbMocks.BatchGetItemsBuilder)
+	batchBuilder.On("WithHashKeys", []string{"foo", "fuu"}).Return(batchBuilder)
+
+	repo.On("BatchGetItemsBuilder").Return(batchBuilder)
+	repo.On("BatchGetItems", mock.Anything, batchBuilder, mock.AnythingOfType("*[]kvstore.DdbItem")).Run(func(args mock.Arguments) {
+		items := args[2].(*[]kvstore.DdbItem)
+		*items = append(*items, kvstore.DdbItem{
+			Key:   "foo",
+			Value: `{"id":"foo","body":"bar"}`,
+			Ttl:   expired,
+		}, kvstore.DdbItem{
+			Key:   "fuu",
+			Value: `{"id":"fuu","body":"baz"}`,
+			Ttl:   valid,
+		})
+	}).Return(nil, nil)
+
+	result := make(map[string]Item)
+	missing, err := store.GetBatch(context.Background(), []string{"foo", "fuu"}, result)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"foo"}, missing, "the expired item should be missing")
+	assert.Equal(t, map[string]Item{"fuu": {Id: "fuu", Body: "baz"}}, result)
+
+	builder.AssertExpectations(t)
+	batchBuilder.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
 func buildTestableDdbStore() (*kvstore.DdbKvStore, *ddbMocks.Repository) {
+	return buildTestableDdbStoreWithTtl(0)
+}
+
+func buildTestableDdbStoreWithTtl(ttl time.Duration) (*kvstore.DdbKvStore, *ddbMocks.Repository) {
 	repository := new(ddbMocks.Repository)
 
 	store := kvstore.NewDdbKvStoreWithInterfaces(repository, &kvstore.Settings{
@@ -248,6 +336,7 @@ func buildTestableDdbStore() (*kvstore.DdbKvStore, *ddbMocks.Repository) {
 		},
 		Name:      "test",
 		BatchSize: 100,
+		Ttl:       ttl,
 	})
 
 	return store, repository
@@ -0,0 +1,39 @@
+package mon
+
+import (
+	"errors"
+	"fmt"
+	pkgErrors "github.com/pkg/errors"
+	"hash/fnv"
+)
+
+// ErrorFingerprint builds a stable identifier for an error log line from its channel,
+// the unformatted message and the type of the root cause of the error. It is short
+// enough to be used as a metric dimension.
+func ErrorFingerprint(channel string, msg string, err error) string {
+	hash := fnv.New64a()
+
+	_, _ = hash.Write([]byte(channel))
+	_, _ = hash.Write([]byte{0})
+	_, _ = hash.Write([]byte(msg))
+
+	if err != nil {
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write([]byte(fmt.Sprintf("%T", rootCause(err))))
+	}
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+func rootCause(err error) error {
+	for {
+		cause := pkgErrors.Cause(err)
+
+		if unwrapped := errors.Unwrap(cause); unwrapped != nil {
+			err = unwrapped
+			continue
+		}
+
+		return cause
+	}
+}
@@ -3,6 +3,7 @@ package mon
 import (
 	"context"
 	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/jonboulle/clockwork"
 	"io"
 	"os"
@@ -31,7 +32,8 @@ var levels = map[string]int{
 	Panic: 6,
 }
 
-func levelPriority(level string) int {
+// LevelPriority returns the numeric priority of a level, more severe levels have a higher priority.
+func LevelPriority(level string) int {
 	return levels[level]
 }
 
@@ -95,8 +97,11 @@ type logger struct {
 	outputLck   *sync.Mutex
 	ctxResolver []ContextFieldsResolver
 	hooks       []LoggerHook
+	filters     []LoggerFilter
+	redactor    *cfg.Redactor
 
 	level           int
+	channelLevels   map[string]int
 	format          string
 	timestampFormat string
 
@@ -114,7 +119,9 @@ func NewLoggerWithInterfaces(clock clockwork.Clock, out io.Writer) *logger {
 		outputLck:       &sync.Mutex{},
 		ctxResolver:     make([]ContextFieldsResolver, 0),
 		hooks:           make([]LoggerHook, 0),
-		level:           levelPriority(Info),
+		filters:         make([]LoggerFilter, 0),
+		level:           LevelPriority(Info),
+		channelLevels:   make(map[string]int),
 		format:          FormatConsole,
 		timestampFormat: "15:04:05.000",
 		data: Metadata{
@@ -135,7 +142,10 @@ func (l *logger) copy() *logger {
 		output:          l.output,
 		ctxResolver:     l.ctxResolver,
 		hooks:           l.hooks,
+		filters:         l.filters,
+		redactor:        l.redactor,
 		level:           l.level,
+		channelLevels:   l.channelLevels,
 		format:          l.format,
 		timestampFormat: l.timestampFormat,
 		data:            l.data,
@@ -191,7 +201,7 @@ func (l *logger) Infof(msg string, args ...interface{}) {
 }
 
 func (l *logger) Debug(args ...interface{}) {
-	if l.level > levels[Debug] {
+	if l.minLevel() > levels[Debug] {
 		return
 	}
 
@@ -199,7 +209,7 @@ func (l *logger) Debug(args ...interface{}) {
 }
 
 func (l *logger) Debugf(msg string, args ...interface{}) {
-	if l.level > levels[Debug] {
+	if l.minLevel() > levels[Debug] {
 		return
 	}
 
@@ -215,49 +225,63 @@ func (l *logger) Warnf(msg string, args ...interface{}) {
 }
 
 func (l *logger) Error(err error, msg string) {
-	l.logError(Error, err, msg)
+	l.logError(Error, err, msg, msg)
 }
 
 func (l *logger) Errorf(err error, msg string, args ...interface{}) {
-	l.logError(Error, err, fmt.Sprintf(msg, args...))
+	l.logError(Error, err, msg, fmt.Sprintf(msg, args...))
 }
 
 func (l *logger) Fatal(err error, msg string) {
-	l.logError(Fatal, err, msg)
+	l.logError(Fatal, err, msg, msg)
 	os.Exit(1)
 }
 
 func (l *logger) Fatalf(err error, msg string, args ...interface{}) {
-	l.logError(Fatal, err, fmt.Sprintf(msg, args...))
+	l.logError(Fatal, err, msg, fmt.Sprintf(msg, args...))
 	os.Exit(1)
 }
 
 func (l *logger) Panic(err error, msg string) {
-	l.logError(Panic, err, msg)
+	l.logError(Panic, err, msg, msg)
 	panic(err)
 }
 
 func (l *logger) Panicf(err error, msg string, args ...interface{}) {
-	l.logError(Panic, err, fmt.Sprintf(msg, args...))
+	l.logError(Panic, err, msg, fmt.Sprintf(msg, args...))
 	panic(err)
 }
 
-func (l *logger) logError(level string, err error, msg string) {
+// the fingerprint is built from the unformatted message, so it stays stable
+// for every occurrence of the same error regardless of the arguments
+func (l *logger) logError(level string, err error, template string, msg string) {
 	l.log(level, msg, err, Fields{
-		"stacktrace": GetStackTrace(1),
+		"stacktrace":  GetStackTrace(1),
+		"fingerprint": ErrorFingerprint(l.data.Channel, template, err),
 	})
 }
 
 func (l *logger) log(level string, msg string, logErr error, fields Fields) {
 	levelNo := levels[level]
 
-	if levelNo < l.level {
+	if levelNo < l.minLevel() {
 		return
 	}
 
 	cpyData := l.data
 	cpyData.Fields = mergeMapStringInterface(cpyData.Fields, fields)
 
+	for _, f := range l.filters {
+		if !f(level, msg, &cpyData) {
+			return
+		}
+	}
+
+	if l.redactor != nil {
+		cpyData.Fields = l.redactor.Redact(cpyData.Fields)
+		cpyData.ContextFields = l.redactor.Redact(cpyData.ContextFields)
+	}
+
 	for _, h := range l.hooks {
 		if err := h.Fire(level, msg, logErr, &cpyData); err != nil {
 			l.err(err)
@@ -271,7 +295,15 @@ func (l *logger) log(level string, msg string, logErr error, fields Fields) {
 		_, _ = fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
 	}
 
-	l.write(buffer)
+	l.write(level, buffer)
+}
+
+func (l *logger) minLevel() int {
+	if level, ok := l.channelLevels[l.data.Channel]; ok {
+		return level
+	}
+
+	return l.level
 }
 
 func (l *logger) err(err error) {
@@ -282,14 +314,20 @@ func (l *logger) err(err error) {
 		_, _ = fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
 	}
 
-	l.write(buffer)
+	l.write(Error, buffer)
 }
 
-func (l *logger) write(buffer []byte) {
+func (l *logger) write(level string, buffer []byte) {
 	l.outputLck.Lock()
 	defer l.outputLck.Unlock()
 
-	_, err := l.output.Write(buffer)
+	var err error
+
+	if lw, ok := l.output.(LevelWriter); ok {
+		_, err = lw.WriteLevel(level, buffer)
+	} else {
+		_, err = l.output.Write(buffer)
+	}
 
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
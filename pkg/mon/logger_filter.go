@@ -0,0 +1,93 @@
+package mon
+
+import (
+	"fmt"
+)
+
+const (
+	FilterActionDrop = "drop"
+	FilterActionKeep = "keep"
+
+	FilterSourceFields = "fields"
+	FilterSourceTags   = "tags"
+)
+
+// A LoggerFilter decides if a log entry should be written. It is evaluated
+// before any hook or formatter is run, so dropped entries don't cost any encoding time.
+type LoggerFilter func(level string, msg string, data *Metadata) bool
+
+type LoggerFilterSettings struct {
+	Action  string   `cfg:"action" default:"drop" validate:"oneof=drop keep"`
+	Channel string   `cfg:"channel"`
+	Source  string   `cfg:"source" default:"fields" validate:"oneof=fields tags"`
+	Key     string   `cfg:"key" validate:"required"`
+	Values  []string `cfg:"values"`
+}
+
+// NewLoggerFilter creates a filter from the given settings. A drop filter removes every
+// entry matching the rule, a keep filter removes every entry not matching it. If a
+// channel is set, entries of other channels are not affected by the rule. Without
+// values, the rule matches if the key is present at all.
+func NewLoggerFilter(settings LoggerFilterSettings) (LoggerFilter, error) {
+	if settings.Key == "" {
+		return nil, fmt.Errorf("logger filter needs a key to match on")
+	}
+
+	switch settings.Source {
+	case "", FilterSourceFields, FilterSourceTags:
+	default:
+		return nil, fmt.Errorf("unknown logger filter source: %s", settings.Source)
+	}
+
+	var keep bool
+
+	switch settings.Action {
+	case "", FilterActionDrop:
+		keep = false
+	case FilterActionKeep:
+		keep = true
+	default:
+		return nil, fmt.Errorf("unknown logger filter action: %s", settings.Action)
+	}
+
+	return func(_ string, _ string, data *Metadata) bool {
+		if settings.Channel != "" && settings.Channel != data.Channel {
+			return true
+		}
+
+		matches := filterMatches(settings, data)
+
+		return matches == keep
+	}, nil
+}
+
+func filterMatches(settings LoggerFilterSettings, data *Metadata) bool {
+	var values map[string]interface{}
+
+	switch settings.Source {
+	case FilterSourceTags:
+		values = data.Tags
+	default:
+		values = data.Fields
+	}
+
+	value, ok := values[settings.Key]
+
+	if !ok {
+		return false
+	}
+
+	if len(settings.Values) == 0 {
+		return true
+	}
+
+	str := fmt.Sprint(value)
+
+	for _, expected := range settings.Values {
+		if str == expected {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,85 @@
+package mon_test
+
+import (
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLogger_WithChannelLevel(t *testing.T) {
+	logger, out := getLogger()
+	err := logger.Option(mon.WithChannelLevel("consumer", mon.Warn), mon.WithChannelLevel("callback", mon.Debug))
+	assert.NoError(t, err)
+
+	logger.WithChannel("consumer").Info("msg")
+	assert.Empty(t, out.String(), "info should be dropped for the consumer channel")
+
+	logger.WithChannel("consumer").Warn("msg")
+	assert.NotEmpty(t, out.String(), "warn should be logged for the consumer channel")
+
+	out.Reset()
+	logger.WithChannel("callback").Debug("msg")
+	assert.NotEmpty(t, out.String(), "debug should be logged for the callback channel")
+
+	out.Reset()
+	logger.Debug("msg")
+	assert.Empty(t, out.String(), "debug should be dropped for the default channel")
+
+	err = logger.Option(mon.WithChannelLevel("http", "verbose"))
+	assert.EqualError(t, err, "unknown logger level for channel http: verbose")
+}
+
+func TestLogger_WithFilterSettings(t *testing.T) {
+	logger, out := getLogger()
+	err := logger.Option(mon.WithTags(mon.Tags{"env": "test"}), mon.WithFilterSettings(
+		mon.LoggerFilterSettings{
+			Action:  mon.FilterActionDrop,
+			Channel: "http",
+			Key:     "path",
+			Values:  []string{"/health"},
+		},
+		mon.LoggerFilterSettings{
+			Action: mon.FilterActionKeep,
+			Source: mon.FilterSourceTags,
+			Key:    "env",
+		},
+	))
+	assert.NoError(t, err)
+
+	logger.WithChannel("http").WithFields(mon.Fields{"path": "/health"}).Info("msg")
+	assert.Empty(t, out.String(), "health checks should be dropped")
+
+	logger.WithChannel("http").WithFields(mon.Fields{"path": "/v0/foo"}).Info("msg")
+	assert.NotEmpty(t, out.String(), "other paths should be logged")
+
+	out.Reset()
+	logger.WithFields(mon.Fields{"path": "/health"}).Info("msg")
+	assert.NotEmpty(t, out.String(), "other channels should not be affected")
+}
+
+func TestLogger_WithFilterSettings_Keep(t *testing.T) {
+	logger, out := getLogger()
+	err := logger.Option(mon.WithFilterSettings(mon.LoggerFilterSettings{
+		Action: mon.FilterActionKeep,
+		Key:    "important",
+		Values: []string{"true"},
+	}))
+	assert.NoError(t, err)
+
+	logger.Info("msg")
+	assert.Empty(t, out.String(), "entries without the field should be dropped")
+
+	logger.WithFields(mon.Fields{"important": true}).Info("msg")
+	assert.NotEmpty(t, out.String(), "entries with the field should be logged")
+}
+
+func TestNewLoggerFilter_Invalid(t *testing.T) {
+	_, err := mon.NewLoggerFilter(mon.LoggerFilterSettings{})
+	assert.EqualError(t, err, "logger filter needs a key to match on")
+
+	_, err = mon.NewLoggerFilter(mon.LoggerFilterSettings{Key: "a", Action: "ignore"})
+	assert.EqualError(t, err, "unknown logger filter action: ignore")
+
+	_, err = mon.NewLoggerFilter(mon.LoggerFilterSettings{Key: "a", Source: "context"})
+	assert.EqualError(t, err, "unknown logger filter source: context")
+}
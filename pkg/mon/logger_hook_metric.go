@@ -1,34 +1,78 @@
 package mon
 
 type metricHook struct {
-	writer      MetricWriter
-	application string
+	writer       MetricWriter
+	application  string
+	fingerprints bool
 }
 
 func NewMetricHook() *metricHook {
 	defaults := getDefaultMetrics()
 	writer := NewMetricDaemonWriter(defaults...)
 
+	return NewMetricHookWithInterfaces(writer, false)
+}
+
+// NewMetricHookWithFingerprints additionally counts every error per fingerprint,
+// so dashboards can break down the error metric by the error causing it.
+func NewMetricHookWithFingerprints() *metricHook {
+	defaults := getDefaultMetrics()
+	writer := NewMetricDaemonWriter(defaults...)
+
+	return NewMetricHookWithInterfaces(writer, true)
+}
+
+func NewMetricHookWithInterfaces(writer MetricWriter, fingerprints bool) *metricHook {
 	return &metricHook{
-		writer: writer,
+		writer:       writer,
+		fingerprints: fingerprints,
 	}
 }
 
-func (h metricHook) Fire(level string, _ string, _ error, _ *Metadata) error {
+func (h metricHook) Fire(level string, _ string, _ error, data *Metadata) error {
 	if level != Warn && level != Error {
 		return nil
 	}
 
-	h.writer.WriteOne(&MetricDatum{
+	traceId := metricHookTraceId(data)
+
+	h.writer.WriteOne((&MetricDatum{
 		Priority:   PriorityHigh,
 		MetricName: level,
 		Unit:       UnitCount,
 		Value:      1.0,
-	})
+	}).AddExemplar(traceId))
+
+	fingerprint, ok := data.Fields["fingerprint"].(string)
+
+	if !h.fingerprints || !ok {
+		return nil
+	}
+
+	h.writer.WriteOne((&MetricDatum{
+		Priority:   PriorityHigh,
+		MetricName: level,
+		Dimensions: MetricDimensions{
+			"Fingerprint": fingerprint,
+		},
+		Unit:  UnitCount,
+		Value: 1.0,
+	}).AddExemplar(traceId))
 
 	return nil
 }
 
+// the trace id is available in the context fields if the tracing context fields resolver is used
+func metricHookTraceId(data *Metadata) string {
+	if data == nil {
+		return ""
+	}
+
+	traceId, _ := data.ContextFields["trace_id"].(string)
+
+	return traceId
+}
+
 func getDefaultMetrics() MetricData {
 	return MetricData{
 		{
@@ -0,0 +1,54 @@
+package mon_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestErrorFingerprint(t *testing.T) {
+	err := errors.New("connection refused")
+	wrapped := fmt.Errorf("can not connect: %w", err)
+
+	fingerprint := mon.ErrorFingerprint("db", "can not reach host %s", err)
+
+	assert.Len(t, fingerprint, 16)
+	assert.Equal(t, fingerprint, mon.ErrorFingerprint("db", "can not reach host %s", wrapped), "fingerprint should use the root cause")
+	assert.NotEqual(t, fingerprint, mon.ErrorFingerprint("http", "can not reach host %s", err), "fingerprint should depend on the channel")
+	assert.NotEqual(t, fingerprint, mon.ErrorFingerprint("db", "can not read from host %s", err), "fingerprint should depend on the message")
+}
+
+func TestMetricHook_Fire(t *testing.T) {
+	writer := new(monMocks.MetricWriter)
+	writer.On("WriteOne", &mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: mon.Error,
+		Unit:       mon.UnitCount,
+		Value:      1.0,
+		Exemplars:  []mon.MetricExemplar{{TraceId: "1-5e3d83c1-e6a0db584850d61342823d2d", Value: 1.0}},
+	}).Once()
+	writer.On("WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == mon.Error && len(datum.Dimensions["Fingerprint"]) == 16
+	})).Once()
+
+	logger, _ := getLogger()
+	err := logger.Option(
+		mon.WithHook(mon.NewMetricHookWithInterfaces(writer, true)),
+		mon.WithContextFieldsResolver(mon.ContextLoggerFieldsResolver),
+	)
+	assert.NoError(t, err)
+
+	ctx := mon.AppendLoggerContextField(context.Background(), mon.Fields{
+		"trace_id": "1-5e3d83c1-e6a0db584850d61342823d2d",
+	})
+
+	logger.WithContext(ctx).Errorf(errors.New("boom"), "something went wrong with %d items", 3)
+	logger.Info("not counted")
+
+	writer.AssertExpectations(t)
+}
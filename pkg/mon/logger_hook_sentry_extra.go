@@ -1,6 +1,9 @@
 package mon
 
-import "github.com/pkg/errors"
+import (
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/pkg/errors"
+)
 
 type ConfigProvider interface {
 	AllSettings() map[string]interface{}
@@ -10,6 +13,10 @@ type SentryExtraProvider func(config ConfigProvider, sentryHook *SentryHook) (*S
 
 func SentryExtraConfigProvider(config ConfigProvider, sentryHook *SentryHook) (*SentryHook, error) {
 	configValues := config.AllSettings()
+
+	if c, ok := config.(cfg.Config); ok {
+		configValues = cfg.RedactedSettings(c)
+	}
 	sentryHook = sentryHook.WithExtra(map[string]interface{}{
 		"config": configValues,
 	})
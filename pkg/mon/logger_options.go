@@ -2,11 +2,38 @@ package mon
 
 import (
 	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
 	"io"
 )
 
 type LoggerOption func(logger *logger) error
 
+// WithAsyncOutput makes the logger write through the given async output. The output
+// currently configured on the logger is used as the target of the async output.
+func WithAsyncOutput(output *AsyncOutput) LoggerOption {
+	return func(logger *logger) error {
+		if err := output.start(logger.output); err != nil {
+			return err
+		}
+
+		logger.output = output
+
+		return nil
+	}
+}
+
+func WithChannelLevel(channel string, level string) LoggerOption {
+	return func(logger *logger) error {
+		if _, ok := levels[level]; !ok {
+			return fmt.Errorf("unknown logger level for channel %s: %s", channel, level)
+		}
+
+		logger.channelLevels[channel] = LevelPriority(level)
+
+		return nil
+	}
+}
+
 func WithContextFieldsResolver(resolver ...ContextFieldsResolver) LoggerOption {
 	return func(logger *logger) error {
 		logger.ctxResolver = append(logger.ctxResolver, resolver...)
@@ -15,6 +42,30 @@ func WithContextFieldsResolver(resolver ...ContextFieldsResolver) LoggerOption {
 	}
 }
 
+func WithFilter(filter ...LoggerFilter) LoggerOption {
+	return func(logger *logger) error {
+		logger.filters = append(logger.filters, filter...)
+
+		return nil
+	}
+}
+
+func WithFilterSettings(settings ...LoggerFilterSettings) LoggerOption {
+	return func(logger *logger) error {
+		for _, s := range settings {
+			filter, err := NewLoggerFilter(s)
+
+			if err != nil {
+				return err
+			}
+
+			logger.filters = append(logger.filters, filter)
+		}
+
+		return nil
+	}
+}
+
 func WithFormat(format string) LoggerOption {
 	return func(logger *logger) error {
 		if _, ok := formatters[format]; !ok {
@@ -37,7 +88,7 @@ func WithHook(hook LoggerHook) LoggerOption {
 
 func WithLevel(level string) LoggerOption {
 	return func(logger *logger) error {
-		logger.level = levelPriority(level)
+		logger.level = LevelPriority(level)
 
 		return nil
 	}
@@ -51,6 +102,15 @@ func WithOutput(output io.Writer) LoggerOption {
 	}
 }
 
+// WithRedactor masks sensitive values in the fields and context fields of every log entry.
+func WithRedactor(redactor *cfg.Redactor) LoggerOption {
+	return func(logger *logger) error {
+		logger.redactor = redactor
+
+		return nil
+	}
+}
+
 func WithTags(tags map[string]interface{}) LoggerOption {
 	return func(logger *logger) error {
 		for k, v := range tags {
@@ -0,0 +1,315 @@
+package mon
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/kernel/common"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	AsyncDropPolicyBlock          = "block"
+	AsyncDropPolicyDropOldest     = "drop_oldest"
+	AsyncDropPolicyDropDebugFirst = "drop_debug_first"
+
+	metricNameLogLinesDropped = "LogLinesDropped"
+)
+
+// A LevelWriter is an output which wants to know the level of the log line it is writing.
+type LevelWriter interface {
+	WriteLevel(level string, p []byte) (n int, err error)
+}
+
+type AsyncOutputSettings struct {
+	Enabled    bool   `cfg:"enabled" default:"false"`
+	BufferSize int    `cfg:"buffer_size" default:"1000" validate:"min=1"`
+	DropPolicy string `cfg:"drop_policy" default:"block" validate:"oneof=block drop_oldest drop_debug_first"`
+}
+
+type asyncEntry struct {
+	level  int
+	buffer []byte
+}
+
+// AsyncOutput decouples writing log lines from the actual (possibly slow) output.
+// Log lines are queued in a bounded buffer and written by a background routine.
+// If the buffer is full, the drop policy decides if the caller is blocked or which
+// log line is discarded. The output is flushed once the kernel shuts down, any log
+// line written afterwards is written synchronously. Fatal log lines flush the output
+// and are written synchronously, as the process is about to die. Panic log lines are
+// written after all buffered log lines, but the output stays asynchronous, as a panic
+// might be recovered.
+type AsyncOutput struct {
+	output   io.Writer
+	metric   MetricWriter
+	settings AsyncOutputSettings
+
+	lck      sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	drained  *sync.Cond
+	entries  []asyncEntry
+	dropped  int
+	started  bool
+	writing  bool
+	closed   bool
+	done     chan struct{}
+}
+
+func NewAsyncOutput(settings AsyncOutputSettings) *AsyncOutput {
+	defaults := getAsyncOutputDefaultMetrics()
+	metric := NewMetricDaemonWriter(defaults...)
+
+	return NewAsyncOutputWithInterfaces(metric, settings)
+}
+
+func NewAsyncOutputWithInterfaces(metric MetricWriter, settings AsyncOutputSettings) *AsyncOutput {
+	if settings.BufferSize <= 0 {
+		settings.BufferSize = 1
+	}
+
+	if settings.DropPolicy == "" {
+		settings.DropPolicy = AsyncDropPolicyBlock
+	}
+
+	o := &AsyncOutput{
+		metric:   metric,
+		settings: settings,
+		entries:  make([]asyncEntry, 0, settings.BufferSize),
+		done:     make(chan struct{}),
+	}
+
+	o.notEmpty = sync.NewCond(&o.lck)
+	o.notFull = sync.NewCond(&o.lck)
+	o.drained = sync.NewCond(&o.lck)
+
+	return o
+}
+
+func (o *AsyncOutput) GetType() string {
+	return common.TypeBackground
+}
+
+func (o *AsyncOutput) GetStage() int {
+	return common.StageEssential
+}
+
+func (o *AsyncOutput) Boot(_ cfg.Config, _ Logger) error {
+	return nil
+}
+
+func (o *AsyncOutput) Run(ctx context.Context) error {
+	<-ctx.Done()
+	o.Flush()
+
+	return nil
+}
+
+func (o *AsyncOutput) Write(p []byte) (int, error) {
+	return o.WriteLevel(Info, p)
+}
+
+func (o *AsyncOutput) WriteLevel(level string, p []byte) (int, error) {
+	o.lck.Lock()
+
+	if !o.started {
+		o.lck.Unlock()
+		return 0, fmt.Errorf("async log output is not attached to a logger")
+	}
+
+	if o.closed {
+		o.lck.Unlock()
+		return o.writeSync(p)
+	}
+
+	if level == Fatal {
+		o.lck.Unlock()
+		o.Flush()
+
+		return o.writeSync(p)
+	}
+
+	if level == Panic {
+		return o.writeDrained(p)
+	}
+
+	entry := asyncEntry{
+		level:  LevelPriority(level),
+		buffer: append([]byte(nil), p...),
+	}
+
+	for len(o.entries) >= o.settings.BufferSize && !o.closed {
+		if o.settings.DropPolicy == AsyncDropPolicyBlock {
+			o.notFull.Wait()
+			continue
+		}
+
+		if !o.makeRoom(entry) {
+			o.dropped++
+			o.lck.Unlock()
+
+			return len(p), nil
+		}
+
+		o.dropped++
+	}
+
+	if o.closed {
+		o.lck.Unlock()
+		return o.writeSync(p)
+	}
+
+	o.entries = append(o.entries, entry)
+	o.notEmpty.Signal()
+	o.lck.Unlock()
+
+	return len(p), nil
+}
+
+// Flush writes all pending log lines and switches the output to synchronous writes.
+func (o *AsyncOutput) Flush() {
+	o.lck.Lock()
+
+	if !o.started || o.closed {
+		o.lck.Unlock()
+		return
+	}
+
+	o.closed = true
+	o.notEmpty.Broadcast()
+	o.notFull.Broadcast()
+	o.lck.Unlock()
+
+	<-o.done
+}
+
+func (o *AsyncOutput) start(output io.Writer) error {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	if o.started {
+		return fmt.Errorf("async log output is already attached to a logger")
+	}
+
+	o.output = output
+	o.started = true
+
+	go o.run()
+
+	return nil
+}
+
+// writeDrained waits until the background routine wrote all buffered log lines and
+// writes the log line while holding the lock, so the background routine can't write
+// concurrently. It has to be called with the lock held.
+func (o *AsyncOutput) writeDrained(p []byte) (int, error) {
+	for (len(o.entries) > 0 || o.writing) && !o.closed {
+		o.drained.Wait()
+	}
+
+	if o.closed {
+		o.lck.Unlock()
+		return o.writeSync(p)
+	}
+
+	defer o.lck.Unlock()
+
+	return o.output.Write(p)
+}
+
+// writeSync waits for the background routine to finish before writing, so
+// log lines are neither reordered nor written concurrently.
+func (o *AsyncOutput) writeSync(p []byte) (int, error) {
+	<-o.done
+
+	return o.output.Write(p)
+}
+
+// makeRoom removes a buffered entry according to the drop policy. It returns
+// false if the new entry should be dropped instead.
+func (o *AsyncOutput) makeRoom(entry asyncEntry) bool {
+	if o.settings.DropPolicy == AsyncDropPolicyDropDebugFirst {
+		for i, e := range o.entries {
+			if e.level <= LevelPriority(Debug) {
+				o.entries = append(o.entries[:i], o.entries[i+1:]...)
+				return true
+			}
+		}
+
+		if entry.level <= LevelPriority(Debug) {
+			return false
+		}
+	}
+
+	o.entries = o.entries[1:]
+
+	return true
+}
+
+func (o *AsyncOutput) run() {
+	for {
+		o.lck.Lock()
+
+		for len(o.entries) == 0 && !o.closed {
+			o.notEmpty.Wait()
+		}
+
+		entries := o.entries
+		dropped := o.dropped
+		closed := o.closed
+
+		o.entries = make([]asyncEntry, 0, o.settings.BufferSize)
+		o.dropped = 0
+		o.writing = true
+		o.notFull.Broadcast()
+		o.lck.Unlock()
+
+		for _, e := range entries {
+			if _, err := o.output.Write(e.buffer); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+			}
+		}
+
+		o.lck.Lock()
+		o.writing = false
+		o.drained.Broadcast()
+		o.lck.Unlock()
+
+		// signal we are done before writing the metric, the metric writer might log
+		// something itself which would otherwise wait for us forever
+		if closed {
+			close(o.done)
+		}
+
+		if dropped > 0 {
+			o.writeMetric(dropped)
+		}
+
+		if closed {
+			return
+		}
+	}
+}
+
+func (o *AsyncOutput) writeMetric(dropped int) {
+	o.metric.WriteOne(&MetricDatum{
+		Priority:   PriorityHigh,
+		MetricName: metricNameLogLinesDropped,
+		Unit:       UnitCount,
+		Value:      float64(dropped),
+	})
+}
+
+func getAsyncOutputDefaultMetrics() MetricData {
+	return MetricData{
+		{
+			Priority:   PriorityHigh,
+			MetricName: metricNameLogLinesDropped,
+			Unit:       UnitCount,
+			Value:      0.0,
+		},
+	}
+}
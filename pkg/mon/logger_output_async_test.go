@@ -0,0 +1,233 @@
+package mon_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type blockingWriter struct {
+	lck     sync.Mutex
+	out     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() {
+		close(w.started)
+		<-w.release
+	})
+
+	w.lck.Lock()
+	defer w.lck.Unlock()
+
+	return w.out.Write(p)
+}
+
+func (w *blockingWriter) lines() []string {
+	w.lck.Lock()
+	defer w.lck.Unlock()
+
+	return strings.Split(strings.TrimSpace(w.out.String()), "\n")
+}
+
+func getAsyncLogger(t *testing.T, policy string) (mon.Logger, *mon.AsyncOutput, *blockingWriter, *monMocks.MetricWriter) {
+	out := newBlockingWriter()
+	metric := new(monMocks.MetricWriter)
+
+	async := mon.NewAsyncOutputWithInterfaces(metric, mon.AsyncOutputSettings{
+		Enabled:    true,
+		BufferSize: 2,
+		DropPolicy: policy,
+	})
+
+	logger := mon.NewLoggerWithInterfaces(clockwork.NewFakeClock(), out)
+	err := logger.Option(mon.WithLevel(mon.Debug), mon.WithFormat(mon.FormatConsole), mon.WithAsyncOutput(async))
+	assert.NoError(t, err)
+
+	return logger, async, out, metric
+}
+
+func TestAsyncOutput_Block(t *testing.T) {
+	logger, async, out, metric := getAsyncLogger(t, mon.AsyncDropPolicyBlock)
+
+	logger.Info("msg1")
+	<-out.started
+
+	logger.Info("msg2")
+	logger.Info("msg3")
+
+	done := make(chan struct{})
+	go func() {
+		logger.Info("msg4")
+		close(done)
+	}()
+
+	close(out.release)
+	<-done
+
+	async.Flush()
+
+	lines := out.lines()
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[3], "msg4")
+	metric.AssertNotCalled(t, "WriteOne", mock.Anything)
+}
+
+func TestAsyncOutput_DropOldest(t *testing.T) {
+	logger, async, out, metric := getAsyncLogger(t, mon.AsyncDropPolicyDropOldest)
+	metric.On("WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == "LogLinesDropped" && datum.Value == 1.0
+	})).Once()
+
+	logger.Info("msg1")
+	<-out.started
+
+	logger.Info("msg2")
+	logger.Info("msg3")
+	logger.Info("msg4")
+
+	close(out.release)
+	async.Flush()
+
+	lines := out.lines()
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "msg1")
+	assert.Contains(t, lines[1], "msg3")
+	assert.Contains(t, lines[2], "msg4")
+	metric.AssertExpectations(t)
+}
+
+func TestAsyncOutput_DropDebugFirst(t *testing.T) {
+	logger, async, out, metric := getAsyncLogger(t, mon.AsyncDropPolicyDropDebugFirst)
+	metric.On("WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == "LogLinesDropped" && datum.Value == 2.0
+	})).Once()
+
+	logger.Info("msg1")
+	<-out.started
+
+	logger.Info("msg2")
+	logger.Debug("msg3")
+	logger.Warn("msg4")
+	logger.Debug("msg5")
+
+	close(out.release)
+	async.Flush()
+
+	lines := out.lines()
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "msg1")
+	assert.Contains(t, lines[1], "msg2")
+	assert.Contains(t, lines[2], "msg4")
+	metric.AssertExpectations(t)
+}
+
+func TestAsyncOutput_Run(t *testing.T) {
+	logger, async, out, _ := getAsyncLogger(t, mon.AsyncDropPolicyBlock)
+	close(out.release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	logger.Info("msg1")
+	err := async.Run(ctx)
+	assert.NoError(t, err)
+
+	logger.Info("msg2")
+
+	lines := out.lines()
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "msg2")
+}
+
+func TestAsyncOutput_Panic(t *testing.T) {
+	logger, _, out, _ := getAsyncLogger(t, mon.AsyncDropPolicyBlock)
+	close(out.release)
+
+	logger.Info("msg1")
+
+	assert.Panics(t, func() {
+		logger.Panic(fmt.Errorf("boom"), "msg2")
+	})
+
+	// the panic line is written before the logger panics, so it isn't lost if the process dies
+	lines := out.lines()
+	assert.Contains(t, lines[0], "msg1")
+	assert.Contains(t, strings.Join(lines[1:], "\n"), "msg2")
+}
+
+type slowLineWriter struct {
+	lck     sync.Mutex
+	out     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *slowLineWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("slow")) {
+		<-w.release
+	}
+
+	w.lck.Lock()
+	defer w.lck.Unlock()
+
+	return w.out.Write(p)
+}
+
+func TestAsyncOutput_PanicRecovered(t *testing.T) {
+	out := &slowLineWriter{
+		release: make(chan struct{}),
+	}
+
+	async := mon.NewAsyncOutputWithInterfaces(new(monMocks.MetricWriter), mon.AsyncOutputSettings{
+		Enabled:    true,
+		BufferSize: 2,
+		DropPolicy: mon.AsyncDropPolicyBlock,
+	})
+
+	logger := mon.NewLoggerWithInterfaces(clockwork.NewFakeClock(), out)
+	err := logger.Option(mon.WithFormat(mon.FormatConsole), mon.WithAsyncOutput(async))
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() {
+		logger.Panic(fmt.Errorf("boom"), "msg1")
+	})
+
+	// the output is still asynchronous after a recovered panic, so a slow write doesn't block the caller
+	written := make(chan struct{})
+	go func() {
+		logger.Info("slow msg2")
+		close(written)
+	}()
+
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the log line should have been written asynchronously")
+	}
+
+	close(out.release)
+	async.Flush()
+
+	lines := strings.Split(strings.TrimSpace(out.out.String()), "\n")
+	assert.Contains(t, lines[0], "msg1")
+	assert.Contains(t, lines[len(lines)-1], "slow msg2")
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
@@ -253,3 +254,19 @@ func getLogger() (mon.GosoLog, *bytes.Buffer) {
 
 	return client, out
 }
+
+func TestLogger_WithRedactor(t *testing.T) {
+	logger, out := getLogger()
+	err := logger.Option(mon.WithRedactor(cfg.NewDefaultRedactor()))
+	assert.NoError(t, err)
+
+	logger.WithFields(mon.Fields{
+		"user": "foo",
+		"db": map[string]interface{}{
+			"password": "bar",
+		},
+	}).Info("msg")
+
+	expected := `{"fields":{"user":"foo","db":{"password":"***"}},"context":{},"channel": "default", "level":2,"level_name":"info","message":"msg","timestamp":"1984-04-04T00:00:00Z"}`
+	assert.JSONEq(t, expected, out.String(), "output should match")
+}
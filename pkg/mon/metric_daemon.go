@@ -34,6 +34,7 @@ type BatchedMetricDatum struct {
 	Dimensions MetricDimensions
 	Values     []float64
 	Unit       string
+	Exemplars  []MetricExemplar
 }
 
 type cwDaemon struct {
@@ -218,11 +219,25 @@ func (d *cwDaemon) append(datum *MetricDatum) {
 			Unit:       datum.Unit,
 			Values:     []float64{datum.Value},
 		}
+		d.appendExemplars(d.batch[key], datum.Exemplars)
 		return
 	}
 
 	existing := d.batch[key]
 	existing.Values = append(existing.Values, datum.Value)
+	d.appendExemplars(existing, datum.Exemplars)
+}
+
+// keep only the first few exemplars of every batched datum, a handful of
+// example traces is enough to jump from a metric to the traces
+func (d *cwDaemon) appendExemplars(batched *BatchedMetricDatum, exemplars []MetricExemplar) {
+	for _, exemplar := range exemplars {
+		if len(batched.Exemplars) >= maxMetricExemplars {
+			return
+		}
+
+		batched.Exemplars = append(batched.Exemplars, exemplar)
+	}
 }
 
 func (d *cwDaemon) amendFromDefault(datum *MetricDatum) {
@@ -284,6 +299,7 @@ func (d *cwDaemon) buildMetricData() MetricData {
 			Dimensions: v.Dimensions,
 			Unit:       unit,
 			Value:      value,
+			Exemplars:  v.Exemplars,
 		}
 
 		data = append(data, datum)
@@ -22,12 +22,19 @@ const (
 	UnitMilliseconds = cloudwatch.StandardUnitMilliseconds
 
 	chunkSizeCloudWatch = 20
+	maxMetricExemplars  = 5
 	minusOneWeek        = -1 * 7 * 24 * time.Hour
 	plusOneHour         = 1 * time.Hour
 )
 
 type MetricDimensions map[string]string
 
+// A MetricExemplar links a data point to the trace it was recorded in.
+type MetricExemplar struct {
+	TraceId string  `json:"traceId"`
+	Value   float64 `json:"value"`
+}
+
 type MetricDatum struct {
 	Priority   int              `json:"-"`
 	Timestamp  time.Time        `json:"timestamp"`
@@ -35,6 +42,22 @@ type MetricDatum struct {
 	Dimensions MetricDimensions `json:"dimensions"`
 	Value      float64          `json:"value"`
 	Unit       string           `json:"unit"`
+	Exemplars  []MetricExemplar `json:"exemplars,omitempty"`
+}
+
+// AddExemplar attaches the trace id to the datum. Writers which don't support
+// exemplars (like cloudwatch) ignore them.
+func (d *MetricDatum) AddExemplar(traceId string) *MetricDatum {
+	if traceId == "" {
+		return d
+	}
+
+	d.Exemplars = append(d.Exemplars, MetricExemplar{
+		TraceId: traceId,
+		Value:   d.Value,
+	})
+
+	return d
 }
 
 func (d *MetricDatum) Id() string {
@@ -39,6 +39,7 @@ type Client interface {
 	LLen(key string) (int64, error)
 	RPush(key string, values ...interface{}) (int64, error)
 
+	HDel(key string, fields ...string) (int64, error)
 	HExists(key string, field string) (bool, error)
 	HKeys(key string) ([]string, error)
 	HGet(key string, field string) (string, error)
@@ -52,9 +53,21 @@ type Client interface {
 	Decr(key string) (int64, error)
 	DecrBy(key string, amount int64) (int64, error)
 
+	ZAdd(key string, score float64, member interface{}) (int64, error)
+	ZRangeByScore(key string, min string, max string, count int64) ([]string, error)
+	ZRem(key string, members ...interface{}) (int64, error)
+
+	XAdd(args *baseRedis.XAddArgs) (string, error)
+	XAck(stream string, group string, ids ...string) (int64, error)
+	XClaim(args *baseRedis.XClaimArgs) ([]baseRedis.XMessage, error)
+	XGroupCreateMkStream(stream string, group string, start string) (string, error)
+	XPendingExt(args *baseRedis.XPendingExtArgs) ([]baseRedis.XPendingExt, error)
+	XReadGroup(args *baseRedis.XReadGroupArgs) ([]baseRedis.XStream, error)
+
 	IsAlive() bool
 
 	Pipeline() baseRedis.Pipeliner
+	TxPipeline() baseRedis.Pipeliner
 }
 
 type redisClient struct {
@@ -234,6 +247,14 @@ func (c *redisClient) HKeys(key string) ([]string, error) {
 	return cmd.(*baseRedis.StringSliceCmd).Val(), err
 }
 
+func (c *redisClient) HDel(key string, fields ...string) (int64, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.HDel(key, fields...)
+	})
+
+	return cmd.(*baseRedis.IntCmd).Val(), err
+}
+
 func (c *redisClient) HGet(key, field string) (string, error) {
 	cmd, err := c.execute(func() ErrCmder {
 		return c.base.HGet(key, field)
@@ -300,6 +321,87 @@ func (c *redisClient) Expire(key string, ttl time.Duration) (bool, error) {
 	return cmd.(*baseRedis.BoolCmd).Val(), err
 }
 
+func (c *redisClient) ZAdd(key string, score float64, member interface{}) (int64, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.ZAdd(key, baseRedis.Z{
+			Score:  score,
+			Member: member,
+		})
+	})
+
+	return cmd.(*baseRedis.IntCmd).Val(), err
+}
+
+// ZRangeByScore returns up to count members with a score in [min, max], ordered by score. A count of 0 returns all members.
+func (c *redisClient) ZRangeByScore(key string, min string, max string, count int64) ([]string, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.ZRangeByScore(key, baseRedis.ZRangeBy{
+			Min:   min,
+			Max:   max,
+			Count: count,
+		})
+	})
+
+	return cmd.(*baseRedis.StringSliceCmd).Val(), err
+}
+
+func (c *redisClient) ZRem(key string, members ...interface{}) (int64, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.ZRem(key, members...)
+	})
+
+	return cmd.(*baseRedis.IntCmd).Val(), err
+}
+
+func (c *redisClient) XAdd(args *baseRedis.XAddArgs) (string, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.XAdd(args)
+	})
+
+	return cmd.(*baseRedis.StringCmd).Val(), err
+}
+
+func (c *redisClient) XAck(stream string, group string, ids ...string) (int64, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.XAck(stream, group, ids...)
+	})
+
+	return cmd.(*baseRedis.IntCmd).Val(), err
+}
+
+func (c *redisClient) XClaim(args *baseRedis.XClaimArgs) ([]baseRedis.XMessage, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.XClaim(args)
+	})
+
+	return cmd.(*baseRedis.XMessageSliceCmd).Val(), err
+}
+
+func (c *redisClient) XGroupCreateMkStream(stream string, group string, start string) (string, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.XGroupCreateMkStream(stream, group, start)
+	})
+
+	return cmd.(*baseRedis.StatusCmd).Val(), err
+}
+
+func (c *redisClient) XPendingExt(args *baseRedis.XPendingExtArgs) ([]baseRedis.XPendingExt, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.XPendingExt(args)
+	})
+
+	return cmd.(*baseRedis.XPendingExtCmd).Val(), err
+}
+
+// XReadGroup returns the error Nil if no message was read until the block duration of the args passed.
+func (c *redisClient) XReadGroup(args *baseRedis.XReadGroupArgs) ([]baseRedis.XStream, error) {
+	cmd, err := c.execute(func() ErrCmder {
+		return c.base.XReadGroup(args)
+	})
+
+	return cmd.(*baseRedis.XStreamSliceCmd).Val(), err
+}
+
 func (c *redisClient) IsAlive() bool {
 	cmd, err := c.execute(func() ErrCmder {
 		return c.base.Ping()
@@ -314,6 +416,10 @@ func (c *redisClient) Pipeline() baseRedis.Pipeliner {
 	return c.base.Pipeline()
 }
 
+func (c *redisClient) TxPipeline() baseRedis.Pipeliner {
+	return c.base.TxPipeline()
+}
+
 func (c *redisClient) execute(wrappedCmd func() ErrCmder) (interface{}, error) {
 	return c.executor.Execute(context.Background(), func(ctx context.Context) (interface{}, error) {
 		cmder := wrappedCmd()
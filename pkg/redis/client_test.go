@@ -174,6 +174,35 @@ func (s *ClientWithMiniRedisTestSuite) TestIsAlive() {
 	s.True(alive)
 }
 
+func (s *ClientWithMiniRedisTestSuite) TestSortedSet() {
+	for i, member := range []string{"c", "a", "b"} {
+		added, err := s.client.ZAdd("set", float64(3-i), member)
+		s.NoError(err, "there should be no error on ZAdd")
+		s.Equal(int64(1), added)
+	}
+
+	members, err := s.client.ZRangeByScore("set", "-inf", "2", 0)
+	s.NoError(err, "there should be no error on ZRangeByScore")
+	s.Equal([]string{"b", "a"}, members)
+
+	members, err = s.client.ZRangeByScore("set", "-inf", "+inf", 2)
+	s.NoError(err, "there should be no error on ZRangeByScore")
+	s.Equal([]string{"b", "a"}, members)
+
+	removed, err := s.client.ZRem("set", "a", "b", "d")
+	s.NoError(err, "there should be no error on ZRem")
+	s.Equal(int64(2), removed)
+}
+
+func (s *ClientWithMiniRedisTestSuite) TestHDel() {
+	err := s.client.HSet("hash", "field", "value")
+	s.NoError(err, "there should be no error on HSet")
+
+	count, err := s.client.HDel("hash", "field", "missing")
+	s.NoError(err, "there should be no error on HDel")
+	s.Equal(int64(1), count)
+}
+
 func TestClientWithMiniRedisTestSuite(t *testing.T) {
 	suite.Run(t, new(ClientWithMiniRedisTestSuite))
 }
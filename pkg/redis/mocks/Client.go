@@ -195,6 +195,34 @@ func (_m *Client) Get(key string) (string, error) {
 	return r0, r1
 }
 
+// HDel provides a mock function with given fields: key, fields
+func (_m *Client) HDel(key string, fields ...string) (int64, error) {
+	_va := make([]interface{}, len(fields))
+	for _i := range fields {
+		_va[_i] = fields[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, key)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, ...string) int64); ok {
+		r0 = rf(key, fields...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, ...string) error); ok {
+		r1 = rf(key, fields...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // HExists provides a mock function with given fields: key, field
 func (_m *Client) HExists(key string, field string) (bool, error) {
 	ret := _m.Called(key, field)
@@ -556,3 +584,226 @@ func (_m *Client) SetNX(key string, value interface{}, ttl time.Duration) (bool,
 
 	return r0, r1
 }
+
+// TxPipeline provides a mock function with given fields:
+func (_m *Client) TxPipeline() go_redisredis.Pipeliner {
+	ret := _m.Called()
+
+	var r0 go_redisredis.Pipeliner
+	if rf, ok := ret.Get(0).(func() go_redisredis.Pipeliner); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(go_redisredis.Pipeliner)
+		}
+	}
+
+	return r0
+}
+
+// ZAdd provides a mock function with given fields: key, score, member
+func (_m *Client) ZAdd(key string, score float64, member interface{}) (int64, error) {
+	ret := _m.Called(key, score, member)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, float64, interface{}) int64); ok {
+		r0 = rf(key, score, member)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, float64, interface{}) error); ok {
+		r1 = rf(key, score, member)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ZRangeByScore provides a mock function with given fields: key, min, max, count
+func (_m *Client) ZRangeByScore(key string, min string, max string, count int64) ([]string, error) {
+	ret := _m.Called(key, min, max, count)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, string, string, int64) []string); ok {
+		r0 = rf(key, min, max, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string, int64) error); ok {
+		r1 = rf(key, min, max, count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ZRem provides a mock function with given fields: key, members
+func (_m *Client) ZRem(key string, members ...interface{}) (int64, error) {
+	var _ca []interface{}
+	_ca = append(_ca, key)
+	_ca = append(_ca, members...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, ...interface{}) int64); ok {
+		r0 = rf(key, members...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, ...interface{}) error); ok {
+		r1 = rf(key, members...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XAck provides a mock function with given fields: stream, group, ids
+func (_m *Client) XAck(stream string, group string, ids ...string) (int64, error) {
+	_va := make([]interface{}, len(ids))
+	for _i := range ids {
+		_va[_i] = ids[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, stream, group)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, string, ...string) int64); ok {
+		r0 = rf(stream, group, ids...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, ...string) error); ok {
+		r1 = rf(stream, group, ids...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XAdd provides a mock function with given fields: args
+func (_m *Client) XAdd(args *go_redisredis.XAddArgs) (string, error) {
+	ret := _m.Called(args)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XAddArgs) string); ok {
+		r0 = rf(args)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XAddArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XClaim provides a mock function with given fields: args
+func (_m *Client) XClaim(args *go_redisredis.XClaimArgs) ([]go_redisredis.XMessage, error) {
+	ret := _m.Called(args)
+
+	var r0 []go_redisredis.XMessage
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XClaimArgs) []go_redisredis.XMessage); ok {
+		r0 = rf(args)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]go_redisredis.XMessage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XClaimArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XGroupCreateMkStream provides a mock function with given fields: stream, group, start
+func (_m *Client) XGroupCreateMkStream(stream string, group string, start string) (string, error) {
+	ret := _m.Called(stream, group, start)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, string, string) string); ok {
+		r0 = rf(stream, group, start)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(stream, group, start)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XPendingExt provides a mock function with given fields: args
+func (_m *Client) XPendingExt(args *go_redisredis.XPendingExtArgs) ([]go_redisredis.XPendingExt, error) {
+	ret := _m.Called(args)
+
+	var r0 []go_redisredis.XPendingExt
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XPendingExtArgs) []go_redisredis.XPendingExt); ok {
+		r0 = rf(args)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]go_redisredis.XPendingExt)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XPendingExtArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XReadGroup provides a mock function with given fields: args
+func (_m *Client) XReadGroup(args *go_redisredis.XReadGroupArgs) ([]go_redisredis.XStream, error) {
+	ret := _m.Called(args)
+
+	var r0 []go_redisredis.XStream
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XReadGroupArgs) []go_redisredis.XStream); ok {
+		r0 = rf(args)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]go_redisredis.XStream)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XReadGroupArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -12,6 +12,34 @@ type Queue struct {
 	mock.Mock
 }
 
+// ChangeMessageVisibility provides a mock function with given fields: receiptHandle, visibilityTimeout
+func (_m *Queue) ChangeMessageVisibility(receiptHandle string, visibilityTimeout int64) error {
+	ret := _m.Called(receiptHandle, visibilityTimeout)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(receiptHandle, visibilityTimeout)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangeMessageVisibilityBatch provides a mock function with given fields: receiptHandles, visibilityTimeout
+func (_m *Queue) ChangeMessageVisibilityBatch(receiptHandles []string, visibilityTimeout int64) error {
+	ret := _m.Called(receiptHandles, visibilityTimeout)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string, int64) error); ok {
+		r0 = rf(receiptHandles, visibilityTimeout)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteMessage provides a mock function with given fields: receiptHandle
 func (_m *Queue) DeleteMessage(receiptHandle string) error {
 	ret := _m.Called(receiptHandle)
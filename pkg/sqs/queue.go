@@ -32,6 +32,8 @@ type Queue interface {
 
 	DeleteMessage(receiptHandle string) error
 	DeleteMessageBatch(receiptHandles []string) error
+	ChangeMessageVisibility(receiptHandle string, visibilityTimeout int64) error
+	ChangeMessageVisibilityBatch(receiptHandles []string, visibilityTimeout int64) error
 	Receive(ctx context.Context, maxNumberOfMessages int64, waitTime int64) ([]*sqs.Message, error)
 	Send(ctx context.Context, msg *Message) error
 	SendBatch(ctx context.Context, messages []*Message) error
@@ -187,6 +189,7 @@ func (q *queue) Receive(ctx context.Context, maxNumberOfMessages int64, waitTime
 	logger := q.logger.WithContext(ctx)
 
 	input := &sqs.ReceiveMessageInput{
+		AttributeNames:        []*string{aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount)},
 		MessageAttributeNames: []*string{aws.String("ALL")},
 		MaxNumberOfMessages:   aws.Int64(maxNumberOfMessages),
 		QueueUrl:              aws.String(q.properties.Url),
@@ -279,6 +282,64 @@ func (q *queue) doDeleteMessageBatch(input *sqs.DeleteMessageBatchInput) error {
 	return err
 }
 
+func (q *queue) ChangeMessageVisibility(receiptHandle string, visibilityTimeout int64) error {
+	input := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.properties.Url),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: aws.Int64(visibilityTimeout),
+	}
+
+	_, err := q.executor.Execute(context.Background(), func() (*request.Request, interface{}) {
+		return q.client.ChangeMessageVisibilityRequest(input)
+	})
+
+	if err != nil {
+		q.logger.Errorf(err, "could not change the visibility of a message of sqs queue %s", q.properties.Name)
+		return err
+	}
+
+	return nil
+}
+
+func (q *queue) ChangeMessageVisibilityBatch(receiptHandles []string, visibilityTimeout int64) error {
+	input := &sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: aws.String(q.properties.Url),
+	}
+
+	entries := make([]*sqs.ChangeMessageVisibilityBatchRequestEntry, len(receiptHandles))
+
+	for i, receiptHandle := range receiptHandles {
+		entries[i] = &sqs.ChangeMessageVisibilityBatchRequestEntry{
+			Id:                mdl.String(uuid.NewV4().String()),
+			ReceiptHandle:     mdl.String(receiptHandle),
+			VisibilityTimeout: aws.Int64(visibilityTimeout),
+		}
+	}
+
+	multiError := new(multierror.Error)
+
+	for i := 0; i < len(entries); i += sqsBatchSize {
+		j := i + sqsBatchSize
+
+		if j > len(entries) {
+			j = len(entries)
+		}
+
+		input.Entries = entries[i:j]
+
+		_, err := q.executor.Execute(context.Background(), func() (*request.Request, interface{}) {
+			return q.client.ChangeMessageVisibilityBatchRequest(input)
+		})
+
+		if err != nil {
+			q.logger.Errorf(err, "could not change the visibility of the messages of sqs queue %s", q.properties.Name)
+			multiError = multierror.Append(multiError, err)
+		}
+	}
+
+	return multiError.ErrorOrNil()
+}
+
 func (q *queue) GetName() string {
 	return q.properties.Name
 }
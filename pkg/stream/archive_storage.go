@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/blob"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ArchiveStorageTypeLocal = "local"
+	ArchiveStorageTypeS3    = "s3"
+
+	archiveFileExtension   = ".jsonl"
+	archiveGzipExtension   = ".gz"
+	archiveFileTimeFormat  = "20060102T150405.000000000Z"
+	archivePartitionFormat = "2006/01/02/15"
+)
+
+// An archiveRecord is a line of an archive file, the message with the time it was written at.
+type archiveRecord struct {
+	Timestamp  time.Time              `json:"timestamp"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Body       string                 `json:"body"`
+}
+
+// ArchiveStorageSettings locate the files of an archive. The files are partitioned by the hour they were
+// started at, like {prefix}/2020/04/21/13/20200421T130000.000000000Z-{uuid}.jsonl.gz, and contain one json
+// encoded message and the time it was written at per line. The names of the files sort by time, which keeps the order of the messages on replay.
+type ArchiveStorageSettings struct {
+	Type string `cfg:"type" default:"s3" validate:"oneof=s3 local"`
+	// Bucket of the s3 storage, defaults to {project}-{env}-{family}
+	Bucket string `cfg:"bucket"`
+	// Directory of the local storage
+	Directory string `cfg:"directory"`
+	// Prefix of all files, defaults to the name of the input or output
+	Prefix string `cfg:"prefix"`
+}
+
+// An ArchiveStorage lists, reads and writes the files of an archive.
+type ArchiveStorage interface {
+	List(ctx context.Context, prefix string) ([]string, error)
+	Read(ctx context.Context, key string) (io.ReadCloser, error)
+	Write(ctx context.Context, key string, body []byte) error
+}
+
+func NewArchiveStorage(config cfg.Config, settings *ArchiveStorageSettings) ArchiveStorage {
+	if settings.Type == ArchiveStorageTypeLocal {
+		return NewArchiveLocalStorage(settings.Directory)
+	}
+
+	bucket := settings.Bucket
+
+	if bucket == "" {
+		appId := cfg.GetAppIdFromConfig(config)
+		bucket = fmt.Sprintf("%s-%s-%s", appId.Project, appId.Environment, appId.Family)
+	}
+
+	return NewArchiveS3Storage(blob.ProvideS3Client(config), bucket)
+}
+
+type archiveS3Storage struct {
+	client s3iface.S3API
+	bucket string
+}
+
+func NewArchiveS3Storage(client s3iface.S3API, bucket string) ArchiveStorage {
+	return &archiveS3Storage{
+		client: client,
+		bucket: bucket,
+	}
+}
+
+func (s *archiveS3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	err := s.client.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("can not list the objects of s3://%s/%s: %w", s.bucket, prefix, err)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *archiveS3Storage) Read(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("can not get the object s3://%s/%s: %w", s.bucket, key, err)
+	}
+
+	return out.Body, nil
+}
+
+func (s *archiveS3Storage) Write(ctx context.Context, key string, body []byte) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	})
+
+	if err != nil {
+		return fmt.Errorf("can not put the object s3://%s/%s: %w", s.bucket, key, err)
+	}
+
+	return nil
+}
+
+type archiveLocalStorage struct {
+	directory string
+}
+
+func NewArchiveLocalStorage(directory string) ArchiveStorage {
+	return &archiveLocalStorage{
+		directory: directory,
+	}
+}
+
+func (s *archiveLocalStorage) List(_ context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	root := filepath.Join(s.directory, filepath.FromSlash(prefix))
+
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.directory, file)
+
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, filepath.ToSlash(rel))
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("can not list the files of %s: %w", root, err)
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (s *archiveLocalStorage) Read(_ context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.directory, filepath.FromSlash(key)))
+}
+
+func (s *archiveLocalStorage) Write(_ context.Context, key string, body []byte) error {
+	file := filepath.Join(s.directory, filepath.FromSlash(key))
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("can not create the directory of %s: %w", file, err)
+	}
+
+	return ioutil.WriteFile(file, body, 0644)
+}
+
+func archiveFileKey(prefix string, start time.Time, id string, gzip bool) string {
+	start = start.UTC()
+	name := fmt.Sprintf("%s-%s%s", start.Format(archiveFileTimeFormat), id, archiveFileExtension)
+
+	if gzip {
+		name += archiveGzipExtension
+	}
+
+	return path.Join(prefix, start.Format(archivePartitionFormat), name)
+}
+
+// archiveFileTime parses the start time from the name of an archive file.
+func archiveFileTime(key string) (time.Time, bool) {
+	name := path.Base(key)
+
+	if len(name) < len(archiveFileTimeFormat) || !strings.Contains(name, archiveFileExtension) {
+		return time.Time{}, false
+	}
+
+	start, err := time.Parse(archiveFileTimeFormat, name[:len(archiveFileTimeFormat)])
+
+	return start, err == nil
+}
@@ -0,0 +1,153 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/clock"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func readArchive(t *testing.T, storage stream.ArchiveStorage, from time.Time, until time.Time) []string {
+	input := stream.NewArchiveInputWithInterfaces(monMocks.NewLoggerMockedAll(), storage, &stream.ArchiveInputSettings{
+		Storage: stream.ArchiveStorageSettings{
+			Prefix: "events",
+		},
+		From:             from,
+		Until:            until,
+		RotationInterval: 5 * time.Minute,
+	})
+
+	done := make(chan error)
+
+	go func() {
+		done <- input.Run(context.Background())
+	}()
+
+	bodies := make([]string, 0)
+
+	for msg := range input.Data() {
+		bodies = append(bodies, msg.Body)
+	}
+
+	assert.NoError(t, <-done)
+
+	return bodies
+}
+
+func TestArchive_WriteAndReplay(t *testing.T) {
+	directory, err := ioutil.TempDir("", "stream-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	ctx := context.Background()
+	logger := monMocks.NewLoggerMockedAll()
+	storage := stream.NewArchiveLocalStorage(directory)
+	clk := clock.NewFakeClockAt(time.Date(2020, 4, 21, 13, 59, 0, 0, time.UTC))
+
+	output := stream.NewArchiveOutputWithInterfaces(logger, clk, storage, &stream.ArchiveOutputSettings{
+		Storage: stream.ArchiveStorageSettings{
+			Prefix: "events",
+		},
+		Gzip:             true,
+		RotationInterval: 5 * time.Minute,
+		RotationSize:     1024,
+	})
+
+	err = output.Write(ctx, []*stream.Message{stream.NewMessage("1"), stream.NewMessage("2")})
+	assert.NoError(t, err)
+
+	// the file is due after the rotation interval and contains the message written last
+	clk.Advance(6 * time.Minute)
+	err = output.WriteOne(ctx, stream.NewMessage("3"))
+	assert.NoError(t, err)
+
+	err = output.WriteOne(ctx, stream.NewMessage("4"))
+	assert.NoError(t, err)
+	err = output.Flush(ctx)
+	assert.NoError(t, err)
+
+	uncompressed := stream.NewArchiveOutputWithInterfaces(logger, clock.NewFakeClockAt(time.Date(2020, 4, 22, 0, 10, 0, 0, time.UTC)), storage, &stream.ArchiveOutputSettings{
+		Storage: stream.ArchiveStorageSettings{
+			Prefix: "events",
+		},
+		RotationInterval: 5 * time.Minute,
+		RotationSize:     1,
+	})
+
+	err = uncompressed.WriteOne(ctx, stream.NewMessage("5"))
+	assert.NoError(t, err, "the file should be written as soon as it exceeds the rotation size")
+
+	keys, err := storage.List(ctx, "events/")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+	assert.Regexp(t, `^events/2020/04/21/13/20200421T135900.000000000Z-.+\.jsonl\.gz$`, keys[0])
+	assert.Regexp(t, `^events/2020/04/21/14/20200421T140500.000000000Z-.+\.jsonl\.gz$`, keys[1])
+	assert.Regexp(t, `^events/2020/04/22/00/20200422T001000.000000000Z-.+\.jsonl$`, keys[2])
+
+	all := readArchive(t, storage, time.Date(2020, 4, 21, 0, 0, 0, 0, time.UTC), time.Time{})
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, all)
+
+	// "3" was written after the start of the range into the file started before it
+	ranged := readArchive(t, storage, time.Date(2020, 4, 21, 14, 0, 0, 0, time.UTC), time.Date(2020, 4, 22, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, []string{"3", "4"}, ranged)
+
+	ranged = readArchive(t, storage, time.Date(2020, 4, 21, 13, 0, 0, 0, time.UTC), time.Date(2020, 4, 21, 14, 0, 0, 0, time.UTC))
+	assert.Equal(t, []string{"1", "2"}, ranged)
+}
+
+type archiveFailingStorage struct {
+	stream.ArchiveStorage
+	failing bool
+}
+
+func (s *archiveFailingStorage) Write(ctx context.Context, key string, body []byte) error {
+	if s.failing {
+		return fmt.Errorf("storage is down")
+	}
+
+	return s.ArchiveStorage.Write(ctx, key, body)
+}
+
+func TestArchive_FailingStorage(t *testing.T) {
+	directory, err := ioutil.TempDir("", "stream-archive")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	ctx := context.Background()
+	storage := &archiveFailingStorage{
+		ArchiveStorage: stream.NewArchiveLocalStorage(directory),
+		failing:        true,
+	}
+
+	output := stream.NewArchiveOutputWithInterfaces(monMocks.NewLoggerMockedAll(), clock.NewFakeClockAt(time.Date(2020, 4, 21, 13, 0, 0, 0, time.UTC)), storage, &stream.ArchiveOutputSettings{
+		Storage: stream.ArchiveStorageSettings{
+			Prefix: "events",
+		},
+		RotationInterval: 5 * time.Minute,
+		RotationSize:     1,
+		MaxBufferSize:    200,
+	})
+
+	err = output.Write(ctx, []*stream.Message{stream.NewMessage("1"), stream.NewMessage("2")})
+	assert.NoError(t, err, "the messages are buffered, so the failed rotation should not fail the write")
+
+	err = output.Write(ctx, []*stream.Message{stream.NewMessage("3"), stream.NewMessage("4")})
+	assert.Error(t, err, "the messages exceed the size of the buffer")
+
+	storage.failing = false
+	err = output.WriteOne(ctx, stream.NewMessage("5"))
+	assert.NoError(t, err)
+
+	keys, err := storage.List(ctx, "events/")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	all := readArchive(t, storage, time.Date(2020, 4, 21, 0, 0, 0, 0, time.UTC), time.Time{})
+	assert.Equal(t, []string{"1", "2", "5"}, all)
+}
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	AttributeBusMessageId    = "busMessageId"
+	AttributeBusReceiveCount = "busReceiveCount"
+)
+
+// A busTopic is the in-process counterpart of a sns topic. Every message written to a topic is delivered to
+// all of its subscriptions, each of them buffers and acknowledges its messages independently.
+type busTopic struct {
+	lck           sync.Mutex
+	name          string
+	subscriptions map[string]*busInput
+}
+
+var busTopics = struct {
+	sync.Mutex
+	instances map[string]*busTopic
+}{
+	instances: map[string]*busTopic{},
+}
+
+func provideBusTopic(name string) *busTopic {
+	busTopics.Lock()
+	defer busTopics.Unlock()
+
+	if topic, ok := busTopics.instances[name]; ok {
+		return topic
+	}
+
+	busTopics.instances[name] = &busTopic{
+		name:          name,
+		subscriptions: map[string]*busInput{},
+	}
+
+	return busTopics.instances[name]
+}
+
+func (t *busTopic) subscribe(name string, input *busInput) error {
+	t.lck.Lock()
+	defer t.lck.Unlock()
+
+	if _, ok := t.subscriptions[name]; ok {
+		return fmt.Errorf("there is already a subscription %s of the bus topic %s", name, t.name)
+	}
+
+	t.subscriptions[name] = input
+
+	return nil
+}
+
+func (t *busTopic) unsubscribe(name string) {
+	t.lck.Lock()
+	defer t.lck.Unlock()
+
+	delete(t.subscriptions, name)
+}
+
+// publish delivers a copy of the message to every subscription. It blocks while the buffer of a subscription
+// is full, so a slow subscriber slows down the producers of the topic. Messages written to a topic without
+// subscriptions are dropped.
+func (t *busTopic) publish(ctx context.Context, msg *Message) error {
+	for _, input := range t.currentSubscriptions() {
+		if err := input.publish(ctx, copyBusMessage(msg)); err != nil {
+			return fmt.Errorf("can not publish the message to the subscription %s of the bus topic %s: %w", input.settings.Subscription, t.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *busTopic) currentSubscriptions() []*busInput {
+	t.lck.Lock()
+	defer t.lck.Unlock()
+
+	names := make([]string, 0, len(t.subscriptions))
+
+	for name := range t.subscriptions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	inputs := make([]*busInput, len(names))
+
+	for i, name := range names {
+		inputs[i] = t.subscriptions[name]
+	}
+
+	return inputs
+}
+
+func copyBusMessage(msg *Message) *Message {
+	attributes := make(map[string]interface{}, len(msg.Attributes))
+
+	for key, value := range msg.Attributes {
+		attributes[key] = value
+	}
+
+	return &Message{
+		Attributes: attributes,
+		Body:       msg.Body,
+	}
+}
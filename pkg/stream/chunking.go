@@ -42,6 +42,41 @@ func BuildChunks(batch []*Message, size int) (Chunks, error) {
 	return chunks, err
 }
 
+// A ChunkRange is the half open range [Start, End) of the elements of a chunk.
+type ChunkRange struct {
+	Start int
+	End   int
+}
+
+// BuildSizedChunks splits n elements into chunks of at most count elements and bytes bytes, e.g. to respect the
+// limits of a single request of a batch api. size returns the size of the i-th element. An element which exceeds
+// bytes on its own forms a chunk of its own.
+func BuildSizedChunks(n int, count int, bytes int, size func(i int) int) []ChunkRange {
+	chunks := make([]ChunkRange, 0)
+	chunk := ChunkRange{}
+	chunkBytes := 0
+
+	for i := 0; i < n; i++ {
+		elementBytes := size(i)
+		chunkCount := chunk.End - chunk.Start
+
+		if chunkCount > 0 && (chunkCount == count || chunkBytes+elementBytes > bytes) {
+			chunks = append(chunks, chunk)
+			chunk = ChunkRange{Start: i, End: i}
+			chunkBytes = 0
+		}
+
+		chunk.End++
+		chunkBytes += elementBytes
+	}
+
+	if chunk.End > chunk.Start {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
 func ByteChunkToStrings(chunk Chunk) []string {
 	strings := make([]string, len(chunk))
 
@@ -85,3 +85,23 @@ func TestByteChunkToStrings(t *testing.T) {
 
 	assert.Equal(t, i, s)
 }
+
+func TestBuildSizedChunks(t *testing.T) {
+	sizes := []int{4, 4, 3, 10, 1, 1, 1}
+
+	chunks := stream.BuildSizedChunks(len(sizes), 2, 8, func(i int) int {
+		return sizes[i]
+	})
+
+	assert.Equal(t, []stream.ChunkRange{
+		{Start: 0, End: 2},
+		{Start: 2, End: 3},
+		{Start: 3, End: 4},
+		{Start: 4, End: 6},
+		{Start: 6, End: 7},
+	}, chunks)
+
+	assert.Empty(t, stream.BuildSizedChunks(0, 2, 8, func(i int) int {
+		return 0
+	}))
+}
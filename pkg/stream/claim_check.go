@@ -0,0 +1,360 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/applike/gosoline/pkg/blob"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	// AttributeExtendedPayloadSize carries the size of an offloaded body, like the aws extended clients do
+	AttributeExtendedPayloadSize = "ExtendedPayloadSize"
+
+	claimCheckPointerClass = "software.amazon.payloadoffloading.PayloadS3Pointer"
+
+	metricNameClaimCheckLoadErrorCount = "ClaimCheckLoadErrorCount"
+)
+
+type ClaimCheckSettings struct {
+	Enabled bool `cfg:"enabled" default:"false"`
+	// Threshold is the size of the marshaled message in bytes above which the body is stored in s3.
+	// The default leaves some room below the 256KB limit of sqs and sns for the envelope of sns notifications.
+	Threshold int    `cfg:"threshold" default:"245760" validate:"min=1"`
+	Bucket    string `cfg:"bucket"`
+	Prefix    string `cfg:"prefix" default:"claim-check"`
+}
+
+type ClaimCheckInputSettings struct {
+	Enabled bool `cfg:"enabled" default:"false"`
+	// DeleteAfterAck removes the stored body after the message was acknowledged
+	DeleteAfterAck bool `cfg:"delete_after_ack" default:"false"`
+}
+
+// claimCheckPointer is the reference to an offloaded body in the format of the aws extended clients:
+// ["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"bucket","s3Key":"key"}]
+type claimCheckPointer struct {
+	S3BucketName string `json:"s3BucketName"`
+	S3Key        string `json:"s3Key"`
+}
+
+func (p *claimCheckPointer) MarshalJSON() ([]byte, error) {
+	type location claimCheckPointer
+
+	return json.Marshal([]interface{}{claimCheckPointerClass, (*location)(p)})
+}
+
+func (p *claimCheckPointer) UnmarshalJSON(data []byte) error {
+	type location claimCheckPointer
+
+	var class string
+	pointer := []interface{}{&class, (*location)(p)}
+
+	if err := json.Unmarshal(data, &pointer); err != nil {
+		return err
+	}
+
+	if class != claimCheckPointerClass {
+		return fmt.Errorf("unknown pointer class %s", class)
+	}
+
+	return nil
+}
+
+func isClaimCheckPointer(msg *Message) bool {
+	if _, ok := msg.Attributes[AttributeExtendedPayloadSize]; ok {
+		return true
+	}
+
+	// messages of the extended clients read with the raw unmarshaller don't have any attributes
+	return bytes.HasPrefix([]byte(msg.Body), []byte(fmt.Sprintf(`["%s"`, claimCheckPointerClass)))
+}
+
+type claimCheckEncodeHandler struct {
+	store    blob.Store
+	bucket   string
+	settings *ClaimCheckSettings
+}
+
+// NewClaimCheckEncodeHandler creates an encode handler which stores the body of messages exceeding
+// the threshold in s3 and replaces it with a pointer to the stored body.
+func NewClaimCheckEncodeHandler(config cfg.Config, logger mon.Logger, settings *ClaimCheckSettings) EncodeHandler {
+	if settings.Bucket == "" {
+		appId := cfg.GetAppIdFromConfig(config)
+		settings.Bucket = fmt.Sprintf("%s-%s-%s", appId.Project, appId.Environment, appId.Family)
+	}
+
+	store := blob.NewStore(config, logger, blob.Settings{
+		Bucket: settings.Bucket,
+		Prefix: settings.Prefix,
+	})
+
+	return NewClaimCheckEncodeHandlerWithInterfaces(store, settings)
+}
+
+func NewClaimCheckEncodeHandlerWithInterfaces(store blob.Store, settings *ClaimCheckSettings) EncodeHandler {
+	return &claimCheckEncodeHandler{
+		store:    store,
+		bucket:   settings.Bucket,
+		settings: settings,
+	}
+}
+
+func (h *claimCheckEncodeHandler) Encode(ctx context.Context, _ interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error) {
+	return ctx, attributes, nil
+}
+
+func (h *claimCheckEncodeHandler) Decode(ctx context.Context, _ interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error) {
+	return ctx, attributes, nil
+}
+
+func (h *claimCheckEncodeHandler) EncodeMessage(ctx context.Context, msg *Message) (context.Context, error) {
+	data, err := msg.MarshalToBytes()
+
+	if err != nil {
+		return ctx, fmt.Errorf("can not marshal message to check its size: %w", err)
+	}
+
+	if len(data) <= h.settings.Threshold {
+		return ctx, nil
+	}
+
+	obj := &blob.Object{
+		Key:  aws.String(blob.CreateKey()),
+		Body: blob.StreamBytes([]byte(msg.Body)),
+	}
+
+	if err = h.store.WriteOne(obj); err != nil {
+		return ctx, fmt.Errorf("can not store message body of %d bytes: %w", len(msg.Body), err)
+	}
+
+	pointer, err := json.Marshal(&claimCheckPointer{
+		S3BucketName: h.bucket,
+		S3Key:        obj.GetFullKey(),
+	})
+
+	if err != nil {
+		return ctx, fmt.Errorf("can not marshal pointer to the stored message body: %w", err)
+	}
+
+	msg.Attributes[AttributeExtendedPayloadSize] = len(msg.Body)
+	msg.Body = string(pointer)
+
+	return ctx, nil
+}
+
+type claimCheckInput struct {
+	Input
+	logger   mon.Logger
+	metric   mon.MetricWriter
+	client   s3iface.S3API
+	name     string
+	channel  chan *Message
+	pointers sync.Map
+	settings *ClaimCheckInputSettings
+}
+
+// NewClaimCheckInput wraps an input and replaces the pointers to bodies stored in s3 with the stored bodies.
+func NewClaimCheckInput(config cfg.Config, logger mon.Logger, name string, input Input, settings *ClaimCheckInputSettings) Input {
+	client := blob.ProvideS3Client(config)
+
+	defaults := getClaimCheckInputDefaultMetrics(name)
+	metric := mon.NewMetricDaemonWriter(defaults...)
+
+	return NewClaimCheckInputWithInterfaces(logger, metric, client, name, input, settings)
+}
+
+func NewClaimCheckInputWithInterfaces(logger mon.Logger, metric mon.MetricWriter, client s3iface.S3API, name string, input Input, settings *ClaimCheckInputSettings) Input {
+	return &claimCheckInput{
+		Input:    input,
+		logger:   logger,
+		metric:   metric,
+		client:   client,
+		name:     name,
+		channel:  make(chan *Message),
+		settings: settings,
+	}
+}
+
+func (i *claimCheckInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *claimCheckInput) Run(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer close(i.channel)
+
+		for msg := range i.Input.Data() {
+			if err := i.load(ctx, msg); err != nil {
+				i.logger.WithContext(ctx).Error(err, "can not load the stored message body")
+				i.writeLoadErrorMetric()
+				i.nack(ctx, msg)
+
+				continue
+			}
+
+			// the wrapped input is drained after the consumer stopped reading, so its run can finish
+			select {
+			case i.channel <- msg:
+			case <-ctx.Done():
+				i.nack(ctx, msg)
+			}
+		}
+	}()
+
+	err := i.Input.Run(ctx)
+	<-done
+
+	return err
+}
+
+func (i *claimCheckInput) Pause() {
+	if pausable, ok := i.Input.(PausableInput); ok {
+		pausable.Pause()
+	}
+}
+
+func (i *claimCheckInput) Resume() {
+	if pausable, ok := i.Input.(PausableInput); ok {
+		pausable.Resume()
+	}
+}
+
+func (i *claimCheckInput) load(ctx context.Context, msg *Message) error {
+	if !isClaimCheckPointer(msg) {
+		return nil
+	}
+
+	pointer := &claimCheckPointer{}
+
+	if err := json.Unmarshal([]byte(msg.Body), pointer); err != nil {
+		return fmt.Errorf("can not unmarshal pointer to the stored message body: %w", err)
+	}
+
+	out, err := i.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(pointer.S3BucketName),
+		Key:    aws.String(pointer.S3Key),
+	})
+
+	if err != nil {
+		return fmt.Errorf("can not get message body from s3://%s/%s: %w", pointer.S3BucketName, pointer.S3Key, err)
+	}
+
+	defer out.Body.Close()
+
+	body, err := ioutil.ReadAll(out.Body)
+
+	if err != nil {
+		return fmt.Errorf("can not read message body from s3://%s/%s: %w", pointer.S3BucketName, pointer.S3Key, err)
+	}
+
+	msg.Body = string(body)
+	delete(msg.Attributes, AttributeExtendedPayloadSize)
+
+	if i.settings.DeleteAfterAck {
+		i.pointers.Store(msg, pointer)
+	}
+
+	return nil
+}
+
+func (i *claimCheckInput) Ack(msg *Message) error {
+	if ackInput, ok := i.Input.(AcknowledgeableInput); ok {
+		if err := ackInput.Ack(msg); err != nil {
+			return err
+		}
+	}
+
+	return i.deleteStored(msg)
+}
+
+func (i *claimCheckInput) AckBatch(msgs []*Message) error {
+	if ackInput, ok := i.Input.(AcknowledgeableInput); ok {
+		if err := ackInput.AckBatch(msgs); err != nil {
+			return err
+		}
+	}
+
+	for _, msg := range msgs {
+		if err := i.deleteStored(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Nack keeps the stored body, as the message is delivered again.
+func (i *claimCheckInput) Nack(msg *Message) error {
+	if nackInput, ok := i.Input.(NackableInput); ok {
+		return nackInput.Nack(msg)
+	}
+
+	return nil
+}
+
+// nack hands a message which isn't delivered back to the wrapped input, so it doesn't wait for its acknowledgement.
+func (i *claimCheckInput) nack(ctx context.Context, msg *Message) {
+	if err := i.Nack(msg); err != nil {
+		i.logger.WithContext(ctx).Error(err, "can not nack the message")
+	}
+}
+
+func (i *claimCheckInput) writeLoadErrorMetric() {
+	i.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: metricNameClaimCheckLoadErrorCount,
+		Dimensions: map[string]string{
+			"Input": i.name,
+		},
+		Unit:  mon.UnitCount,
+		Value: 1.0,
+	})
+}
+
+func (i *claimCheckInput) deleteStored(msg *Message) error {
+	value, ok := i.pointers.Load(msg)
+
+	if !ok {
+		return nil
+	}
+
+	i.pointers.Delete(msg)
+	pointer := value.(*claimCheckPointer)
+
+	_, err := i.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(pointer.S3BucketName),
+		Key:    aws.String(pointer.S3Key),
+	})
+
+	if err != nil {
+		return fmt.Errorf("can not delete message body from s3://%s/%s: %w", pointer.S3BucketName, pointer.S3Key, err)
+	}
+
+	return nil
+}
+
+func getClaimCheckInputDefaultMetrics(name string) mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameClaimCheckLoadErrorCount,
+			Dimensions: map[string]string{
+				"Input": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+	}
+}
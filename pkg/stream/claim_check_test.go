@@ -0,0 +1,175 @@
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/blob"
+	blobMocks "github.com/applike/gosoline/pkg/blob/mocks"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestClaimCheckEncodeHandler(t *testing.T) {
+	var storedBody []byte
+
+	store := new(blobMocks.Store)
+	store.On("WriteOne", mock.AnythingOfType("*blob.Object")).Run(func(args mock.Arguments) {
+		obj := args.Get(0).(*blob.Object)
+		obj.Key = aws.String("key")
+		storedBody, _ = obj.Body.ReadAll()
+	}).Return(nil).Once()
+
+	handler := stream.NewClaimCheckEncodeHandlerWithInterfaces(store, &stream.ClaimCheckSettings{
+		Threshold: 100,
+		Bucket:    "bucket",
+	})
+
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+		Encoding:       stream.EncodingText,
+		EncodeHandlers: []stream.EncodeHandler{handler},
+	})
+
+	msg, err := encoder.Encode(context.Background(), "small")
+	assert.NoError(t, err)
+	assert.Equal(t, "small", msg.Body)
+	assert.NotContains(t, msg.Attributes, stream.AttributeExtendedPayloadSize)
+
+	large := strings.Repeat("a", 200)
+	msg, err = encoder.Encode(context.Background(), large)
+	assert.NoError(t, err)
+	assert.Equal(t, `["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"bucket","s3Key":"//key"}]`, msg.Body)
+	assert.Equal(t, 200, msg.Attributes[stream.AttributeExtendedPayloadSize])
+	assert.Equal(t, stream.EncodingText, msg.Attributes[stream.AttributeEncoding])
+	assert.Equal(t, large, string(storedBody))
+
+	store.AssertExpectations(t)
+}
+
+type claimCheckTestInput struct {
+	*streamMocks.Input
+	*streamMocks.NackableInput
+}
+
+func TestClaimCheckInput(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	missing := &stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeExtendedPayloadSize: 200,
+		},
+		Body: `["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"bucket","s3Key":"missing"}]`,
+	}
+
+	data := make(chan *stream.Message, 3)
+	data <- missing
+	data <- &stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeEncoding:            stream.EncodingText,
+			stream.AttributeExtendedPayloadSize: 200,
+		},
+		Body: `["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"bucket","s3Key":"large"}]`,
+	}
+	data <- &stream.Message{
+		Attributes: map[string]interface{}{},
+		Body:       "small",
+	}
+	close(data)
+
+	input := &claimCheckTestInput{
+		Input:         new(streamMocks.Input),
+		NackableInput: new(streamMocks.NackableInput),
+	}
+	input.Input.On("Run", mock.Anything).Return(nil)
+	input.Input.On("Data").Return(data)
+	input.NackableInput.On("Nack", missing).Return(nil).Once()
+
+	metric := new(monMocks.MetricWriter)
+	metric.On("WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == "ClaimCheckLoadErrorCount" && datum.Dimensions["Input"] == "test"
+	})).Once()
+
+	client := new(blobMocks.S3API)
+	client.On("GetObjectWithContext", mock.Anything, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("missing"),
+	}).Return(nil, fmt.Errorf("NoSuchKey")).Once()
+	client.On("GetObjectWithContext", mock.Anything, &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("large"),
+	}).Return(&s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewBufferString("large body")),
+	}, nil).Once()
+	client.On("DeleteObject", &s3.DeleteObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("large"),
+	}).Return(&s3.DeleteObjectOutput{}, nil).Once()
+
+	claimCheckInput := stream.NewClaimCheckInputWithInterfaces(logger, metric, client, "test", input, &stream.ClaimCheckInputSettings{
+		Enabled:        true,
+		DeleteAfterAck: true,
+	})
+
+	go func() {
+		err := claimCheckInput.Run(context.Background())
+		assert.NoError(t, err)
+	}()
+
+	received := make([]*stream.Message, 0)
+	for msg := range claimCheckInput.Data() {
+		received = append(received, msg)
+	}
+
+	assert.Len(t, received, 2)
+	assert.Equal(t, "large body", received[0].Body)
+	assert.Equal(t, map[string]interface{}{stream.AttributeEncoding: stream.EncodingText}, received[0].Attributes)
+	assert.Equal(t, "small", received[1].Body)
+
+	ackInput := claimCheckInput.(stream.AcknowledgeableInput)
+	assert.NoError(t, ackInput.AckBatch(received))
+
+	client.AssertExpectations(t)
+	input.NackableInput.AssertExpectations(t)
+	metric.AssertExpectations(t)
+}
+
+func TestClaimCheckInput_Stopped(t *testing.T) {
+	msg := &stream.Message{
+		Attributes: map[string]interface{}{},
+		Body:       "small",
+	}
+
+	data := make(chan *stream.Message, 1)
+	data <- msg
+	close(data)
+
+	input := &claimCheckTestInput{
+		Input:         new(streamMocks.Input),
+		NackableInput: new(streamMocks.NackableInput),
+	}
+	input.Input.On("Run", mock.Anything).Return(nil)
+	input.Input.On("Data").Return(data)
+	input.NackableInput.On("Nack", msg).Return(nil).Once()
+
+	claimCheckInput := stream.NewClaimCheckInputWithInterfaces(monMocks.NewLoggerMockedAll(), monMocks.NewMetricWriterMockedAll(), new(blobMocks.S3API), "test", input, &stream.ClaimCheckInputSettings{
+		Enabled: true,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// nobody reads the messages anymore, so the run has to finish without delivering them
+	err := claimCheckInput.Run(ctx)
+	assert.NoError(t, err)
+
+	input.NackableInput.AssertExpectations(t)
+}
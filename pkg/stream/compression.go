@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"compress/gzip"
 	"fmt"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"sync"
 )
 
 const (
-	CompressionNone = "none"
-	CompressionGZip = "application/gzip"
+	CompressionNone   = "none"
+	CompressionGZip   = "application/gzip"
+	CompressionLz4    = "application/x-lz4"
+	CompressionSnappy = "application/x-snappy"
+	CompressionZstd   = "application/zstd"
 )
 
 type MessageBodyCompressor interface {
@@ -17,8 +24,15 @@ type MessageBodyCompressor interface {
 }
 
 var messageBodyCompressors = map[string]MessageBodyCompressor{
-	CompressionNone: new(noopCompressor),
-	CompressionGZip: new(gZipCompressor),
+	CompressionNone:   new(noopCompressor),
+	CompressionGZip:   new(gZipCompressor),
+	CompressionLz4:    new(lz4Compressor),
+	CompressionSnappy: new(snappyCompressor),
+	CompressionZstd:   new(zstdCompressor),
+}
+
+func AddMessageBodyCompressor(compression string, compressor MessageBodyCompressor) {
+	messageBodyCompressors[compression] = compressor
 }
 
 type noopCompressor struct {
@@ -78,3 +92,116 @@ func (g gZipCompressor) Decompress(body []byte) ([]byte, error) {
 
 	return uncompressed, nil
 }
+
+type lz4Compressor struct {
+}
+
+func (l lz4Compressor) Compress(body []byte) ([]byte, error) {
+	if body == nil {
+		return body, nil
+	}
+
+	var out bytes.Buffer
+	zw := lz4.NewWriter(&out)
+
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("can not write body to lz4: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("can not close lz4 writer: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+func (l lz4Compressor) Decompress(body []byte) ([]byte, error) {
+	if body == nil {
+		return body, nil
+	}
+
+	bufOut := &bytes.Buffer{}
+	reader := lz4.NewReader(bytes.NewBuffer(body))
+
+	if _, err := bufOut.ReadFrom(reader); err != nil {
+		return nil, fmt.Errorf("can not read from lz4 reader: %w", err)
+	}
+
+	return bufOut.Bytes(), nil
+}
+
+type snappyCompressor struct {
+}
+
+func (s snappyCompressor) Compress(body []byte) ([]byte, error) {
+	if body == nil {
+		return body, nil
+	}
+
+	return snappy.Encode(nil, body), nil
+}
+
+func (s snappyCompressor) Decompress(body []byte) ([]byte, error) {
+	if body == nil {
+		return body, nil
+	}
+
+	uncompressed, err := snappy.Decode(nil, body)
+
+	if err != nil {
+		return nil, fmt.Errorf("can not decode snappy body: %w", err)
+	}
+
+	return uncompressed, nil
+}
+
+// the zstd encoder and decoder are safe for concurrent use with EncodeAll and DecodeAll
+// and expensive to create, so they are shared by all messages
+type zstdCompressor struct {
+	once    sync.Once
+	err     error
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func (z *zstdCompressor) init() error {
+	z.once.Do(func() {
+		if z.encoder, z.err = zstd.NewWriter(nil); z.err != nil {
+			return
+		}
+
+		z.decoder, z.err = zstd.NewReader(nil)
+	})
+
+	return z.err
+}
+
+func (z *zstdCompressor) Compress(body []byte) ([]byte, error) {
+	if body == nil {
+		return body, nil
+	}
+
+	if err := z.init(); err != nil {
+		return nil, fmt.Errorf("can not create zstd encoder: %w", err)
+	}
+
+	return z.encoder.EncodeAll(body, nil), nil
+}
+
+func (z *zstdCompressor) Decompress(body []byte) ([]byte, error) {
+	if body == nil {
+		return body, nil
+	}
+
+	if err := z.init(); err != nil {
+		return nil, fmt.Errorf("can not create zstd decoder: %w", err)
+	}
+
+	uncompressed, err := z.decoder.DecodeAll(body, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("can not decode zstd body: %w", err)
+	}
+
+	return uncompressed, nil
+}
@@ -38,6 +38,10 @@ type ConsumerSettings struct {
 	RunnerCount int           `cfg:"runner_count" default:"10" validate:"min=1"`
 	Encoding    string        `cfg:"encoding" default:"application/json"`
 	IdleTimeout time.Duration `cfg:"idle_timeout" default:"10s"`
+
+	Partitioning  ConsumerPartitioningSettings  `cfg:"partitioning"`
+	Deduplication ConsumerDeduplicationSettings `cfg:"deduplication"`
+	Adaptive      ConsumerAdaptiveSettings      `cfg:"adaptive"`
 }
 
 type Consumer struct {
@@ -58,6 +62,11 @@ type Consumer struct {
 	settings  *ConsumerSettings
 	callback  ConsumerCallback
 	processed int32
+
+	partitions    []chan *partitionedMessage
+	acks          *consumerAckSequencer
+	deduplication *ConsumerDeduplication
+	limiter       *consumerConcurrencyLimiter
 }
 
 func NewConsumer(name string, callback ConsumerCallback) *Consumer {
@@ -82,6 +91,15 @@ func (c *Consumer) Boot(config cfg.Config, logger mon.Logger) error {
 	tracer := tracing.ProviderTracer(config, logger)
 
 	defaultMetrics := getConsumerDefaultMetrics()
+
+	if settings.Adaptive.Enabled {
+		defaultMetrics = append(defaultMetrics, getConsumerAdaptiveDefaultMetrics(c.name)...)
+	}
+
+	if upcasting, ok := c.callback.(UpcastingConsumerCallback); ok {
+		defaultMetrics = append(defaultMetrics, getConsumerUpcastingDefaultMetrics(c.name, upcasting.GetModelVersion())...)
+	}
+
 	mw := mon.NewMetricDaemonWriter(defaultMetrics...)
 
 	input := NewConfigurableInput(config, logger, settings.Input)
@@ -91,6 +109,10 @@ func (c *Consumer) Boot(config cfg.Config, logger mon.Logger) error {
 
 	c.BootWithInterfaces(logger, tracer, mw, input, encoder, settings)
 
+	if settings.Deduplication.Enabled {
+		c.SetDeduplication(NewConsumerDeduplication(config, logger, c.name, &settings.Deduplication))
+	}
+
 	return nil
 }
 
@@ -102,6 +124,24 @@ func (c *Consumer) BootWithInterfaces(logger mon.Logger, tracer tracing.Tracer,
 	c.ConsumerAcknowledge = NewConsumerAcknowledgeWithInterfaces(logger, c.input)
 	c.encoder = encoder
 	c.settings = settings
+
+	if settings.Adaptive.Enabled {
+		c.limiter = newConsumerConcurrencyLimiter(&settings.Adaptive, settings.RunnerCount, input)
+	}
+
+	if !settings.Partitioning.Enabled {
+		return
+	}
+
+	c.partitions = make([]chan *partitionedMessage, settings.RunnerCount)
+
+	for i := range c.partitions {
+		c.partitions[i] = make(chan *partitionedMessage, settings.Partitioning.BufferSize)
+	}
+
+	if settings.Partitioning.OrderedAcks {
+		c.acks = newConsumerAckSequencer(c.Acknowledge)
+	}
 }
 
 func (c *Consumer) Run(kernelCtx context.Context) error {
@@ -117,6 +157,11 @@ func (c *Consumer) Run(kernelCtx context.Context) error {
 
 	cfn.GoWithContextf(manualCtx, c.logConsumeCounter, "panic during counter log")
 	cfn.GoWithContextf(manualCtx, c.runCallback, "panic during run of the callback")
+
+	if c.limiter != nil {
+		cfn.GoWithContextf(manualCtx, c.runConcurrencyControl, "panic during concurrency control")
+	}
+
 	// run the input after the counters are running to make sure our coffin does not immediately
 	// die just because Run() immediately returns
 	cfn.GoWithContextf(dyingCtx, c.input.Run, "panic during run of the consumer input")
@@ -124,8 +169,20 @@ func (c *Consumer) Run(kernelCtx context.Context) error {
 	c.wg.Add(c.settings.RunnerCount)
 	cfn.Go(c.stopConsuming)
 
-	for i := 0; i < c.settings.RunnerCount; i++ {
-		cfn.GoWithContextf(manualCtx, c.runConsuming, "panic during consuming")
+	if c.settings.Partitioning.Enabled {
+		cfn.GoWithContextf(manualCtx, c.runPartitioning, "panic during partitioning")
+
+		for i := range c.partitions {
+			partition := c.partitions[i]
+
+			cfn.GoWithContextf(manualCtx, func(ctx context.Context) error {
+				return c.runPartitionedConsuming(ctx, partition)
+			}, "panic during consuming")
+		}
+	} else {
+		for i := 0; i < c.settings.RunnerCount; i++ {
+			cfn.GoWithContextf(manualCtx, c.runConsuming, "panic during consuming")
+		}
 	}
 
 	// stop input on kernel cancel
@@ -174,44 +231,102 @@ func (c *Consumer) runConsuming(ctx context.Context) error {
 	var msg *Message
 
 	for {
+		if c.limiter != nil && !c.limiter.acquire(ctx) {
+			return fmt.Errorf("return from consuming as the coffin is dying")
+		}
+
 		select {
 		case <-ctx.Done():
+			c.releaseRunner()
 			return fmt.Errorf("return from consuming as the coffin is dying")
 
 		case msg, ok = <-c.input.Data():
 		}
 
 		if !ok {
+			c.releaseRunner()
 			return nil
 		}
 
+		if c.limiter != nil {
+			c.limiter.received()
+		}
+
 		c.doConsuming(msg)
+		c.countProcessed()
+	}
+}
 
-		atomic.AddInt32(&c.processed, 1)
-		c.mw.WriteOne(&mon.MetricDatum{
-			MetricName: metricNameConsumerProcessedCount,
-			Value:      1.0,
-		})
+func (c *Consumer) releaseRunner() {
+	if c.limiter != nil {
+		c.limiter.release()
 	}
 }
 
+func (c *Consumer) countProcessed() {
+	atomic.AddInt32(&c.processed, 1)
+	c.mw.WriteOne(&mon.MetricDatum{
+		MetricName: metricNameConsumerProcessedCount,
+		Value:      1.0,
+	})
+}
+
 func (c *Consumer) doConsuming(msg *Message) {
-	defer c.recover()
+	ctx, ack := c.consumeLimited(msg)
+
+	if !ack {
+		c.Nack(ctx, msg)
+		return
+	}
+
+	c.Acknowledge(ctx, msg)
+}
+
+// consumeLimited consumes the message and reports its latency and outcome to the concurrency limiter.
+// The runner has to hold a slot of the limiter.
+func (c *Consumer) consumeLimited(msg *Message) (context.Context, bool) {
+	if c.limiter == nil {
+		ctx, ack, _ := c.consume(msg)
+		return ctx, ack
+	}
+
+	start := time.Now()
+	ctx, ack, err := c.consume(msg)
+	c.limiter.done(time.Since(start), err != nil)
+
+	return ctx, ack
+}
+
+// consume decodes the message and hands it to the callback. It returns whether the message should be acknowledged
+// and the error of the decoding or the callback.
+func (c *Consumer) consume(msg *Message) (ctx context.Context, ack bool, err error) {
+	defer c.recover(&err)
+
+	ctx = context.Background()
+
+	var deduplicationId string
+
+	if c.deduplication != nil {
+		var duplicate bool
+
+		if deduplicationId, duplicate = c.deduplication.IsDuplicate(ctx, msg); duplicate {
+			return ctx, true, nil
+		}
+	}
 
-	ctx := context.Background()
 	model := c.callback.GetModel(msg.Attributes)
 
-	ctx, attributes, err := c.encoder.Decode(ctx, msg, model)
+	ctx, attributes, err := c.decode(ctx, msg, model)
 
 	if err != nil {
 		c.logger.WithContext(ctx).Error(err, "an error occurred during the consume operation")
-		return
+		return ctx, false, err
 	}
 
 	ctx, span := c.tracer.StartSpanFromContext(ctx, c.id)
 	defer span.Finish()
 
-	ack, err := c.callback.Consume(ctx, model, attributes)
+	ack, err = c.callback.Consume(ctx, model, attributes)
 
 	if err != nil {
 		// one could think that we should just initialize this logger once, but the ctx used
@@ -220,20 +335,21 @@ func (c *Consumer) doConsuming(msg *Message) {
 		c.logger.WithContext(ctx).Error(err, "an error occurred during the consume operation")
 	}
 
-	if !ack {
-		return
+	if ack && c.deduplication != nil {
+		c.deduplication.MarkConsumed(ctx, deduplicationId)
 	}
 
-	c.Acknowledge(ctx, msg)
+	return ctx, ack, err
 }
 
-func (c *Consumer) recover() {
+func (c *Consumer) recover(result *error) {
 	err := coffin.ResolveRecovery(recover())
 	if err == nil {
 		return
 	}
 
 	c.logger.Error(err, err.Error())
+	*result = err
 }
 
 func (c *Consumer) stopConsuming() error {
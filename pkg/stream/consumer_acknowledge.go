@@ -32,6 +32,22 @@ func (c *ConsumerAcknowledge) Acknowledge(ctx context.Context, msg *Message) {
 	}
 }
 
+// Nack hands a message which was not acknowledged back to the input, so it can redeliver it.
+func (c *ConsumerAcknowledge) Nack(ctx context.Context, msg *Message) {
+	var ok bool
+	var nackInput NackableInput
+
+	if nackInput, ok = c.input.(NackableInput); !ok {
+		return
+	}
+
+	err := nackInput.Nack(msg)
+
+	if err != nil {
+		c.logger.WithContext(ctx).Error(err, "could not nack the message")
+	}
+}
+
 func (c *ConsumerAcknowledge) AcknowledgeBatch(ctx context.Context, msg []*Message) {
 	var ok bool
 	var ackInput AcknowledgeableInput
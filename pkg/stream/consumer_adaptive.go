@@ -0,0 +1,230 @@
+package stream
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/mon"
+	"sync"
+	"time"
+)
+
+const metricNameConsumerConcurrency = "ConsumerConcurrency"
+
+// ConsumerAdaptiveSettings enable the adaptive concurrency of a consumer. The number of active runners starts
+// at the runner count and is adjusted in every interval: it is decreased multiplicatively if the average
+// latency of the callback or its error rate exceed their targets and increased additively otherwise.
+type ConsumerAdaptiveSettings struct {
+	Enabled    bool `cfg:"enabled" default:"false"`
+	MinRunners int  `cfg:"min_runners" default:"1" validate:"min=1"`
+	// TargetLatency is the highest average latency of the callback which is accepted without decreasing the concurrency
+	TargetLatency time.Duration `cfg:"target_latency" default:"1s"`
+	// MaxErrorRate is the highest share of failed messages which is accepted without decreasing the concurrency
+	MaxErrorRate float64       `cfg:"max_error_rate" default:"0.1" validate:"min=0,max=1"`
+	Interval     time.Duration `cfg:"interval" default:"10s"`
+	// Increase is the number of runners added per interval
+	Increase int `cfg:"increase" default:"1" validate:"min=1"`
+	// Decrease is the factor the number of runners is multiplied with on overload
+	Decrease float64 `cfg:"decrease" default:"0.5" validate:"gt=0,lt=1"`
+	// MaxInFlight pauses the input while more messages are in progress, 0 uses the current concurrency
+	MaxInFlight int `cfg:"max_in_flight" default:"0" validate:"min=0"`
+}
+
+// consumerConcurrencyLimiter limits the number of runners consuming at the same time and adjusts the limit
+// with the latency and the errors of the consumed messages.
+type consumerConcurrencyLimiter struct {
+	settings *ConsumerAdaptiveSettings
+	max      int
+
+	lck      sync.Mutex
+	changed  chan struct{}
+	limit    int
+	active   int
+	inFlight int
+	paused   bool
+
+	count   int
+	failed  int
+	latency time.Duration
+
+	pause  func()
+	resume func()
+}
+
+func newConsumerConcurrencyLimiter(settings *ConsumerAdaptiveSettings, max int, input Input) *consumerConcurrencyLimiter {
+	limiter := &consumerConcurrencyLimiter{
+		settings: settings,
+		max:      max,
+		changed:  make(chan struct{}),
+		limit:    max,
+		pause:    func() {},
+		resume:   func() {},
+	}
+
+	if pausable, ok := input.(PausableInput); ok {
+		limiter.pause = pausable.Pause
+		limiter.resume = pausable.Resume
+	}
+
+	return limiter
+}
+
+// acquire blocks until the runner is allowed to consume a message. It returns false if the context is done.
+func (l *consumerConcurrencyLimiter) acquire(ctx context.Context) bool {
+	for {
+		l.lck.Lock()
+
+		if l.active < l.limit {
+			l.active++
+			l.lck.Unlock()
+
+			return true
+		}
+
+		changed := l.changed
+		l.lck.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// release frees the slot of a runner which didn't consume a message.
+func (l *consumerConcurrencyLimiter) release() {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	l.active--
+	l.broadcast()
+}
+
+// received counts a message which was read from the input, but is not done yet.
+func (l *consumerConcurrencyLimiter) received() {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	l.inFlight++
+	l.updatePause()
+}
+
+// done frees the slot of a runner and records the outcome of the consumed message.
+func (l *consumerConcurrencyLimiter) done(latency time.Duration, failed bool) {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	l.active--
+	l.inFlight--
+	l.count++
+	l.latency += latency
+
+	if failed {
+		l.failed++
+	}
+
+	l.updatePause()
+	l.broadcast()
+}
+
+// adjust applies the outcome of the messages consumed since the last call to the limit and returns the new limit.
+func (l *consumerConcurrencyLimiter) adjust() int {
+	l.lck.Lock()
+	defer l.lck.Unlock()
+
+	count, failed, latency := l.count, l.failed, l.latency
+	l.count, l.failed, l.latency = 0, 0, 0
+
+	if count == 0 {
+		return l.limit
+	}
+
+	averageLatency := latency / time.Duration(count)
+	errorRate := float64(failed) / float64(count)
+
+	if averageLatency > l.settings.TargetLatency || errorRate > l.settings.MaxErrorRate {
+		l.limit = int(float64(l.limit) * l.settings.Decrease)
+	} else {
+		l.limit += l.settings.Increase
+	}
+
+	if l.limit < l.settings.MinRunners {
+		l.limit = l.settings.MinRunners
+	}
+
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+
+	l.updatePause()
+	l.broadcast()
+
+	return l.limit
+}
+
+func (l *consumerConcurrencyLimiter) updatePause() {
+	maxInFlight := l.settings.MaxInFlight
+
+	if maxInFlight == 0 {
+		maxInFlight = l.limit
+	}
+
+	switch {
+	case !l.paused && l.inFlight >= maxInFlight:
+		l.paused = true
+		l.pause()
+
+	case l.paused && l.inFlight < maxInFlight:
+		l.paused = false
+		l.resume()
+	}
+}
+
+func (l *consumerConcurrencyLimiter) broadcast() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (c *Consumer) runConcurrencyControl(ctx context.Context) error {
+	defer c.logger.Debug("runConcurrencyControl is ending")
+
+	ticker := time.NewTicker(c.settings.Adaptive.Interval)
+	defer ticker.Stop()
+
+	c.writeConcurrencyMetric(c.limiter.max)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			c.writeConcurrencyMetric(c.limiter.adjust())
+		}
+	}
+}
+
+func (c *Consumer) writeConcurrencyMetric(limit int) {
+	c.mw.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: metricNameConsumerConcurrency,
+		Dimensions: map[string]string{
+			"Consumer": c.name,
+		},
+		Unit:  mon.UnitCountAverage,
+		Value: float64(limit),
+	})
+}
+
+func getConsumerAdaptiveDefaultMetrics(name string) mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerConcurrency,
+			Dimensions: map[string]string{
+				"Consumer": name,
+			},
+			Unit:  mon.UnitCountAverage,
+			Value: 0.0,
+		},
+	}
+}
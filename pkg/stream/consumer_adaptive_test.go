@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type pausableTestInput struct {
+	Input
+	paused bool
+}
+
+func (i *pausableTestInput) Pause() {
+	i.paused = true
+}
+
+func (i *pausableTestInput) Resume() {
+	i.paused = false
+}
+
+func newTestConcurrencyLimiter(input Input) *consumerConcurrencyLimiter {
+	return newConsumerConcurrencyLimiter(&ConsumerAdaptiveSettings{
+		MinRunners:    2,
+		TargetLatency: time.Second,
+		MaxErrorRate:  0.1,
+		Increase:      1,
+		Decrease:      0.5,
+	}, 10, input)
+}
+
+func consumeWithLimiter(limiter *consumerConcurrencyLimiter, count int, latency time.Duration, failed bool) {
+	for i := 0; i < count; i++ {
+		limiter.acquire(context.Background())
+		limiter.received()
+		limiter.done(latency, failed)
+	}
+}
+
+func TestConsumerConcurrencyLimiter_Adjust(t *testing.T) {
+	limiter := newTestConcurrencyLimiter(nil)
+
+	assert.Equal(t, 10, limiter.adjust(), "the limit should not change without messages")
+
+	consumeWithLimiter(limiter, 10, 2*time.Second, false)
+	assert.Equal(t, 5, limiter.adjust(), "the limit should be halved if the latency is too high")
+
+	consumeWithLimiter(limiter, 8, 100*time.Millisecond, false)
+	consumeWithLimiter(limiter, 2, 100*time.Millisecond, true)
+	assert.Equal(t, 2, limiter.adjust(), "the limit should be halved if the error rate is too high")
+
+	consumeWithLimiter(limiter, 10, 2*time.Second, false)
+	assert.Equal(t, 2, limiter.adjust(), "the limit should not fall below the min runners")
+
+	consumeWithLimiter(limiter, 10, 100*time.Millisecond, false)
+	assert.Equal(t, 3, limiter.adjust(), "the limit should be increased if the consumer is healthy")
+
+	for i := 0; i < 10; i++ {
+		consumeWithLimiter(limiter, 1, 100*time.Millisecond, false)
+		limiter.adjust()
+	}
+
+	assert.Equal(t, 10, limiter.limit, "the limit should not exceed the runner count")
+}
+
+func TestConsumerConcurrencyLimiter_Acquire(t *testing.T) {
+	limiter := newTestConcurrencyLimiter(nil)
+	limiter.limit = 2
+
+	assert.True(t, limiter.acquire(context.Background()))
+	assert.True(t, limiter.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.False(t, limiter.acquire(ctx), "the third runner should wait until the context is done")
+
+	acquired := make(chan bool)
+
+	go func() {
+		acquired <- limiter.acquire(context.Background())
+	}()
+
+	limiter.release()
+	assert.True(t, <-acquired, "the waiting runner should get the released slot")
+}
+
+func TestConsumerConcurrencyLimiter_PauseInput(t *testing.T) {
+	input := &pausableTestInput{}
+	limiter := newTestConcurrencyLimiter(input)
+	limiter.limit = 2
+
+	limiter.acquire(context.Background())
+	limiter.received()
+	assert.False(t, input.paused)
+
+	limiter.acquire(context.Background())
+	limiter.received()
+	assert.True(t, input.paused, "the input should be paused if the in flight messages reach the limit")
+
+	limiter.done(time.Millisecond, false)
+	assert.False(t, input.paused, "the input should be resumed if a message is done")
+}
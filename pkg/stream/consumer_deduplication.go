@@ -0,0 +1,173 @@
+package stream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/kvstore"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/spf13/cast"
+	"time"
+)
+
+const (
+	DeduplicationStrategyMessageId = "message_id"
+	DeduplicationStrategyAttribute = "attribute"
+	DeduplicationStrategyHash      = "hash"
+
+	metricNameConsumerDuplicateCount = "ConsumerDuplicateCount"
+)
+
+// ConsumerDeduplicationSettings enable skipping messages which were already consumed successfully. The id of a
+// message is the sqs message id, the value of the configured attribute or the sha256 of the body. It is stored
+// in a kvstore of the backend redis, ddb or inMemory for the duration of the ttl after the message was acknowledged.
+// The deduplication is best effort: a message is only marked after it was consumed, so duplicates which are
+// consumed concurrently, e.g. by another runner or instance, are all consumed. Consumers which must not process
+// a message twice still have to be idempotent.
+type ConsumerDeduplicationSettings struct {
+	Enabled   bool          `cfg:"enabled" default:"false"`
+	Strategy  string        `cfg:"strategy" default:"message_id" validate:"oneof=message_id attribute hash"`
+	Attribute string        `cfg:"attribute"`
+	Backend   string        `cfg:"backend" default:"redis" validate:"oneof=redis ddb inMemory"`
+	Ttl       time.Duration `cfg:"ttl" default:"24h"`
+}
+
+type ConsumerDeduplication struct {
+	logger   mon.Logger
+	metric   mon.MetricWriter
+	store    kvstore.KvStore
+	name     string
+	settings *ConsumerDeduplicationSettings
+}
+
+func NewConsumerDeduplication(config cfg.Config, logger mon.Logger, name string, settings *ConsumerDeduplicationSettings) *ConsumerDeduplication {
+	storeSettings := &kvstore.Settings{
+		Name:      fmt.Sprintf("consumer-deduplication-%s", name),
+		Ttl:       settings.Ttl,
+		BatchSize: 1,
+	}
+
+	var store kvstore.KvStore
+
+	switch settings.Backend {
+	case kvstore.TypeDdb:
+		store = kvstore.NewDdbKvStore(config, logger, storeSettings)
+	case kvstore.TypeInMemory:
+		store = kvstore.NewInMemoryKvStore(config, logger, storeSettings)
+	default:
+		store = kvstore.NewRedisKvStore(config, logger, storeSettings)
+	}
+
+	defaults := getConsumerDeduplicationDefaultMetrics(name)
+	metric := mon.NewMetricDaemonWriter(defaults...)
+
+	return NewConsumerDeduplicationWithInterfaces(logger, metric, store, name, settings)
+}
+
+func NewConsumerDeduplicationWithInterfaces(logger mon.Logger, metric mon.MetricWriter, store kvstore.KvStore, name string, settings *ConsumerDeduplicationSettings) *ConsumerDeduplication {
+	return &ConsumerDeduplication{
+		logger:   logger.WithChannel("consumer-deduplication"),
+		metric:   metric,
+		store:    store,
+		name:     name,
+		settings: settings,
+	}
+}
+
+// IsDuplicate returns the id of the message and whether a message with the same id was already consumed.
+// If the id or the state of the message can't be determined, the message is treated as new. A message with
+// the same id which is still in progress is not detected.
+func (d *ConsumerDeduplication) IsDuplicate(ctx context.Context, msg *Message) (string, bool) {
+	logger := d.logger.WithContext(ctx)
+	id, err := d.messageId(msg)
+
+	if err != nil {
+		logger.Warnf("can not deduplicate the message: %s", err.Error())
+		return "", false
+	}
+
+	exists, err := d.store.Contains(ctx, id)
+
+	if err != nil {
+		logger.Errorf(err, "can not check if the message %s was already consumed", id)
+		return id, false
+	}
+
+	if !exists {
+		return id, false
+	}
+
+	d.metric.WriteOne(&mon.MetricDatum{
+		MetricName: metricNameConsumerDuplicateCount,
+		Dimensions: map[string]string{
+			"Consumer": d.name,
+		},
+		Unit:  mon.UnitCount,
+		Value: 1.0,
+	})
+
+	return id, true
+}
+
+// MarkConsumed stores the id of a successfully consumed message.
+func (d *ConsumerDeduplication) MarkConsumed(ctx context.Context, id string) {
+	if id == "" {
+		return
+	}
+
+	if err := d.store.Put(ctx, id, true); err != nil {
+		d.logger.WithContext(ctx).Errorf(err, "can not mark the message %s as consumed", id)
+	}
+}
+
+func (d *ConsumerDeduplication) messageId(msg *Message) (string, error) {
+	switch d.settings.Strategy {
+	case DeduplicationStrategyHash:
+		hash := sha256.Sum256([]byte(msg.Body))
+
+		return hex.EncodeToString(hash[:]), nil
+
+	case DeduplicationStrategyAttribute:
+		return d.attribute(msg, d.settings.Attribute)
+
+	default:
+		return d.attribute(msg, AttributeSqsMessageId)
+	}
+}
+
+func (d *ConsumerDeduplication) attribute(msg *Message, attribute string) (string, error) {
+	value, ok := msg.Attributes[attribute]
+
+	if !ok {
+		return "", fmt.Errorf("the message has no attribute %s", attribute)
+	}
+
+	id, err := cast.ToStringE(value)
+
+	if err != nil || id == "" {
+		return "", fmt.Errorf("the attribute %s of the message is no valid id: %v", attribute, value)
+	}
+
+	return id, nil
+}
+
+// SetDeduplication makes the consumer skip and acknowledge messages which were already consumed.
+func (c *Consumer) SetDeduplication(deduplication *ConsumerDeduplication) {
+	c.deduplication = deduplication
+}
+
+func getConsumerDeduplicationDefaultMetrics(name string) mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerDuplicateCount,
+			Dimensions: map[string]string{
+				"Consumer": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+	}
+}
@@ -0,0 +1,135 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/kvstore"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type deduplicationTestCallback struct {
+	consumed []int
+}
+
+func (c *deduplicationTestCallback) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (c *deduplicationTestCallback) GetModel(_ map[string]interface{}) interface{} {
+	var index int
+	return &index
+}
+
+func (c *deduplicationTestCallback) Consume(_ context.Context, model interface{}, _ map[string]interface{}) (bool, error) {
+	index := *model.(*int)
+	c.consumed = append(c.consumed, index)
+
+	return index != 3, nil
+}
+
+func newDeduplicationTestMetric(duplicates *int) *monMocks.MetricWriter {
+	mw := new(monMocks.MetricWriter)
+	mw.On("WriteOne", mock.AnythingOfType("*mon.MetricDatum")).Run(func(args mock.Arguments) {
+		if args.Get(0).(*mon.MetricDatum).MetricName == "ConsumerDuplicateCount" {
+			*duplicates++
+		}
+	})
+
+	return mw
+}
+
+func TestConsumer_Deduplication(t *testing.T) {
+	data := make(chan *stream.Message, 5)
+	data <- stream.NewJsonMessage("1", map[string]interface{}{stream.AttributeSqsMessageId: "a"})
+	data <- stream.NewJsonMessage("2", map[string]interface{}{stream.AttributeSqsMessageId: "b"})
+	data <- stream.NewJsonMessage("1", map[string]interface{}{stream.AttributeSqsMessageId: "a"})
+	data <- stream.NewJsonMessage("3", map[string]interface{}{stream.AttributeSqsMessageId: "c"})
+	data <- stream.NewJsonMessage("3", map[string]interface{}{stream.AttributeSqsMessageId: "c"})
+	close(data)
+
+	acked := make([][]*stream.Message, 0)
+	input := &acknowledgingInput{
+		Input: new(mocks.Input),
+		acked: &acked,
+	}
+	input.On("Data").Return(data)
+	input.On("Run", mock.Anything).Return(nil)
+	input.On("Stop")
+
+	duplicates := 0
+	logger := monMocks.NewLoggerMockedAll()
+	mw := newDeduplicationTestMetric(&duplicates)
+	store := kvstore.NewInMemoryKvStoreWithInterfaces(&kvstore.Settings{})
+	settings := &stream.ConsumerDeduplicationSettings{
+		Enabled:  true,
+		Strategy: stream.DeduplicationStrategyMessageId,
+	}
+
+	callback := &deduplicationTestCallback{}
+
+	consumer := stream.NewConsumer("test", callback)
+	consumer.BootWithInterfaces(logger, tracing.NewNoopTracer(), mw, input, stream.NewMessageEncoder(&stream.MessageEncoderSettings{}), &stream.ConsumerSettings{
+		Input:       "test",
+		RunnerCount: 1,
+		IdleTimeout: time.Second,
+	})
+	consumer.SetDeduplication(stream.NewConsumerDeduplicationWithInterfaces(logger, mw, store, "test", settings))
+
+	err := consumer.Run(context.Background())
+	assert.NoError(t, err)
+
+	ackedIds := make([]string, 0)
+
+	for _, batch := range acked {
+		for _, msg := range batch {
+			ackedIds = append(ackedIds, msg.Attributes[stream.AttributeSqsMessageId].(string))
+		}
+	}
+
+	assert.Equal(t, []int{1, 2, 3, 3}, callback.consumed, "the duplicate should be skipped and the failed message retried")
+	assert.Equal(t, []string{"a", "b", "a"}, ackedIds, "the duplicate should be acknowledged")
+	assert.Equal(t, 1, duplicates)
+}
+
+func TestConsumerDeduplication_Strategies(t *testing.T) {
+	ctx := context.Background()
+	duplicates := 0
+	logger := monMocks.NewLoggerMockedAll()
+	mw := newDeduplicationTestMetric(&duplicates)
+
+	hashed := stream.NewConsumerDeduplicationWithInterfaces(logger, mw, kvstore.NewInMemoryKvStoreWithInterfaces(&kvstore.Settings{}), "test", &stream.ConsumerDeduplicationSettings{
+		Strategy: stream.DeduplicationStrategyHash,
+	})
+
+	id, duplicate := hashed.IsDuplicate(ctx, stream.NewMessage("body"))
+	assert.Equal(t, "230d8358dc8e8890b4c58deeb62912ee2f20357ae92a5cc861b98e68fe31acb5", id)
+	assert.False(t, duplicate)
+
+	hashed.MarkConsumed(ctx, id)
+
+	_, duplicate = hashed.IsDuplicate(ctx, stream.NewMessage("body"))
+	assert.True(t, duplicate)
+
+	attribute := stream.NewConsumerDeduplicationWithInterfaces(logger, mw, kvstore.NewInMemoryKvStoreWithInterfaces(&kvstore.Settings{}), "test", &stream.ConsumerDeduplicationSettings{
+		Strategy:  stream.DeduplicationStrategyAttribute,
+		Attribute: "eventId",
+	})
+
+	id, duplicate = attribute.IsDuplicate(ctx, stream.NewMessage("body", map[string]interface{}{"eventId": 42}))
+	assert.Equal(t, "42", id)
+	assert.False(t, duplicate)
+
+	id, duplicate = attribute.IsDuplicate(ctx, stream.NewMessage("body"))
+	assert.Equal(t, "", id, "a message without id should not be deduplicated")
+	assert.False(t, duplicate)
+
+	assert.Equal(t, 1, duplicates)
+}
@@ -0,0 +1,218 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cast"
+	"hash/fnv"
+	"sync"
+)
+
+// ConsumerPartitioningSettings enable the partitioned mode of a consumer. Every message is routed to
+// a runner by its key, so messages with the same key are consumed one after another in the order of
+// the input while messages with different keys are still consumed in parallel. Messages without a key
+// are distributed round robin.
+type ConsumerPartitioningSettings struct {
+	Enabled bool `cfg:"enabled" default:"false"`
+	// Attribute of the message containing the key, used if the callback is no PartitionedConsumerCallback
+	Attribute string `cfg:"attribute" default:"partitionKey"`
+	// BufferSize is the number of messages queued per runner
+	BufferSize int `cfg:"buffer_size" default:"10" validate:"min=0"`
+	// OrderedAcks delays the acknowledgement of a message until all messages received before it are acknowledged,
+	// so inputs tracking their position by the acknowledged messages, like kinesis, never skip a message which is
+	// still in progress. After a failed message no later message is acknowledged anymore, they are read again
+	// after a restart. Don't enable it for inputs redelivering single messages like sqs.
+	OrderedAcks bool `cfg:"ordered_acks" default:"false"`
+}
+
+// A PartitionedConsumerCallback provides the key of a message for the partitioned mode of the consumer.
+//go:generate mockery -name=PartitionedConsumerCallback
+type PartitionedConsumerCallback interface {
+	GetPartitionKey(attributes map[string]interface{}) string
+}
+
+type partitionedMessage struct {
+	msg *Message
+	seq uint64
+}
+
+type pendingAck struct {
+	ctx context.Context
+	msg *Message
+	ack bool
+}
+
+// consumerAckSequencer releases the acknowledgements of the messages in the order of their sequence numbers.
+// The sequence is held at the first failed message, so no later message overtakes it.
+type consumerAckSequencer struct {
+	lck     sync.Mutex
+	seq     uint64
+	next    uint64
+	failed  bool
+	pending map[uint64]*pendingAck
+	ack     func(ctx context.Context, msg *Message)
+}
+
+func newConsumerAckSequencer(ack func(ctx context.Context, msg *Message)) *consumerAckSequencer {
+	return &consumerAckSequencer{
+		pending: make(map[uint64]*pendingAck),
+		ack:     ack,
+	}
+}
+
+func (s *consumerAckSequencer) register() uint64 {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	seq := s.seq
+	s.seq++
+
+	return seq
+}
+
+func (s *consumerAckSequencer) done(ctx context.Context, seq uint64, msg *Message, ack bool) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	if s.failed {
+		return
+	}
+
+	s.pending[seq] = &pendingAck{
+		ctx: ctx,
+		msg: msg,
+		ack: ack,
+	}
+
+	for {
+		pending, ok := s.pending[s.next]
+
+		if !ok {
+			return
+		}
+
+		if !pending.ack {
+			s.failed = true
+			s.pending = make(map[uint64]*pendingAck)
+
+			return
+		}
+
+		s.ack(pending.ctx, pending.msg)
+
+		delete(s.pending, s.next)
+		s.next++
+	}
+}
+
+func (c *Consumer) runPartitioning(ctx context.Context) error {
+	defer c.logger.Debug("runPartitioning is ending")
+	defer func() {
+		for _, partition := range c.partitions {
+			close(partition)
+		}
+	}()
+
+	var next int
+
+	for {
+		var ok bool
+		var msg *Message
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case msg, ok = <-c.input.Data():
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if c.limiter != nil {
+			c.limiter.received()
+		}
+
+		pm := &partitionedMessage{
+			msg: msg,
+		}
+
+		if c.acks != nil {
+			pm.seq = c.acks.register()
+		}
+
+		index, keyed := c.partitionIndex(msg)
+
+		if !keyed {
+			index = next
+			next = (next + 1) % len(c.partitions)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case c.partitions[index] <- pm:
+		}
+	}
+}
+
+func (c *Consumer) runPartitionedConsuming(ctx context.Context, partition chan *partitionedMessage) error {
+	defer c.logger.Debug("runPartitionedConsuming is ending")
+	defer c.wg.Done()
+
+	var ok bool
+	var pm *partitionedMessage
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("return from consuming as the coffin is dying")
+
+		case pm, ok = <-partition:
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if c.limiter != nil && !c.limiter.acquire(ctx) {
+			return fmt.Errorf("return from consuming as the coffin is dying")
+		}
+
+		msgCtx, ack := c.consumeLimited(pm.msg)
+
+		if !ack {
+			c.Nack(msgCtx, pm.msg)
+		}
+
+		if c.acks != nil {
+			c.acks.done(msgCtx, pm.seq, pm.msg, ack)
+		} else if ack {
+			c.Acknowledge(msgCtx, pm.msg)
+		}
+
+		c.countProcessed()
+	}
+}
+
+// partitionIndex returns the runner of the message and false if the message has no key.
+func (c *Consumer) partitionIndex(msg *Message) (int, bool) {
+	var key string
+
+	if partitioned, ok := c.callback.(PartitionedConsumerCallback); ok {
+		key = partitioned.GetPartitionKey(msg.Attributes)
+	} else if value, ok := msg.Attributes[c.settings.Partitioning.Attribute]; ok {
+		key = cast.ToString(value)
+	}
+
+	if key == "" {
+		return 0, false
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+
+	return int(hash.Sum32() % uint32(len(c.partitions))), true
+}
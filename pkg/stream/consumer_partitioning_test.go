@@ -0,0 +1,142 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+type partitioningTestCallback struct {
+	lck      sync.Mutex
+	consumed map[string][]int
+}
+
+func (c *partitioningTestCallback) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (c *partitioningTestCallback) GetModel(_ map[string]interface{}) interface{} {
+	var index int
+	return &index
+}
+
+func (c *partitioningTestCallback) Consume(_ context.Context, model interface{}, attributes map[string]interface{}) (bool, error) {
+	index := *model.(*int)
+
+	// slow down some messages so the messages of other keys would overtake them
+	if index%4 == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	key := attributes["entity"].(string)
+	c.consumed[key] = append(c.consumed[key], index)
+
+	return index != 5, nil
+}
+
+type partitioningKeyedTestCallback struct {
+	*partitioningTestCallback
+}
+
+func (c *partitioningKeyedTestCallback) GetPartitionKey(attributes map[string]interface{}) string {
+	return attributes["entity"].(string)
+}
+
+func runPartitionedConsumer(t *testing.T, callback stream.ConsumerCallback, attribute string) (map[string][]int, []int) {
+	data := make(chan *stream.Message, 30)
+
+	for i := 0; i < 30; i++ {
+		entity := fmt.Sprintf("entity-%d", i%3)
+
+		data <- stream.NewJsonMessage(fmt.Sprint(i), map[string]interface{}{
+			"entity": entity,
+		})
+	}
+	close(data)
+
+	acked := make([][]*stream.Message, 0)
+	input := &acknowledgingInput{
+		Input: new(mocks.Input),
+		acked: &acked,
+	}
+	input.On("Data").Return(data)
+	input.On("Run", mock.Anything).Return(nil)
+	input.On("Stop")
+
+	consumer := stream.NewConsumer("test", callback)
+	consumer.BootWithInterfaces(monMocks.NewLoggerMockedAll(), tracing.NewNoopTracer(), monMocks.NewMetricWriterMockedAll(), input, stream.NewMessageEncoder(&stream.MessageEncoderSettings{}), &stream.ConsumerSettings{
+		Input:       "test",
+		RunnerCount: 3,
+		IdleTimeout: time.Second,
+		Partitioning: stream.ConsumerPartitioningSettings{
+			Enabled:     true,
+			Attribute:   attribute,
+			BufferSize:  10,
+			OrderedAcks: true,
+		},
+	})
+
+	err := consumer.Run(context.Background())
+	assert.NoError(t, err)
+
+	ackedIndices := make([]int, 0, len(acked))
+
+	for _, batch := range acked {
+		for _, msg := range batch {
+			var index int
+			_, err = fmt.Sscan(msg.Body, &index)
+			assert.NoError(t, err)
+
+			ackedIndices = append(ackedIndices, index)
+		}
+	}
+
+	return callback.(interface{ getConsumed() map[string][]int }).getConsumed(), ackedIndices
+}
+
+func (c *partitioningTestCallback) getConsumed() map[string][]int {
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	return c.consumed
+}
+
+func TestConsumer_Partitioning(t *testing.T) {
+	expectedConsumed := map[string][]int{}
+	expectedAcked := make([]int, 0)
+
+	for i := 0; i < 30; i++ {
+		entity := fmt.Sprintf("entity-%d", i%3)
+		expectedConsumed[entity] = append(expectedConsumed[entity], i)
+
+		// the message 5 fails, so no later message is acknowledged
+		if i < 5 {
+			expectedAcked = append(expectedAcked, i)
+		}
+	}
+
+	for name, callback := range map[string]stream.ConsumerCallback{
+		"attribute": &partitioningTestCallback{consumed: map[string][]int{}},
+		"callback":  &partitioningKeyedTestCallback{&partitioningTestCallback{consumed: map[string][]int{}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			consumed, acked := runPartitionedConsumer(t, callback, "entity")
+
+			assert.Equal(t, expectedConsumed, consumed, "the messages of every key should be consumed in order")
+			assert.Equal(t, expectedAcked, acked, "the messages should be acknowledged in the order of the input until the failed one")
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/spf13/cast"
+	"strconv"
+)
+
+const (
+	AttributeModelVersion = "modelVersion"
+
+	metricNameConsumerModelVersionCount = "ConsumerModelVersionCount"
+)
+
+// A VersionedModel is stamped with its version by the producer, so consumers can upcast older versions.
+type VersionedModel interface {
+	GetModelVersion() int
+}
+
+// An Upcaster transforms the raw payload of a model from one version to the next one.
+type Upcaster func(payload map[string]interface{}) (map[string]interface{}, error)
+
+// An UpcastingConsumerCallback consumes models of the version it returns. Messages of older versions
+// are decoded into a raw payload and transformed by the upcasters, which are indexed by the version
+// they upcast from, until they reached the version of the callback. Messages without a version
+// are treated as version 1.
+//go:generate mockery -name=UpcastingConsumerCallback
+type UpcastingConsumerCallback interface {
+	GetModelVersion() int
+	GetUpcasters() map[int]Upcaster
+}
+
+type modelVersionEncodeHandler struct{}
+
+func NewModelVersionEncodeHandler() EncodeHandler {
+	return &modelVersionEncodeHandler{}
+}
+
+func (h *modelVersionEncodeHandler) Encode(ctx context.Context, data interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error) {
+	versioned, ok := data.(VersionedModel)
+
+	if !ok {
+		return ctx, attributes, nil
+	}
+
+	if _, ok := attributes[AttributeModelVersion]; !ok {
+		attributes[AttributeModelVersion] = versioned.GetModelVersion()
+	}
+
+	return ctx, attributes, nil
+}
+
+func (h *modelVersionEncodeHandler) Decode(ctx context.Context, _ interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error) {
+	return ctx, attributes, nil
+}
+
+func getModelVersion(attributes map[string]interface{}) (int, error) {
+	version, ok := attributes[AttributeModelVersion]
+
+	if !ok {
+		return 1, nil
+	}
+
+	versionInt, err := cast.ToIntE(version)
+
+	if err != nil {
+		return 0, fmt.Errorf("the %s attribute '%v' is not a valid version: %w", AttributeModelVersion, version, err)
+	}
+
+	return versionInt, nil
+}
+
+func (c *Consumer) decode(ctx context.Context, msg *Message, model interface{}) (context.Context, map[string]interface{}, error) {
+	upcasting, ok := c.callback.(UpcastingConsumerCallback)
+
+	if !ok {
+		return c.encoder.Decode(ctx, msg, model)
+	}
+
+	version, err := getModelVersion(msg.Attributes)
+
+	if err != nil {
+		return ctx, msg.Attributes, err
+	}
+
+	current := upcasting.GetModelVersion()
+
+	if version > current {
+		return ctx, msg.Attributes, fmt.Errorf("can not consume model version %d as the consumer only knows versions up to %d", version, current)
+	}
+
+	c.mw.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: metricNameConsumerModelVersionCount,
+		Dimensions: map[string]string{
+			"Consumer":     c.name,
+			"ModelVersion": strconv.Itoa(version),
+		},
+		Unit:  mon.UnitCount,
+		Value: 1.0,
+	})
+
+	if version == current {
+		return c.encoder.Decode(ctx, msg, model)
+	}
+
+	// the upcasters work on the raw json payload, other encodings can't be decoded into it without losing types
+	if encoding := c.messageEncoding(msg); encoding != EncodingJson {
+		return ctx, msg.Attributes, fmt.Errorf("can not upcast model version %d with encoding %s, only %s is supported", version, encoding, EncodingJson)
+	}
+
+	payload := make(map[string]interface{})
+	ctx, attributes, err := c.encoder.Decode(ctx, msg, &payload)
+
+	if err != nil {
+		return ctx, attributes, err
+	}
+
+	upcasters := upcasting.GetUpcasters()
+
+	for ; version < current; version++ {
+		upcaster, ok := upcasters[version]
+
+		if !ok {
+			return ctx, attributes, fmt.Errorf("there is no upcaster from model version %d to %d", version, version+1)
+		}
+
+		if payload, err = upcaster(payload); err != nil {
+			return ctx, attributes, fmt.Errorf("can not upcast model from version %d to %d: %w", version, version+1, err)
+		}
+	}
+
+	data, err := json.Marshal(payload)
+
+	if err != nil {
+		return ctx, attributes, fmt.Errorf("can not marshal upcasted payload: %w", err)
+	}
+
+	if err = json.Unmarshal(data, model); err != nil {
+		return ctx, attributes, fmt.Errorf("can not unmarshal upcasted payload into the model: %w", err)
+	}
+
+	attributes[AttributeModelVersion] = current
+
+	return ctx, attributes, nil
+}
+
+func (c *Consumer) messageEncoding(msg *Message) string {
+	if encoding, ok := msg.Attributes[AttributeEncoding]; ok {
+		return cast.ToString(encoding)
+	}
+
+	if c.settings.Encoding != "" {
+		return c.settings.Encoding
+	}
+
+	return defaultMessageBodyEncoding
+}
+
+func getConsumerUpcastingDefaultMetrics(name string, current int) mon.MetricData {
+	defaults := make(mon.MetricData, 0, current)
+
+	for version := 1; version <= current; version++ {
+		defaults = append(defaults, &mon.MetricDatum{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerModelVersionCount,
+			Dimensions: map[string]string{
+				"Consumer":     name,
+				"ModelVersion": strconv.Itoa(version),
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		})
+	}
+
+	return defaults
+}
@@ -0,0 +1,118 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upcastingTestModel struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Age       int    `json:"age"`
+}
+
+func (m *upcastingTestModel) GetModelVersion() int {
+	return 3
+}
+
+type upcastingTestCallback struct {
+	consumed []*upcastingTestModel
+}
+
+func (c *upcastingTestCallback) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (c *upcastingTestCallback) GetModel(_ map[string]interface{}) interface{} {
+	return &upcastingTestModel{}
+}
+
+func (c *upcastingTestCallback) Consume(_ context.Context, model interface{}, _ map[string]interface{}) (bool, error) {
+	c.consumed = append(c.consumed, model.(*upcastingTestModel))
+
+	return true, nil
+}
+
+func (c *upcastingTestCallback) GetModelVersion() int {
+	return 3
+}
+
+func (c *upcastingTestCallback) GetUpcasters() map[int]stream.Upcaster {
+	return map[int]stream.Upcaster{
+		// version 2 split the name into first and last name
+		1: func(payload map[string]interface{}) (map[string]interface{}, error) {
+			names := strings.SplitN(payload["name"].(string), " ", 2)
+
+			return map[string]interface{}{
+				"firstName": names[0],
+				"lastName":  names[1],
+			}, nil
+		},
+		// version 3 added the age
+		2: func(payload map[string]interface{}) (map[string]interface{}, error) {
+			payload["age"] = -1
+
+			return payload, nil
+		},
+	}
+}
+
+func TestConsumer_Upcasting(t *testing.T) {
+	data := make(chan *stream.Message, 10)
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{})
+
+	current, err := encoder.Encode(context.Background(), &upcastingTestModel{FirstName: "Jane", LastName: "Doe", Age: 42})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, current.Attributes[stream.AttributeModelVersion])
+
+	data <- stream.NewJsonMessage(`{"name":"John Doe"}`)
+	data <- stream.NewJsonMessage(`{"firstName":"Max","lastName":"Mustermann"}`, map[string]interface{}{stream.AttributeModelVersion: 2.0})
+	data <- current
+	data <- stream.NewJsonMessage(`{"fullName":"Future Person"}`, map[string]interface{}{stream.AttributeModelVersion: 4.0})
+	data <- stream.NewMessage("gqRuYW1lq01zZ3BhY2sgRG9l", map[string]interface{}{stream.AttributeEncoding: stream.EncodingMsgpack, stream.AttributeModelVersion: 1})
+	close(data)
+
+	input := new(mocks.Input)
+	input.On("Data").Return(data)
+	input.On("Run", mock.Anything).Return(nil)
+	input.On("Stop")
+
+	versions := make(map[string]int)
+	mw := new(monMocks.MetricWriter)
+	mw.On("WriteOne", mock.AnythingOfType("*mon.MetricDatum")).Run(func(args mock.Arguments) {
+		datum := args.Get(0).(*mon.MetricDatum)
+
+		if datum.MetricName == "ConsumerModelVersionCount" {
+			versions[datum.Dimensions["ModelVersion"]]++
+		}
+	})
+
+	callback := &upcastingTestCallback{}
+
+	consumer := stream.NewConsumer("test", callback)
+	consumer.BootWithInterfaces(monMocks.NewLoggerMockedAll(), tracing.NewNoopTracer(), mw, input, encoder, &stream.ConsumerSettings{
+		Input:       "test",
+		RunnerCount: 1,
+		IdleTimeout: time.Second,
+	})
+
+	err = consumer.Run(context.Background())
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*upcastingTestModel{
+		{FirstName: "John", LastName: "Doe", Age: -1},
+		{FirstName: "Max", LastName: "Mustermann", Age: -1},
+		{FirstName: "Jane", LastName: "Doe", Age: 42},
+	}, callback.consumed, "the message of version 4 and the msgpack encoded message of version 1 should not be consumed")
+	assert.Equal(t, map[string]int{"1": 2, "2": 1, "3": 1}, versions)
+}
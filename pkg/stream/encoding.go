@@ -3,13 +3,18 @@ package stream
 import (
 	"bytes"
 	"fmt"
+	"github.com/applike/gosoline/pkg/encoding/base64"
 	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/encoding/msgpack"
 	"github.com/spf13/cast"
 )
 
 const (
-	EncodingJson = "application/json"
-	EncodingText = "text/plain"
+	EncodingAvro     = "application/avro"
+	EncodingJson     = "application/json"
+	EncodingMsgpack  = "application/msgpack"
+	EncodingProtobuf = "application/x-protobuf"
+	EncodingText     = "text/plain"
 )
 
 var defaultMessageBodyEncoding = EncodingJson
@@ -23,9 +28,20 @@ type MessageBodyEncoder interface {
 	Decode(data []byte, out interface{}) error
 }
 
+// A SchemaMessageBodyEncoder encodes the body using a schema from the schema registry. The id of the schema
+// is stored in the schemaId attribute of the message, so the body can still be decoded after the schema evolved.
+type SchemaMessageBodyEncoder interface {
+	MessageBodyEncoder
+	EncodeWithSchema(data interface{}) ([]byte, string, error)
+	DecodeWithSchema(data []byte, schemaId string, out interface{}) error
+}
+
 var messageBodyEncoders = map[string]MessageBodyEncoder{
-	EncodingJson: new(jsonEncoder),
-	EncodingText: new(textEncoder),
+	EncodingAvro:     new(avroEncoder),
+	EncodingJson:     new(jsonEncoder),
+	EncodingMsgpack:  new(msgpackEncoder),
+	EncodingProtobuf: new(protobufEncoder),
+	EncodingText:     new(textEncoder),
 }
 
 func AddMessageBodyEncoder(encoding string, encoder MessageBodyEncoder) {
@@ -42,6 +58,29 @@ func (e jsonEncoder) Decode(data []byte, out interface{}) error {
 	return json.Unmarshal(data, out)
 }
 
+// the body of a message is a string, so binary encodings are stored base64 encoded
+type msgpackEncoder struct{}
+
+func (e msgpackEncoder) Encode(data interface{}) ([]byte, error) {
+	body, err := msgpack.Marshal(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.Encode(body), nil
+}
+
+func (e msgpackEncoder) Decode(data []byte, out interface{}) error {
+	body, err := base64.Decode(data)
+
+	if err != nil {
+		return fmt.Errorf("can not base64 decode the body: %w", err)
+	}
+
+	return msgpack.Unmarshal(body, out)
+}
+
 type textEncoder struct{}
 
 func (e textEncoder) Encode(data interface{}) ([]byte, error) {
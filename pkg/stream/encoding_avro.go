@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"fmt"
+	"github.com/applike/gosoline/pkg/encoding/base64"
+	"github.com/hamba/avro"
+	"sync"
+)
+
+// avroEncoder encodes the body with the latest avro schema of the subject of the data. The body is always
+// decoded with the schema it was written with, fields missing in the model are skipped.
+type avroEncoder struct {
+	lck     sync.Mutex
+	schemas map[string]avro.Schema
+}
+
+func (e *avroEncoder) Encode(data interface{}) ([]byte, error) {
+	body, _, err := e.EncodeWithSchema(data)
+
+	return body, err
+}
+
+func (e *avroEncoder) EncodeWithSchema(data interface{}) ([]byte, string, error) {
+	registry, err := getSchemaRegistry()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	subject := getSchemaSubject(data)
+	schema, err := registry.GetLatestSchema(subject)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("can not get avro schema of subject %s: %w", subject, err)
+	}
+
+	avroSchema, err := e.parse(schema)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := avro.Marshal(avroSchema, data)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("can not encode with avro schema %s: %w", schema.Id, err)
+	}
+
+	return base64.Encode(body), schema.Id, nil
+}
+
+func (e *avroEncoder) Decode(data []byte, out interface{}) error {
+	return e.DecodeWithSchema(data, "", out)
+}
+
+func (e *avroEncoder) DecodeWithSchema(data []byte, schemaId string, out interface{}) error {
+	registry, err := getSchemaRegistry()
+
+	if err != nil {
+		return err
+	}
+
+	var schema *Schema
+
+	if schemaId != "" {
+		schema, err = registry.GetSchema(schemaId)
+	} else {
+		schema, err = registry.GetLatestSchema(getSchemaSubject(out))
+	}
+
+	if err != nil {
+		return fmt.Errorf("can not get avro schema to decode the body: %w", err)
+	}
+
+	avroSchema, err := e.parse(schema)
+
+	if err != nil {
+		return err
+	}
+
+	body, err := base64.Decode(data)
+
+	if err != nil {
+		return fmt.Errorf("can not base64 decode the body: %w", err)
+	}
+
+	if err = avro.Unmarshal(avroSchema, body, out); err != nil {
+		return fmt.Errorf("can not decode with avro schema %s: %w", schema.Id, err)
+	}
+
+	return nil
+}
+
+func (e *avroEncoder) parse(schema *Schema) (avro.Schema, error) {
+	e.lck.Lock()
+	defer e.lck.Unlock()
+
+	if e.schemas == nil {
+		e.schemas = make(map[string]avro.Schema)
+	}
+
+	if avroSchema, ok := e.schemas[schema.Id]; ok {
+		return avroSchema, nil
+	}
+
+	// every version gets its own cache, as the versions of a schema share the same names
+	avroSchema, err := avro.ParseWithCache(schema.Definition, "", &avro.SchemaCache{})
+
+	if err != nil {
+		return nil, fmt.Errorf("can not parse avro schema %s: %w", schema.Id, err)
+	}
+
+	e.schemas[schema.Id] = avroSchema
+
+	return avroSchema, nil
+}
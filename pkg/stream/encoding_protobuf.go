@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"fmt"
+	"github.com/applike/gosoline/pkg/encoding/base64"
+	"github.com/golang/protobuf/proto"
+)
+
+// protobufEncoder encodes proto messages. The subject of a message is its full proto name. As protobuf stays
+// compatible as long as field numbers aren't reused, the schema is only used to verify the type of the body.
+type protobufEncoder struct{}
+
+func (e protobufEncoder) Encode(data interface{}) ([]byte, error) {
+	body, _, err := e.EncodeWithSchema(data)
+
+	return body, err
+}
+
+func (e protobufEncoder) EncodeWithSchema(data interface{}) ([]byte, string, error) {
+	msg, ok := data.(proto.Message)
+
+	if !ok {
+		return nil, "", fmt.Errorf("the data of type %T is not a proto message", data)
+	}
+
+	registry, err := getSchemaRegistry()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	subject := proto.MessageName(msg)
+	schema, err := registry.GetLatestSchema(subject)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("can not get protobuf schema of subject %s: %w", subject, err)
+	}
+
+	body, err := proto.Marshal(msg)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("can not encode proto message %s: %w", subject, err)
+	}
+
+	return base64.Encode(body), schema.Id, nil
+}
+
+func (e protobufEncoder) Decode(data []byte, out interface{}) error {
+	return e.DecodeWithSchema(data, "", out)
+}
+
+func (e protobufEncoder) DecodeWithSchema(data []byte, schemaId string, out interface{}) error {
+	msg, ok := out.(proto.Message)
+
+	if !ok {
+		return fmt.Errorf("the out parameter of type %T is not a proto message", out)
+	}
+
+	if schemaId != "" {
+		registry, err := getSchemaRegistry()
+
+		if err != nil {
+			return err
+		}
+
+		schema, err := registry.GetSchema(schemaId)
+
+		if err != nil {
+			return fmt.Errorf("can not get protobuf schema to decode the body: %w", err)
+		}
+
+		if subject := proto.MessageName(msg); schema.Subject != subject {
+			return fmt.Errorf("the body was encoded with schema %s and can not be decoded into %s", schema.Id, subject)
+		}
+	}
+
+	body, err := base64.Decode(data)
+
+	if err != nil {
+		return fmt.Errorf("can not base64 decode the body: %w", err)
+	}
+
+	return proto.Unmarshal(body, msg)
+}
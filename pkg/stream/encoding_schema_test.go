@@ -0,0 +1,170 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/encoding/base64"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/hamba/avro"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"testing"
+)
+
+type avroTestModel struct {
+	Id   int      `avro:"id" msgpack:"id"`
+	Text string   `avro:"text" msgpack:"text"`
+	Tags []string `avro:"tags" msgpack:"tags"`
+}
+
+type SchemaEncodingSuite struct {
+	suite.Suite
+}
+
+func (s *SchemaEncodingSuite) SetupTest() {
+	registry, err := stream.NewFileSchemaRegistry("testdata/schemas")
+	s.NoError(err)
+
+	stream.SetSchemaRegistry(registry)
+}
+
+func (s *SchemaEncodingSuite) TearDownTest() {
+	stream.SetSchemaRegistry(nil)
+}
+
+func (s *SchemaEncodingSuite) TestFileSchemaRegistry() {
+	registry, err := stream.NewFileSchemaRegistry("testdata/schemas")
+	s.NoError(err)
+
+	schema, err := registry.GetLatestSchema("avroTestModel")
+	s.NoError(err)
+	s.Equal("avroTestModel/2", schema.Id)
+	s.Equal(2, schema.Version)
+
+	schema, err = registry.GetSchema("avroTestModel/1")
+	s.NoError(err)
+	s.Equal("avroTestModel", schema.Subject)
+	s.Equal(1, schema.Version)
+
+	_, err = registry.GetSchema("avroTestModel/3")
+	s.EqualError(err, "there is no schema with id avroTestModel/3 in testdata/schemas: file does not exist")
+
+	_, err = stream.NewFileSchemaRegistry("testdata/missing")
+	s.Error(err)
+}
+
+func (s *SchemaEncodingSuite) TestAvro() {
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+		Encoding: stream.EncodingAvro,
+	})
+
+	msg, err := encoder.Encode(context.Background(), &avroTestModel{
+		Id:   3,
+		Text: "example",
+		Tags: []string{"a", "b"},
+	})
+
+	s.NoError(err)
+	s.Equal(stream.EncodingAvro, msg.Attributes[stream.AttributeEncoding])
+	s.Equal("avroTestModel/2", msg.Attributes[stream.AttributeSchemaId])
+
+	out := &avroTestModel{}
+	_, attributes, err := encoder.Decode(context.Background(), msg, out)
+
+	s.NoError(err)
+	s.NotContains(attributes, stream.AttributeSchemaId)
+	s.Equal(&avroTestModel{Id: 3, Text: "example", Tags: []string{"a", "b"}}, out)
+}
+
+func (s *SchemaEncodingSuite) TestAvroRegistryError() {
+	registry := new(mocks.SchemaRegistry)
+	registry.On("GetLatestSchema", "avroTestModel").Return(nil, fmt.Errorf("registry is down"))
+	stream.SetSchemaRegistry(registry)
+
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+		Encoding: stream.EncodingAvro,
+	})
+
+	_, err := encoder.Encode(context.Background(), &avroTestModel{Id: 3})
+
+	s.EqualError(err, "could not encode message body: can not encode message body with encoding 'application/avro': can not get avro schema of subject avroTestModel: registry is down")
+	registry.AssertExpectations(s.T())
+}
+
+func (s *SchemaEncodingSuite) TestAvroPreviousSchemaVersion() {
+	definition, err := ioutil.ReadFile("testdata/schemas/avroTestModel/1.avsc")
+	s.NoError(err)
+
+	body, err := avro.Marshal(avro.MustParse(string(definition)), &avroTestModel{
+		Id:   3,
+		Text: "example",
+	})
+	s.NoError(err)
+
+	msg := &stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeEncoding: stream.EncodingAvro,
+			stream.AttributeSchemaId: "avroTestModel/1",
+		},
+		Body: string(base64.Encode(body)),
+	}
+
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{})
+	out := &avroTestModel{}
+	_, _, err = encoder.Decode(context.Background(), msg, out)
+
+	s.NoError(err)
+	s.Equal(&avroTestModel{Id: 3, Text: "example"}, out)
+}
+
+func (s *SchemaEncodingSuite) TestProtobuf() {
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+		Encoding: stream.EncodingProtobuf,
+	})
+
+	msg, err := encoder.Encode(context.Background(), &wrappers.StringValue{Value: "example"})
+
+	s.NoError(err)
+	s.Equal("google.protobuf.StringValue/1", msg.Attributes[stream.AttributeSchemaId])
+
+	out := &wrappers.StringValue{}
+	_, _, err = encoder.Decode(context.Background(), msg, out)
+
+	s.NoError(err)
+	s.Equal("example", out.Value)
+
+	msg, err = encoder.Encode(context.Background(), &wrappers.StringValue{Value: "example"})
+	s.NoError(err)
+
+	_, _, err = encoder.Decode(context.Background(), msg, &wrappers.Int64Value{})
+	s.EqualError(err, "can not decode message body: can not decode message body with encoding 'application/x-protobuf': the body was encoded with schema google.protobuf.StringValue/1 and can not be decoded into google.protobuf.Int64Value")
+
+	_, err = encoder.Encode(context.Background(), &avroTestModel{})
+	s.EqualError(err, "could not encode message body: can not encode message body with encoding 'application/x-protobuf': the data of type *stream_test.avroTestModel is not a proto message")
+}
+
+func (s *SchemaEncodingSuite) TestMsgpack() {
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+		Encoding: stream.EncodingMsgpack,
+	})
+
+	msg, err := encoder.Encode(context.Background(), &avroTestModel{
+		Id:   3,
+		Text: "example",
+	})
+
+	s.NoError(err)
+	s.NotContains(msg.Attributes, stream.AttributeSchemaId)
+
+	out := &avroTestModel{}
+	_, _, err = encoder.Decode(context.Background(), msg, out)
+
+	s.NoError(err)
+	s.Equal(&avroTestModel{Id: 3, Text: "example"}, out)
+}
+
+func TestSchemaEncodingSuite(t *testing.T) {
+	suite.Run(t, new(SchemaEncodingSuite))
+}
@@ -14,3 +14,17 @@ type AcknowledgeableInput interface {
 	Ack(msg *Message) error
 	AckBatch(msgs []*Message) error
 }
+
+// A NackableInput is notified about the messages which were not acknowledged, so it can redeliver them.
+//go:generate mockery -name NackableInput
+type NackableInput interface {
+	Nack(msg *Message) error
+}
+
+// A PausableInput stops fetching new messages while it is paused. Messages which were already
+// fetched are still delivered.
+//go:generate mockery -name PausableInput
+type PausableInput interface {
+	Pause()
+	Resume()
+}
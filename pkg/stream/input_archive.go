@@ -0,0 +1,198 @@
+package stream
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/mon"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveInputSettings select the messages of an archive to replay. A message is replayed if it was written
+// in the range [From, Until), a zero Until replays all messages up to now.
+type ArchiveInputSettings struct {
+	Storage ArchiveStorageSettings
+	From    time.Time
+	Until   time.Time
+	// MaxLineSize is the size of the longest message which can be read, in bytes
+	MaxLineSize int
+	// RotationInterval of the output which wrote the archive. The files started up to this long before From
+	// are read as well, as they can contain messages written after From
+	RotationInterval time.Duration
+}
+
+type archiveInput struct {
+	logger   mon.Logger
+	storage  ArchiveStorage
+	settings *ArchiveInputSettings
+
+	channel  chan *Message
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+func NewArchiveInput(config cfg.Config, logger mon.Logger, settings *ArchiveInputSettings) Input {
+	storage := NewArchiveStorage(config, &settings.Storage)
+
+	return NewArchiveInputWithInterfaces(logger, storage, settings)
+}
+
+func NewArchiveInputWithInterfaces(logger mon.Logger, storage ArchiveStorage, settings *ArchiveInputSettings) Input {
+	if settings.MaxLineSize <= 0 {
+		settings.MaxLineSize = bufio.MaxScanTokenSize
+	}
+
+	return &archiveInput{
+		logger:   logger,
+		storage:  storage,
+		settings: settings,
+		channel:  make(chan *Message),
+		stopped:  make(chan struct{}),
+	}
+}
+
+func (i *archiveInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *archiveInput) Run(ctx context.Context) error {
+	defer close(i.channel)
+
+	from, until := i.timeRange()
+	keys, err := i.listFiles(ctx, from, until)
+
+	if err != nil {
+		return fmt.Errorf("can not list the archive files: %w", err)
+	}
+
+	i.logger.Infof("replaying %d archive files from %s", len(keys), i.settings.Storage.Prefix)
+
+	for _, key := range keys {
+		done, err := i.replayFile(ctx, key, from, until)
+
+		if err != nil {
+			return fmt.Errorf("can not replay the archive file %s: %w", key, err)
+		}
+
+		if done {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (i *archiveInput) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stopped)
+	})
+}
+
+func (i *archiveInput) timeRange() (time.Time, time.Time) {
+	from := i.settings.From.UTC()
+	until := i.settings.Until.UTC()
+
+	if i.settings.Until.IsZero() {
+		until = time.Now().UTC()
+	}
+
+	return from, until
+}
+
+// listFiles returns the files which can contain messages of the time range in order. These are the files
+// started in the range and the ones started within a rotation interval before it. Only the partitions of
+// the days of these files are listed.
+func (i *archiveInput) listFiles(ctx context.Context, from time.Time, until time.Time) ([]string, error) {
+	earliest := from.Add(-i.settings.RotationInterval - archiveRotationCheckInterval)
+
+	keys := make([]string, 0)
+	day := time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, time.UTC)
+
+	for ; day.Before(until); day = day.AddDate(0, 0, 1) {
+		prefix := path.Join(i.settings.Storage.Prefix, day.Format("2006/01/02")) + "/"
+		dayKeys, err := i.storage.List(ctx, prefix)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range dayKeys {
+			start, ok := archiveFileTime(key)
+
+			if !ok || start.Before(earliest) || !start.Before(until) {
+				continue
+			}
+
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// replayFile writes the messages of the file to the channel. It returns true if the input was stopped.
+// Messages written outside of the time range are skipped.
+func (i *archiveInput) replayFile(ctx context.Context, key string, from time.Time, until time.Time) (bool, error) {
+	body, err := i.storage.Read(ctx, key)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer body.Close()
+
+	var reader io.Reader = body
+
+	if strings.HasSuffix(key, archiveGzipExtension) {
+		gzipReader, err := gzip.NewReader(body)
+
+		if err != nil {
+			return false, fmt.Errorf("can not create gzip reader: %w", err)
+		}
+
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), i.settings.MaxLineSize)
+
+	for scanner.Scan() {
+		record := &archiveRecord{}
+
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			i.logger.Errorf(err, "can not unmarshal a message of the archive file %s", key)
+			continue
+		}
+
+		if record.Timestamp.Before(from) || !record.Timestamp.Before(until) {
+			continue
+		}
+
+		msg := &Message{
+			Attributes: record.Attributes,
+			Body:       record.Body,
+		}
+
+		select {
+		case i.channel <- msg:
+		case <-i.stopped:
+			return true, nil
+		case <-ctx.Done():
+			return true, nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("can not read the file: %w", err)
+	}
+
+	return false, nil
+}
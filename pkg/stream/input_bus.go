@@ -0,0 +1,200 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/spf13/cast"
+	"github.com/twinj/uuid"
+	"sync"
+	"time"
+)
+
+type BusInputSettings struct {
+	Topic        string
+	Subscription string
+	// Size is the number of messages buffered by the subscription before the producers of the topic are blocked
+	Size int
+	// MaxReceiveCount drops a message after it was nacked this many times, 0 redelivers it forever
+	MaxReceiveCount int
+	// RedeliveryDelay is the time after which a nacked message is delivered again
+	RedeliveryDelay time.Duration
+}
+
+// busInput is a subscription of an in-process bus topic. The messages which were delivered but are neither
+// acknowledged nor nacked yet are kept in flight, a nacked message is delivered again after the redelivery delay.
+type busInput struct {
+	logger   mon.Logger
+	topic    *busTopic
+	settings *BusInputSettings
+
+	lck     sync.RWMutex
+	closed  bool
+	once    sync.Once
+	channel chan *Message
+	stopped chan struct{}
+
+	inFlightLck sync.Mutex
+	inFlight    map[string]*Message
+}
+
+// NewBusInput subscribes to the topic of the settings. The subscription exists from now on, so messages written
+// to the topic before the input is running are buffered.
+func NewBusInput(logger mon.Logger, settings *BusInputSettings) *busInput {
+	input := &busInput{
+		logger:   logger.WithChannel("bus-input"),
+		topic:    provideBusTopic(settings.Topic),
+		settings: settings,
+		channel:  make(chan *Message, settings.Size),
+		stopped:  make(chan struct{}),
+		inFlight: map[string]*Message{},
+	}
+
+	if err := input.topic.subscribe(settings.Subscription, input); err != nil {
+		logger.Fatal(err, "can not subscribe to the bus topic")
+	}
+
+	return input
+}
+
+func (i *busInput) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+	case <-i.stopped:
+	}
+
+	i.topic.unsubscribe(i.settings.Subscription)
+	i.Stop()
+
+	// wait for the producers which are still publishing before closing the channel
+	i.lck.Lock()
+	defer i.lck.Unlock()
+
+	i.closed = true
+	close(i.channel)
+
+	return nil
+}
+
+func (i *busInput) Stop() {
+	i.once.Do(func() {
+		close(i.stopped)
+	})
+}
+
+func (i *busInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *busInput) Ack(msg *Message) error {
+	return i.AckBatch([]*Message{msg})
+}
+
+func (i *busInput) AckBatch(msgs []*Message) error {
+	for _, msg := range msgs {
+		if _, err := i.settle(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Nack delivers the message again after the redelivery delay, unless it exceeded the max receive count.
+func (i *busInput) Nack(msg *Message) error {
+	ok, err := i.settle(msg)
+
+	if err != nil || !ok {
+		return err
+	}
+
+	receiveCount := cast.ToInt(msg.Attributes[AttributeBusReceiveCount])
+
+	if i.settings.MaxReceiveCount > 0 && receiveCount >= i.settings.MaxReceiveCount {
+		i.logger.Warnf("dropping message %s of the subscription %s after %d receives", msg.Attributes[AttributeBusMessageId], i.settings.Subscription, receiveCount)
+		return nil
+	}
+
+	go i.redeliver(msg, receiveCount)
+
+	return nil
+}
+
+func (i *busInput) redeliver(msg *Message, receiveCount int) {
+	timer := time.NewTimer(i.settings.RedeliveryDelay)
+	defer timer.Stop()
+
+	select {
+	case <-i.stopped:
+		return
+	case <-timer.C:
+	}
+
+	redelivery := copyBusMessage(msg)
+	redelivery.Attributes[AttributeBusReceiveCount] = receiveCount + 1
+
+	if err := i.deliver(context.Background(), redelivery); err != nil {
+		i.logger.Error(err, "can not redeliver the nacked message")
+	}
+}
+
+// publish delivers a message written to the topic for the first time.
+func (i *busInput) publish(ctx context.Context, msg *Message) error {
+	msg.Attributes[AttributeBusMessageId] = uuid.NewV4().String()
+	msg.Attributes[AttributeBusReceiveCount] = 1
+
+	return i.deliver(ctx, msg)
+}
+
+func (i *busInput) deliver(ctx context.Context, msg *Message) error {
+	i.lck.RLock()
+	defer i.lck.RUnlock()
+
+	if i.closed {
+		return nil
+	}
+
+	id := cast.ToString(msg.Attributes[AttributeBusMessageId])
+	i.track(id, msg)
+
+	select {
+	case i.channel <- msg:
+		return nil
+	case <-i.stopped:
+		return nil
+	case <-ctx.Done():
+		i.untrack(id)
+		return ctx.Err()
+	}
+}
+
+func (i *busInput) track(id string, msg *Message) {
+	i.inFlightLck.Lock()
+	defer i.inFlightLck.Unlock()
+
+	i.inFlight[id] = msg
+}
+
+func (i *busInput) untrack(id string) {
+	i.inFlightLck.Lock()
+	defer i.inFlightLck.Unlock()
+
+	delete(i.inFlight, id)
+}
+
+// settle removes a delivered message from the messages in flight and reports whether it was in flight.
+func (i *busInput) settle(msg *Message) (bool, error) {
+	id, ok := msg.Attributes[AttributeBusMessageId].(string)
+
+	if !ok || id == "" {
+		return false, fmt.Errorf("the message has no attribute %s", AttributeBusMessageId)
+	}
+
+	i.inFlightLck.Lock()
+	defer i.inFlightLck.Unlock()
+
+	_, ok = i.inFlight[id]
+	delete(i.inFlight, id)
+
+	return ok, nil
+}
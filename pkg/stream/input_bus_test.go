@@ -0,0 +1,111 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type busTestInput interface {
+	stream.Input
+	stream.AcknowledgeableInput
+	stream.NackableInput
+}
+
+func getBusInput(topic string, subscription string, size int, maxReceiveCount int) busTestInput {
+	return stream.NewBusInput(mocks.NewLoggerMockedAll(), &stream.BusInputSettings{
+		Topic:           topic,
+		Subscription:    subscription,
+		Size:            size,
+		MaxReceiveCount: maxReceiveCount,
+		RedeliveryDelay: time.Millisecond,
+	})
+}
+
+func TestBus_FanOut(t *testing.T) {
+	first := getBusInput("fan-out", "first", 2, 0)
+	second := getBusInput("fan-out", "second", 2, 0)
+	output := stream.NewBusOutput(&stream.BusOutputSettings{
+		Topic: "fan-out",
+	})
+
+	err := output.Write(context.Background(), []*stream.Message{
+		stream.NewMessage("foo"),
+		stream.NewMessage("bar"),
+	})
+	assert.NoError(t, err)
+
+	for _, input := range []busTestInput{first, second} {
+		foo := <-input.Data()
+		bar := <-input.Data()
+
+		assert.Equal(t, "foo", foo.Body)
+		assert.Equal(t, "bar", bar.Body)
+		assert.Equal(t, 1, foo.Attributes[stream.AttributeBusReceiveCount])
+		assert.NoError(t, input.AckBatch([]*stream.Message{foo, bar}))
+	}
+
+	first.Stop()
+	assert.NoError(t, first.Run(context.Background()))
+
+	// the stopped subscription doesn't receive messages anymore, so it doesn't block the topic
+	err = output.WriteOne(context.Background(), stream.NewMessage("baz"))
+	assert.NoError(t, err)
+
+	baz := <-second.Data()
+	assert.Equal(t, "baz", baz.Body)
+
+	_, ok := <-first.Data()
+	assert.False(t, ok, "the channel of the stopped input should be closed")
+}
+
+func TestBus_Backpressure(t *testing.T) {
+	input := getBusInput("backpressure", "subscription", 1, 0)
+	output := stream.NewBusOutput(&stream.BusOutputSettings{
+		Topic: "backpressure",
+	})
+
+	err := output.WriteOne(context.Background(), stream.NewMessage("foo"))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = output.WriteOne(ctx, stream.NewMessage("bar"))
+	assert.Error(t, err, "the write should block until the context is canceled as the buffer is full")
+
+	msg := <-input.Data()
+	assert.Equal(t, "foo", msg.Body)
+}
+
+func TestBus_Nack(t *testing.T) {
+	input := getBusInput("nack", "subscription", 1, 2)
+	output := stream.NewBusOutput(&stream.BusOutputSettings{
+		Topic: "nack",
+	})
+
+	err := output.WriteOne(context.Background(), stream.NewMessage("foo"))
+	assert.NoError(t, err)
+
+	msg := <-input.Data()
+	assert.NoError(t, input.Nack(msg))
+
+	redelivered := <-input.Data()
+	assert.Equal(t, "foo", redelivered.Body)
+	assert.Equal(t, msg.Attributes[stream.AttributeBusMessageId], redelivered.Attributes[stream.AttributeBusMessageId])
+	assert.Equal(t, 2, redelivered.Attributes[stream.AttributeBusReceiveCount])
+
+	// the message reached the max receive count and is dropped
+	assert.NoError(t, input.Nack(redelivered))
+
+	select {
+	case <-input.Data():
+		assert.Fail(t, "the message should not be delivered again")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.Error(t, input.Ack(stream.NewMessage("bar")), "a message without a bus message id can't be acknowledged")
+}
@@ -11,23 +11,29 @@ import (
 )
 
 const (
-	InputTypeFile     = "file"
-	InputTypeInMemory = "inMemory"
-	InputTypeKinesis  = "kinesis"
-	InputTypeRedis    = "redis"
-	InputTypeSns      = "sns"
-	InputTypeSqs      = "sqs"
+	InputTypeArchive     = "archive"
+	InputTypeBus         = "bus"
+	InputTypeFile        = "file"
+	InputTypeInMemory    = "inMemory"
+	InputTypeKinesis     = "kinesis"
+	InputTypeRedis       = "redis"
+	InputTypeRedisStream = "redis_stream"
+	InputTypeSns         = "sns"
+	InputTypeSqs         = "sqs"
 )
 
 type InputFactory func(config cfg.Config, logger mon.Logger, name string) Input
 
 var inputFactories = map[string]InputFactory{
-	InputTypeFile:     newFileInputFromConfig,
-	InputTypeInMemory: newInMemoryInputFromConfig,
-	InputTypeKinesis:  newKinesisInputFromConfig,
-	InputTypeRedis:    newRedisInputFromConfig,
-	InputTypeSns:      newSnsInputFromConfig,
-	InputTypeSqs:      newSqsInputFromConfig,
+	InputTypeArchive:     newArchiveInputFromConfig,
+	InputTypeBus:         newBusInputFromConfig,
+	InputTypeFile:        newFileInputFromConfig,
+	InputTypeInMemory:    newInMemoryInputFromConfig,
+	InputTypeKinesis:     newKinesisInputFromConfig,
+	InputTypeRedis:       newRedisInputFromConfig,
+	InputTypeRedisStream: newRedisStreamInputFromConfig,
+	InputTypeSns:         newSnsInputFromConfig,
+	InputTypeSqs:         newSqsInputFromConfig,
 }
 
 func SetInputFactory(typ string, factory InputFactory) {
@@ -59,6 +65,32 @@ func NewConfigurableInput(config cfg.Config, logger mon.Logger, name string) Inp
 	return factory(config, logger, name)
 }
 
+type archiveInputConfiguration struct {
+	Storage          ArchiveStorageSettings `cfg:"storage"`
+	From             time.Time              `cfg:"from" validate:"required"`
+	Until            time.Time              `cfg:"until"`
+	MaxLineSize      int                    `cfg:"max_line_size" default:"1048576" validate:"min=1"`
+	RotationInterval time.Duration          `cfg:"rotation_interval" default:"5m"`
+}
+
+func newArchiveInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
+	key := ConfigurableInputKey(name)
+	configuration := archiveInputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	if configuration.Storage.Prefix == "" {
+		configuration.Storage.Prefix = name
+	}
+
+	return NewArchiveInput(config, logger, &ArchiveInputSettings{
+		Storage:          configuration.Storage,
+		From:             configuration.From,
+		Until:            configuration.Until,
+		MaxLineSize:      configuration.MaxLineSize,
+		RotationInterval: configuration.RotationInterval,
+	})
+}
+
 func newFileInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
 	key := ConfigurableInputKey(name)
 	settings := FileSettings{}
@@ -67,6 +99,33 @@ func newFileInputFromConfig(config cfg.Config, logger mon.Logger, name string) I
 	return NewFileInput(config, logger, settings)
 }
 
+type busInputConfiguration struct {
+	Topic           string        `cfg:"topic" validate:"required,min=1"`
+	Subscription    string        `cfg:"subscription"`
+	Size            int           `cfg:"size" default:"100" validate:"min=0"`
+	MaxReceiveCount int           `cfg:"max_receive_count" default:"0" validate:"min=0"`
+	RedeliveryDelay time.Duration `cfg:"redelivery_delay" default:"1s"`
+}
+
+func newBusInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
+	key := ConfigurableInputKey(name)
+
+	configuration := busInputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	if configuration.Subscription == "" {
+		configuration.Subscription = name
+	}
+
+	return NewBusInput(logger, &BusInputSettings{
+		Topic:           configuration.Topic,
+		Subscription:    configuration.Subscription,
+		Size:            configuration.Size,
+		MaxReceiveCount: configuration.MaxReceiveCount,
+		RedeliveryDelay: configuration.RedeliveryDelay,
+	})
+}
+
 func newInMemoryInputFromConfig(config cfg.Config, _ mon.Logger, name string) Input {
 	key := ConfigurableInputKey(name)
 	settings := &InMemorySettings{}
@@ -76,22 +135,44 @@ func newInMemoryInputFromConfig(config cfg.Config, _ mon.Logger, name string) In
 }
 
 type kinesisInputConfiguration struct {
-	StreamName      string `cfg:"stream_name" validate:"required"`
-	ApplicationName string `cfg:"application_name" validate:"required"`
+	StreamName         string               `cfg:"stream_name" validate:"required"`
+	ApplicationName    string               `cfg:"application_name" validate:"required"`
+	StartingPosition   string               `cfg:"starting_position" default:"LATEST" validate:"oneof=LATEST TRIM_HORIZON AT_TIMESTAMP"`
+	StartingTimestamp  time.Time            `cfg:"starting_timestamp"`
+	EnhancedFanOut     bool                 `cfg:"enhanced_fan_out" default:"false"`
+	MaxRecords         int64                `cfg:"max_records" default:"1000" validate:"min=1,max=10000"`
+	WaitTime           time.Duration        `cfg:"wait_time" default:"1s"`
+	LeaseDuration      time.Duration        `cfg:"lease_duration" default:"1m"`
+	DiscoveryInterval  time.Duration        `cfg:"discovery_interval" default:"10s"`
+	CheckpointInterval time.Duration        `cfg:"checkpoint_interval" default:"10s"`
+	MaxPendingRecords  int                  `cfg:"max_pending_records" default:"10000" validate:"min=1"`
+	Client             cloud.ClientSettings `cfg:"client"`
+	Backoff            exec.BackoffSettings `cfg:"backoff"`
 }
 
 func newKinesisInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
 	key := ConfigurableInputKey(name)
 
-	settings := kinesisInputConfiguration{}
-	config.UnmarshalKey(key, &settings)
+	configuration := kinesisInputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
 
-	readerSettings := KinsumerSettings{
-		StreamName:      settings.StreamName,
-		ApplicationName: settings.ApplicationName,
+	settings := &KinesisInputSettings{
+		StreamName:         configuration.StreamName,
+		ApplicationName:    configuration.ApplicationName,
+		StartingPosition:   configuration.StartingPosition,
+		StartingTimestamp:  configuration.StartingTimestamp,
+		EnhancedFanOut:     configuration.EnhancedFanOut,
+		MaxRecords:         configuration.MaxRecords,
+		WaitTime:           configuration.WaitTime,
+		LeaseDuration:      configuration.LeaseDuration,
+		DiscoveryInterval:  configuration.DiscoveryInterval,
+		CheckpointInterval: configuration.CheckpointInterval,
+		MaxPendingRecords:  configuration.MaxPendingRecords,
+		Client:             configuration.Client,
+		Backoff:            configuration.Backoff,
 	}
 
-	return NewKinsumerInput(config, logger, NewKinsumer, readerSettings)
+	return NewKinesisInput(config, logger, settings)
 }
 
 type redisInputConfiguration struct {
@@ -123,6 +204,47 @@ func newRedisInputFromConfig(config cfg.Config, logger mon.Logger, name string)
 	return NewRedisListInput(config, logger, settings)
 }
 
+type redisStreamInputConfiguration struct {
+	Project       string        `cfg:"project"`
+	Family        string        `cfg:"family"`
+	Application   string        `cfg:"application"`
+	ServerName    string        `cfg:"server_name" default:"default" validate:"min=1"`
+	Key           string        `cfg:"key" validate:"required,min=1"`
+	Group         string        `cfg:"group"`
+	Consumer      string        `cfg:"consumer"`
+	StartId       string        `cfg:"start_id" default:"$"`
+	BatchSize     int64         `cfg:"batch_size" default:"10"`
+	WaitTime      time.Duration `cfg:"wait_time" default:"3s"`
+	ClaimInterval time.Duration `cfg:"claim_interval" default:"30s"`
+	ClaimMinIdle  time.Duration `cfg:"claim_min_idle" default:"5m"`
+}
+
+func newRedisStreamInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
+	key := ConfigurableInputKey(name)
+
+	configuration := redisStreamInputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	settings := &RedisStreamInputSettings{
+		AppId: cfg.AppId{
+			Project:     configuration.Project,
+			Family:      configuration.Family,
+			Application: configuration.Application,
+		},
+		ServerName:    configuration.ServerName,
+		Key:           configuration.Key,
+		Group:         configuration.Group,
+		Consumer:      configuration.Consumer,
+		StartId:       configuration.StartId,
+		BatchSize:     configuration.BatchSize,
+		WaitTime:      configuration.WaitTime,
+		ClaimInterval: configuration.ClaimInterval,
+		ClaimMinIdle:  configuration.ClaimMinIdle,
+	}
+
+	return NewRedisStreamInput(config, logger, settings)
+}
+
 type SnsInputTargetConfiguration struct {
 	Family      string                 `cfg:"family"`
 	Application string                 `cfg:"application" validate:"required"`
@@ -143,6 +265,7 @@ type SnsInputConfiguration struct {
 	RedrivePolicy       sqs.RedrivePolicy             `cfg:"redrive_policy"`
 	Client              cloud.ClientSettings          `cfg:"client"`
 	Backoff             exec.BackoffSettings          `cfg:"backoff"`
+	ClaimCheck          ClaimCheckInputSettings       `cfg:"claim_check"`
 }
 
 func newSnsInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
@@ -178,22 +301,31 @@ func newSnsInputFromConfig(config cfg.Config, logger mon.Logger, name string) In
 		}
 	}
 
-	return NewSnsInput(config, logger, settings, targets)
+	input := NewSnsInput(config, logger, settings, targets)
+
+	if configuration.ClaimCheck.Enabled {
+		return NewClaimCheckInput(config, logger, name, input, &configuration.ClaimCheck)
+	}
+
+	return input
 }
 
 type sqsInputConfiguration struct {
-	Family              string               `cfg:"target_family"`
-	Application         string               `cfg:"target_application"`
-	QueueId             string               `cfg:"target_queue_id" validate:"min=1"`
-	MaxNumberOfMessages int64                `cfg:"max_number_of_messages" default:"10" validate:"min=1,max=10"`
-	WaitTime            int64                `cfg:"wait_time" default:"3" validate:"min=1"`
-	VisibilityTimeout   int                  `cfg:"visibility_timeout" default:"30" validate:"min=1"`
-	RunnerCount         int                  `cfg:"runner_count" default:"1" validate:"min=1"`
-	Fifo                sqs.FifoSettings     `cfg:"fifo"`
-	RedrivePolicy       sqs.RedrivePolicy    `cfg:"redrive_policy"`
-	Client              cloud.ClientSettings `cfg:"client"`
-	Backoff             exec.BackoffSettings `cfg:"backoff"`
-	Unmarshaller        string               `cfg:"unmarshaller" default:"msg"`
+	Family              string                    `cfg:"target_family"`
+	Application         string                    `cfg:"target_application"`
+	QueueId             string                    `cfg:"target_queue_id" validate:"min=1"`
+	MaxNumberOfMessages int64                     `cfg:"max_number_of_messages" default:"10" validate:"min=1,max=10"`
+	WaitTime            int64                     `cfg:"wait_time" default:"3" validate:"min=1"`
+	VisibilityTimeout   int                       `cfg:"visibility_timeout" default:"30" validate:"min=1"`
+	RunnerCount         int                       `cfg:"runner_count" default:"1" validate:"min=1"`
+	Fifo                sqs.FifoSettings          `cfg:"fifo"`
+	RedrivePolicy       sqs.RedrivePolicy         `cfg:"redrive_policy"`
+	Client              cloud.ClientSettings      `cfg:"client"`
+	Backoff             exec.BackoffSettings      `cfg:"backoff"`
+	Unmarshaller        string                    `cfg:"unmarshaller" default:"msg"`
+	ClaimCheck          ClaimCheckInputSettings   `cfg:"claim_check"`
+	Heartbeat           SqsInputHeartbeatSettings `cfg:"heartbeat"`
+	Nack                SqsInputNackSettings      `cfg:"nack"`
 }
 
 func newSqsInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
@@ -217,9 +349,17 @@ func newSqsInputFromConfig(config cfg.Config, logger mon.Logger, name string) In
 		Client:              configuration.Client,
 		Backoff:             configuration.Backoff,
 		Unmarshaller:        configuration.Unmarshaller,
+		Heartbeat:           configuration.Heartbeat,
+		Nack:                configuration.Nack,
+	}
+
+	input := NewSqsInput(config, logger, settings)
+
+	if configuration.ClaimCheck.Enabled {
+		return NewClaimCheckInput(config, logger, name, input, &configuration.ClaimCheck)
 	}
 
-	return NewSqsInput(config, logger, settings)
+	return input
 }
 
 func ConfigurableInputKey(name string) string {
@@ -0,0 +1,944 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/cloud"
+	gosoAws "github.com/applike/gosoline/pkg/cloud/aws"
+	"github.com/applike/gosoline/pkg/exec"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/uuid"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	KinesisStartingPositionLatest      = kinesis.ShardIteratorTypeLatest
+	KinesisStartingPositionTrimHorizon = kinesis.ShardIteratorTypeTrimHorizon
+	KinesisStartingPositionAtTimestamp = kinesis.ShardIteratorTypeAtTimestamp
+
+	MetricNameKinesisMillisBehindLatest = "KinesisMillisBehindLatest"
+	MetricNameKinesisPendingRecords     = "KinesisPendingRecords"
+
+	AttributeKinesisShardId        = "kinesisShardId"
+	AttributeKinesisSequenceNumber = "kinesisSequenceNumber"
+
+	// a subscription to a shard lasts at most 5 minutes, so the http client has to wait at least that long
+	kinesisFanOutHttpTimeout = 6 * time.Minute
+
+	kinesisDefaultMaxPendingRecords = 10000
+)
+
+type KinesisInputSettings struct {
+	StreamName      string
+	ApplicationName string
+	// StartingPosition is used for shards without checkpoint. Child shards of a reshard
+	// are always read from TRIM_HORIZON to not lose any records.
+	StartingPosition  string
+	StartingTimestamp time.Time
+	// EnhancedFanOut registers a consumer named after the application and
+	// subscribes to the shards instead of polling them
+	EnhancedFanOut    bool
+	MaxRecords        int64
+	WaitTime          time.Duration
+	LeaseDuration     time.Duration
+	DiscoveryInterval time.Duration
+	// CheckpointInterval is the interval in which the lease of a shard is renewed and the sequence number of the
+	// last acknowledged record is stored. It has to be shorter than the lease duration.
+	CheckpointInterval time.Duration
+	// MaxPendingRecords is the number of records with unacknowledged messages after which the reading of a shard is
+	// paused until the oldest of them is acknowledged
+	MaxPendingRecords int
+	Client            cloud.ClientSettings
+	Backoff           exec.BackoffSettings
+}
+
+func (s *KinesisInputSettings) GetResourceName() string {
+	return s.StreamName
+}
+
+type kinesisStartingPosition struct {
+	Type           string
+	SequenceNumber *string
+	Timestamp      *time.Time
+}
+
+// kinesisPendingRecord is a record whose messages are not acknowledged yet.
+type kinesisPendingRecord struct {
+	sequenceNumber string
+	remaining      int
+}
+
+type kinesisShardReader struct {
+	shardId string
+	// sequenceNumber is the last record handed out, a new iterator or subscription continues after it
+	sequenceNumber string
+	stopping       bool
+	cancel         context.CancelFunc
+
+	lck sync.Mutex
+	// acknowledged is the last record whose messages and all messages before were acknowledged
+	acknowledged string
+	// pending are the records in the order they were read, records indexes them by their sequence number
+	pending []*kinesisPendingRecord
+	records map[string]*kinesisPendingRecord
+	// the reader lost its lease and must not release it
+	lostLease bool
+}
+
+func (r *kinesisShardReader) track(sequenceNumber string, messages int) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	record := &kinesisPendingRecord{
+		sequenceNumber: sequenceNumber,
+		remaining:      messages,
+	}
+
+	r.pending = append(r.pending, record)
+	r.records[sequenceNumber] = record
+
+	r.advance()
+}
+
+func (r *kinesisShardReader) ack(sequenceNumber string) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	if record, ok := r.records[sequenceNumber]; ok {
+		record.remaining--
+	}
+
+	r.advance()
+}
+
+// advance moves the acknowledged position over all leading records whose messages are acknowledged. A message
+// which is never acknowledged holds the position, so it is read again by the next owner of the shard.
+func (r *kinesisShardReader) advance() {
+	for len(r.pending) > 0 && r.pending[0].remaining <= 0 {
+		r.acknowledged = r.pending[0].sequenceNumber
+		delete(r.records, r.acknowledged)
+		r.pending = r.pending[1:]
+	}
+}
+
+// oldestPending returns the number of pending records and the sequence number of the oldest of them.
+func (r *kinesisShardReader) oldestPending() (int, string) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	if len(r.pending) == 0 {
+		return 0, ""
+	}
+
+	return len(r.pending), r.pending[0].sequenceNumber
+}
+
+func (r *kinesisShardReader) checkpoint() (string, bool) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	return r.acknowledged, len(r.pending) == 0
+}
+
+func (r *kinesisShardReader) loseLease() {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	r.lostLease = true
+}
+
+func (r *kinesisShardReader) hasLostLease() bool {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	return r.lostLease
+}
+
+type kinesisInput struct {
+	logger      mon.Logger
+	clock       clock.Clock
+	metric      mon.MetricWriter
+	client      kinesisiface.KinesisAPI
+	checkpoints KinesisCheckpointStore
+	settings    *KinesisInputSettings
+
+	owner       string
+	consumerArn *string
+	channel     chan *Message
+
+	lck      sync.Mutex
+	readers  map[string]*kinesisShardReader
+	wg       sync.WaitGroup
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewKinesisInput creates an input reading all shards of a kinesis stream. The shards are balanced between all
+// consumers of the application using leases, which are stored together with the checkpoints of the shards in ddb.
+// A checkpoint only covers the records whose messages were acknowledged, so the messages in progress are read
+// again after a crash.
+func NewKinesisInput(config cfg.Config, logger mon.Logger, settings *KinesisInputSettings) Input {
+	if settings.EnhancedFanOut && settings.Client.HttpTimeout < kinesisFanOutHttpTimeout {
+		settings.Client.HttpTimeout = kinesisFanOutHttpTimeout
+	}
+
+	awsConfig := cloud.GetAwsConfig(config, logger, "kinesis", &settings.Client)
+	sess := session.Must(session.NewSession(awsConfig))
+	client := kinesis.New(sess)
+
+	createKinesisStream(config, logger, client, settings)
+
+	checkpoints := NewDdbKinesisCheckpointStore(config, logger, &KinesisCheckpointStoreSettings{
+		StreamName:      settings.StreamName,
+		ApplicationName: settings.ApplicationName,
+		Backoff:         settings.Backoff,
+	})
+
+	metric := mon.NewMetricDaemonWriter()
+
+	return NewKinesisInputWithInterfaces(logger, clock.NewRealClock(), metric, client, checkpoints, uuid.New(), settings)
+}
+
+func NewKinesisInputWithInterfaces(logger mon.Logger, clock clock.Clock, metric mon.MetricWriter, client kinesisiface.KinesisAPI, checkpoints KinesisCheckpointStore, uuidSource uuid.Uuid, settings *KinesisInputSettings) Input {
+	owner := uuidSource.NewV4()
+
+	if settings.CheckpointInterval <= 0 || settings.CheckpointInterval >= settings.LeaseDuration {
+		settings.CheckpointInterval = settings.LeaseDuration / 3
+	}
+
+	if settings.MaxPendingRecords <= 0 {
+		settings.MaxPendingRecords = kinesisDefaultMaxPendingRecords
+	}
+
+	return &kinesisInput{
+		logger: logger.WithChannel("kinesis-input").WithFields(mon.Fields{
+			"stream_name":      settings.StreamName,
+			"application_name": settings.ApplicationName,
+			"owner":            owner,
+		}),
+		clock:       clock,
+		metric:      metric,
+		client:      client,
+		checkpoints: checkpoints,
+		settings:    settings,
+		owner:       owner,
+		channel:     make(chan *Message),
+		readers:     make(map[string]*kinesisShardReader),
+		stop:        make(chan struct{}),
+	}
+}
+
+func (i *kinesisInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *kinesisInput) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	defer func() {
+		cancel()
+		i.wg.Wait()
+		close(i.channel)
+	}()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-i.stop:
+			cancel()
+		}
+	}()
+
+	if i.settings.EnhancedFanOut {
+		if err := i.registerConsumer(ctx); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("can not register enhanced fan-out consumer: %w", err)
+		}
+	}
+
+	ticker := time.NewTicker(i.settings.DiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := i.balance(ctx); err != nil && ctx.Err() == nil {
+			i.logger.Error(err, "can not balance the shards of the stream")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (i *kinesisInput) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stop)
+	})
+}
+
+func (i *kinesisInput) Ack(msg *Message) error {
+	shardId, ok := msg.Attributes[AttributeKinesisShardId].(string)
+
+	if !ok {
+		return fmt.Errorf("the message has no attribute %s", AttributeKinesisShardId)
+	}
+
+	sequenceNumber, ok := msg.Attributes[AttributeKinesisSequenceNumber].(string)
+
+	if !ok {
+		return fmt.Errorf("the message has no attribute %s", AttributeKinesisSequenceNumber)
+	}
+
+	i.lck.Lock()
+	reader, ok := i.readers[shardId]
+	i.lck.Unlock()
+
+	// the shard was released in the meantime, the next owner reads the message again
+	if !ok {
+		return nil
+	}
+
+	reader.ack(sequenceNumber)
+
+	return nil
+}
+
+func (i *kinesisInput) AckBatch(msgs []*Message) error {
+	for _, msg := range msgs {
+		if err := i.Ack(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// balance spreads the available shards evenly over all consumers with an active lease. Every consumer releases
+// the shards exceeding its share and acquires free shards until it reached its share. A shard is available
+// if it isn't finished and all of its parents have been read until their end.
+func (i *kinesisInput) balance(ctx context.Context) error {
+	shards, err := i.listShards(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	checkpointList, err := i.checkpoints.List(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	now := i.clock.Now()
+	checkpoints := make(map[string]*KinesisShardCheckpoint, len(checkpointList))
+	owners := map[string]bool{i.owner: true}
+
+	for _, checkpoint := range checkpointList {
+		checkpoints[checkpoint.ShardId] = checkpoint
+
+		if !checkpoint.Finished && checkpoint.IsLeased(now) {
+			owners[checkpoint.Owner] = true
+		}
+	}
+
+	shardIds := make(map[string]bool, len(shards))
+	for _, shard := range shards {
+		shardIds[*shard.ShardId] = true
+	}
+
+	available := make([]*kinesis.Shard, 0, len(shards))
+
+	for _, shard := range shards {
+		if checkpoint, ok := checkpoints[*shard.ShardId]; ok && checkpoint.Finished {
+			continue
+		}
+
+		if !i.isParentFinished(shard.ParentShardId, shardIds, checkpoints) || !i.isParentFinished(shard.AdjacentParentShardId, shardIds, checkpoints) {
+			continue
+		}
+
+		available = append(available, shard)
+	}
+
+	target := int(math.Ceil(float64(len(available)) / float64(len(owners))))
+
+	i.lck.Lock()
+	defer i.lck.Unlock()
+
+	owned := make([]string, 0, len(i.readers))
+	for shardId, reader := range i.readers {
+		if !reader.stopping {
+			owned = append(owned, shardId)
+		}
+	}
+
+	sort.Strings(owned)
+
+	for len(owned) > target {
+		shardId := owned[len(owned)-1]
+		owned = owned[:len(owned)-1]
+
+		i.logger.Infof("releasing shard %s to balance %d shards between %d consumers", shardId, len(available), len(owners))
+		i.readers[shardId].stopping = true
+		i.readers[shardId].cancel()
+	}
+
+	for _, shard := range available {
+		if len(owned) >= target {
+			break
+		}
+
+		if _, ok := i.readers[*shard.ShardId]; ok {
+			continue
+		}
+
+		if checkpoint, ok := checkpoints[*shard.ShardId]; ok && checkpoint.IsLeased(now) && checkpoint.Owner != i.owner {
+			continue
+		}
+
+		checkpoint, acquired, err := i.checkpoints.Acquire(ctx, *shard.ShardId, i.owner, i.settings.LeaseDuration)
+
+		if err != nil {
+			return err
+		}
+
+		if !acquired {
+			continue
+		}
+
+		i.logger.Infof("acquired lease of shard %s", *shard.ShardId)
+		i.startReader(ctx, shard, checkpoint, shardIds)
+		owned = append(owned, *shard.ShardId)
+	}
+
+	return nil
+}
+
+func (i *kinesisInput) isParentFinished(parentShardId *string, shardIds map[string]bool, checkpoints map[string]*KinesisShardCheckpoint) bool {
+	// parents which already expired from the stream don't have to be read anymore
+	if parentShardId == nil || !shardIds[*parentShardId] {
+		return true
+	}
+
+	checkpoint, ok := checkpoints[*parentShardId]
+
+	return ok && checkpoint.Finished
+}
+
+func (i *kinesisInput) startReader(ctx context.Context, shard *kinesis.Shard, checkpoint *KinesisShardCheckpoint, shardIds map[string]bool) {
+	readerCtx, cancel := context.WithCancel(ctx)
+
+	reader := &kinesisShardReader{
+		shardId:        *shard.ShardId,
+		sequenceNumber: checkpoint.SequenceNumber,
+		acknowledged:   checkpoint.SequenceNumber,
+		records:        make(map[string]*kinesisPendingRecord),
+		cancel:         cancel,
+	}
+
+	position := &kinesisStartingPosition{
+		Type: i.settings.StartingPosition,
+	}
+
+	switch {
+	case checkpoint.SequenceNumber != "":
+		position.Type = kinesis.ShardIteratorTypeAfterSequenceNumber
+		position.SequenceNumber = aws.String(checkpoint.SequenceNumber)
+
+	case shard.ParentShardId != nil && shardIds[*shard.ParentShardId]:
+		position.Type = KinesisStartingPositionTrimHorizon
+
+	case position.Type == KinesisStartingPositionAtTimestamp:
+		position.Timestamp = aws.Time(i.settings.StartingTimestamp)
+	}
+
+	i.readers[reader.shardId] = reader
+	i.wg.Add(2)
+
+	go func() {
+		defer i.wg.Done()
+		defer cancel()
+
+		i.consumeShard(readerCtx, reader, position)
+	}()
+
+	go func() {
+		defer i.wg.Done()
+
+		i.renewLease(readerCtx, reader)
+	}()
+}
+
+// renewLease renews the lease of the shard and stores the acknowledged position independently of the delivery
+// of the messages, so a slow consumer keeps its lease. The reader is stopped once the lease is lost.
+func (i *kinesisInput) renewLease(ctx context.Context, reader *kinesisShardReader) {
+	ticker := time.NewTicker(i.settings.CheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sequenceNumber, _ := reader.checkpoint()
+		renewed, err := i.checkpoints.Renew(ctx, reader.shardId, i.owner, sequenceNumber, i.settings.LeaseDuration)
+
+		if err != nil {
+			if ctx.Err() == nil {
+				i.logger.Errorf(err, "can not renew lease of shard %s", reader.shardId)
+			}
+
+			continue
+		}
+
+		if !renewed {
+			i.logger.Warnf("lost lease of shard %s", reader.shardId)
+			reader.loseLease()
+			reader.cancel()
+
+			return
+		}
+	}
+}
+
+func (i *kinesisInput) consumeShard(ctx context.Context, reader *kinesisShardReader, position *kinesisStartingPosition) {
+	var err error
+	var finished bool
+
+	logger := i.logger.WithFields(mon.Fields{
+		"shard_id": reader.shardId,
+	})
+
+	if i.settings.EnhancedFanOut {
+		finished, err = i.subscribeShard(ctx, reader, position)
+	} else {
+		finished, err = i.pollShard(ctx, reader, position)
+	}
+
+	if err != nil && !exec.IsRequestCanceled(err) && ctx.Err() == nil {
+		logger.Error(err, "can not read shard")
+	}
+
+	if finished {
+		logger.Info("finished reading shard")
+		finished = i.awaitAcknowledgements(ctx, reader)
+	}
+
+	if !reader.hasLostLease() {
+		sequenceNumber, _ := reader.checkpoint()
+
+		// the context of the reader might be canceled already, but the lease should be released anyway
+		if err = i.checkpoints.Release(context.Background(), reader.shardId, i.owner, sequenceNumber, finished); err != nil {
+			logger.Error(err, "can not release lease of shard")
+		}
+	}
+
+	i.lck.Lock()
+	defer i.lck.Unlock()
+
+	if i.readers[reader.shardId] == reader {
+		delete(i.readers, reader.shardId)
+	}
+}
+
+// awaitAcknowledgements waits until all messages of a finished shard are acknowledged, as its child shards
+// are read once it is released as finished. It returns false if the reader is stopped before.
+func (i *kinesisInput) awaitAcknowledgements(ctx context.Context, reader *kinesisShardReader) bool {
+	for {
+		if _, done := reader.checkpoint(); done {
+			return true
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+
+		i.wait(ctx)
+	}
+}
+
+// pollShard reads the shard using GetRecords until it is finished or the context is canceled.
+func (i *kinesisInput) pollShard(ctx context.Context, reader *kinesisShardReader, position *kinesisStartingPosition) (bool, error) {
+	iterator, err := i.getShardIterator(ctx, reader.shardId, position)
+
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		out, err := i.client.GetRecordsWithContext(ctx, &kinesis.GetRecordsInput{
+			ShardIterator: iterator,
+			Limit:         aws.Int64(i.settings.MaxRecords),
+		})
+
+		switch {
+		case ctx.Err() != nil:
+			return false, nil
+
+		case gosoAws.IsAwsError(err, kinesis.ErrCodeExpiredIteratorException):
+			if reader.sequenceNumber != "" {
+				position = &kinesisStartingPosition{
+					Type:           kinesis.ShardIteratorTypeAfterSequenceNumber,
+					SequenceNumber: aws.String(reader.sequenceNumber),
+				}
+			}
+
+			if iterator, err = i.getShardIterator(ctx, reader.shardId, position); err != nil {
+				return false, err
+			}
+
+			continue
+
+		case gosoAws.IsAwsError(err, kinesis.ErrCodeProvisionedThroughputExceededException):
+			i.wait(ctx)
+			continue
+
+		case err != nil:
+			return false, fmt.Errorf("can not get records: %w", err)
+		}
+
+		i.writeLag(reader.shardId, out.MillisBehindLatest)
+
+		if err = i.handleRecords(ctx, reader, out.Records); err != nil {
+			return false, err
+		}
+
+		if ctx.Err() != nil {
+			return false, nil
+		}
+
+		if out.NextShardIterator == nil {
+			return true, nil
+		}
+
+		iterator = out.NextShardIterator
+
+		if len(out.Records) == 0 {
+			i.wait(ctx)
+		}
+	}
+}
+
+func (i *kinesisInput) getShardIterator(ctx context.Context, shardId string, position *kinesisStartingPosition) (*string, error) {
+	out, err := i.client.GetShardIteratorWithContext(ctx, &kinesis.GetShardIteratorInput{
+		StreamName:             aws.String(i.settings.StreamName),
+		ShardId:                aws.String(shardId),
+		ShardIteratorType:      aws.String(position.Type),
+		StartingSequenceNumber: position.SequenceNumber,
+		Timestamp:              position.Timestamp,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("can not get shard iterator: %w", err)
+	}
+
+	return out.ShardIterator, nil
+}
+
+// subscribeShard reads the shard using enhanced fan-out. As every subscription expires after 5 minutes,
+// the shard is subscribed again after the last handed out record until the shard is finished.
+func (i *kinesisInput) subscribeShard(ctx context.Context, reader *kinesisShardReader, position *kinesisStartingPosition) (bool, error) {
+	for {
+		out, err := i.client.SubscribeToShardWithContext(ctx, &kinesis.SubscribeToShardInput{
+			ConsumerARN: i.consumerArn,
+			ShardId:     aws.String(reader.shardId),
+			StartingPosition: &kinesis.StartingPosition{
+				Type:           aws.String(position.Type),
+				SequenceNumber: position.SequenceNumber,
+				Timestamp:      position.Timestamp,
+			},
+		})
+
+		switch {
+		case ctx.Err() != nil:
+			return false, nil
+
+		// the previous subscription of the shard might still be active for some seconds
+		case gosoAws.IsAwsError(err, kinesis.ErrCodeResourceInUseException), gosoAws.IsAwsError(err, kinesis.ErrCodeLimitExceededException):
+			i.wait(ctx)
+			continue
+
+		case err != nil:
+			return false, fmt.Errorf("can not subscribe to shard: %w", err)
+		}
+
+		finished, continuation, err := i.consumeSubscription(ctx, reader, out.EventStream)
+
+		if finished || ctx.Err() != nil {
+			return finished, nil
+		}
+
+		if err != nil {
+			i.logger.WithFields(mon.Fields{
+				"shard_id": reader.shardId,
+			}).Warnf("subscription to shard ended with error: %s", err.Error())
+		}
+
+		switch {
+		case reader.sequenceNumber != "":
+			position = &kinesisStartingPosition{
+				Type:           kinesis.ShardIteratorTypeAfterSequenceNumber,
+				SequenceNumber: aws.String(reader.sequenceNumber),
+			}
+
+		case continuation != nil:
+			position = &kinesisStartingPosition{
+				Type:           kinesis.ShardIteratorTypeAtSequenceNumber,
+				SequenceNumber: continuation,
+			}
+		}
+	}
+}
+
+func (i *kinesisInput) consumeSubscription(ctx context.Context, reader *kinesisShardReader, stream *kinesis.SubscribeToShardEventStream) (bool, *string, error) {
+	defer func() {
+		if err := stream.Close(); err != nil {
+			i.logger.Warnf("can not close subscription to shard %s: %s", reader.shardId, err.Error())
+		}
+	}()
+
+	var continuation *string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, continuation, nil
+
+		case event, ok := <-stream.Events():
+			if !ok {
+				return false, continuation, stream.Err()
+			}
+
+			shardEvent, ok := event.(*kinesis.SubscribeToShardEvent)
+
+			if !ok {
+				continue
+			}
+
+			i.writeLag(reader.shardId, shardEvent.MillisBehindLatest)
+
+			if err := i.handleRecords(ctx, reader, shardEvent.Records); err != nil {
+				return false, continuation, err
+			}
+
+			if ctx.Err() != nil {
+				return false, continuation, nil
+			}
+
+			// the continuation sequence number is missing after the end of a closed shard was reached
+			if shardEvent.ContinuationSequenceNumber == nil {
+				return true, nil, nil
+			}
+
+			continuation = shardEvent.ContinuationSequenceNumber
+		}
+	}
+}
+
+func (i *kinesisInput) handleRecords(ctx context.Context, reader *kinesisShardReader, records []*kinesis.Record) error {
+	for _, record := range records {
+		messages, err := kinesisRecordToMessages(record.Data)
+
+		if err != nil {
+			i.logger.WithFields(mon.Fields{
+				"shard_id":        reader.shardId,
+				"sequence_number": *record.SequenceNumber,
+			}).Error(err, "can not decode record")
+		}
+
+		if !i.awaitPending(ctx, reader) {
+			return nil
+		}
+
+		reader.track(*record.SequenceNumber, len(messages))
+
+		for _, msg := range messages {
+			if msg.Attributes == nil {
+				msg.Attributes = make(map[string]interface{})
+			}
+
+			msg.Attributes[AttributeKinesisShardId] = reader.shardId
+			msg.Attributes[AttributeKinesisSequenceNumber] = *record.SequenceNumber
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case i.channel <- msg:
+			}
+		}
+
+		reader.sequenceNumber = *record.SequenceNumber
+	}
+
+	return nil
+}
+
+// awaitPending pauses the reading of the shard while the number of pending records exceeds the limit. A message
+// which is never acknowledged holds the checkpoint of the shard, so the sequence number of its record is reported
+// until it is acknowledged. It returns false if the reader is stopped before.
+func (i *kinesisInput) awaitPending(ctx context.Context, reader *kinesisShardReader) bool {
+	for {
+		pending, sequenceNumber := reader.oldestPending()
+
+		if pending < i.settings.MaxPendingRecords {
+			return true
+		}
+
+		i.logger.WithFields(mon.Fields{
+			"shard_id":        reader.shardId,
+			"sequence_number": sequenceNumber,
+		}).Warnf("paused reading the shard as %d records are pending, waiting for the acknowledgement of the oldest one", pending)
+		i.writePending(reader.shardId, pending)
+
+		i.wait(ctx)
+
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
+
+func (i *kinesisInput) listShards(ctx context.Context) ([]*kinesis.Shard, error) {
+	shards := make([]*kinesis.Shard, 0)
+	input := &kinesis.ListShardsInput{
+		StreamName: aws.String(i.settings.StreamName),
+	}
+
+	for {
+		out, err := i.client.ListShardsWithContext(ctx, input)
+
+		if err != nil {
+			return nil, fmt.Errorf("can not list shards: %w", err)
+		}
+
+		shards = append(shards, out.Shards...)
+
+		if out.NextToken == nil {
+			return shards, nil
+		}
+
+		input = &kinesis.ListShardsInput{
+			NextToken: out.NextToken,
+		}
+	}
+}
+
+func (i *kinesisInput) registerConsumer(ctx context.Context) error {
+	summary, err := i.client.DescribeStreamSummaryWithContext(ctx, &kinesis.DescribeStreamSummaryInput{
+		StreamName: aws.String(i.settings.StreamName),
+	})
+
+	if err != nil {
+		return fmt.Errorf("can not describe stream: %w", err)
+	}
+
+	streamArn := summary.StreamDescriptionSummary.StreamARN
+	consumerName := aws.String(i.settings.ApplicationName)
+
+	_, err = i.client.RegisterStreamConsumerWithContext(ctx, &kinesis.RegisterStreamConsumerInput{
+		ConsumerName: consumerName,
+		StreamARN:    streamArn,
+	})
+
+	if err != nil && !gosoAws.IsAwsError(err, kinesis.ErrCodeResourceInUseException) {
+		return fmt.Errorf("can not register stream consumer %s: %w", *consumerName, err)
+	}
+
+	for {
+		out, err := i.client.DescribeStreamConsumerWithContext(ctx, &kinesis.DescribeStreamConsumerInput{
+			ConsumerName: consumerName,
+			StreamARN:    streamArn,
+		})
+
+		if err != nil {
+			return fmt.Errorf("can not describe stream consumer %s: %w", *consumerName, err)
+		}
+
+		if *out.ConsumerDescription.ConsumerStatus == kinesis.ConsumerStatusActive {
+			i.consumerArn = out.ConsumerDescription.ConsumerARN
+			i.logger.Infof("using enhanced fan-out consumer %s", *i.consumerArn)
+
+			return nil
+		}
+
+		i.wait(ctx)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (i *kinesisInput) writeLag(shardId string, millisBehindLatest *int64) {
+	if millisBehindLatest == nil {
+		return
+	}
+
+	i.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: MetricNameKinesisMillisBehindLatest,
+		Dimensions: map[string]string{
+			"StreamName": i.settings.StreamName,
+			"ShardId":    shardId,
+		},
+		Unit:  mon.UnitMilliseconds,
+		Value: float64(*millisBehindLatest),
+	})
+}
+
+func (i *kinesisInput) writePending(shardId string, pending int) {
+	i.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: MetricNameKinesisPendingRecords,
+		Dimensions: map[string]string{
+			"StreamName": i.settings.StreamName,
+			"ShardId":    shardId,
+		},
+		Unit:  mon.UnitCount,
+		Value: float64(pending),
+	})
+}
+
+func (i *kinesisInput) wait(ctx context.Context) {
+	timer := time.NewTimer(i.settings.WaitTime)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func kinesisRecordToMessages(data []byte) ([]*Message, error) {
+	records, err := DeaggregateKinesisRecord(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*Message, 0, len(records))
+
+	for _, record := range records {
+		msg := &Message{}
+
+		if err := msg.UnmarshalFromBytes(record); err != nil {
+			return messages, fmt.Errorf("can not unmarshal message: %w", err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
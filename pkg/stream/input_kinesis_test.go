@@ -0,0 +1,304 @@
+package stream_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/applike/gosoline/pkg/clock"
+	cloudMocks "github.com/applike/gosoline/pkg/cloud/mocks"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	uuidMocks "github.com/applike/gosoline/pkg/uuid/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKinesisInput_ReshardedStream(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	metric := new(monMocks.MetricWriter)
+	metric.On("WriteOne", mock.Anything)
+
+	uuidSource := new(uuidMocks.Uuid)
+	uuidSource.On("NewV4").Return("owner")
+
+	parentMsg := stream.NewMessage("parent")
+	childMsg := stream.NewMessage("child")
+	parentBytes, _ := json.Marshal(parentMsg)
+	childBytes, _ := json.Marshal(childMsg)
+
+	client := new(cloudMocks.KinesisAPI)
+	client.On("ListShardsWithContext", mock.Anything, &kinesis.ListShardsInput{
+		StreamName: aws.String("stream"),
+	}).Return(&kinesis.ListShardsOutput{
+		Shards: []*kinesis.Shard{
+			{ShardId: aws.String("shard-0")},
+			{ShardId: aws.String("shard-1"), ParentShardId: aws.String("shard-0")},
+		},
+	}, nil)
+
+	for _, shardId := range []string{"shard-0", "shard-1"} {
+		client.On("GetShardIteratorWithContext", mock.Anything, &kinesis.GetShardIteratorInput{
+			StreamName:        aws.String("stream"),
+			ShardId:           aws.String(shardId),
+			ShardIteratorType: aws.String(kinesis.ShardIteratorTypeTrimHorizon),
+		}).Return(&kinesis.GetShardIteratorOutput{
+			ShardIterator: aws.String(shardId),
+		}, nil).Once()
+	}
+
+	// the parent shard is closed and ends after its first record
+	client.On("GetRecordsWithContext", mock.Anything, &kinesis.GetRecordsInput{
+		ShardIterator: aws.String("shard-0"),
+		Limit:         aws.Int64(100),
+	}).Return(&kinesis.GetRecordsOutput{
+		MillisBehindLatest: aws.Int64(0),
+		Records: []*kinesis.Record{
+			{SequenceNumber: aws.String("1"), Data: parentBytes},
+		},
+	}, nil).Once()
+
+	client.On("GetRecordsWithContext", mock.Anything, &kinesis.GetRecordsInput{
+		ShardIterator: aws.String("shard-1"),
+		Limit:         aws.Int64(100),
+	}).Return(&kinesis.GetRecordsOutput{
+		MillisBehindLatest: aws.Int64(0),
+		NextShardIterator:  aws.String("shard-1-next"),
+		Records: []*kinesis.Record{
+			{SequenceNumber: aws.String("2"), Data: childBytes},
+		},
+	}, nil).Once()
+
+	client.On("GetRecordsWithContext", mock.Anything, &kinesis.GetRecordsInput{
+		ShardIterator: aws.String("shard-1-next"),
+		Limit:         aws.Int64(100),
+	}).Return(&kinesis.GetRecordsOutput{
+		NextShardIterator: aws.String("shard-1-next"),
+	}, nil).Maybe()
+
+	lck := sync.Mutex{}
+	parentFinished := false
+
+	checkpoints := new(streamMocks.KinesisCheckpointStore)
+	checkpoints.On("List", mock.Anything).Return(func(ctx context.Context) []*stream.KinesisShardCheckpoint {
+		lck.Lock()
+		defer lck.Unlock()
+
+		if !parentFinished {
+			return []*stream.KinesisShardCheckpoint{}
+		}
+
+		return []*stream.KinesisShardCheckpoint{
+			{ShardId: "shard-0", SequenceNumber: "1", Finished: true},
+		}
+	}, nil)
+	checkpoints.On("Acquire", mock.Anything, "shard-0", "owner", time.Minute).Return(&stream.KinesisShardCheckpoint{ShardId: "shard-0"}, true, nil).Once()
+	checkpoints.On("Acquire", mock.Anything, "shard-1", "owner", time.Minute).Return(&stream.KinesisShardCheckpoint{ShardId: "shard-1"}, true, nil).Once()
+	checkpoints.On("Renew", mock.Anything, mock.AnythingOfType("string"), "owner", mock.AnythingOfType("string"), time.Minute).Return(true, nil).Maybe()
+	checkpoints.On("Release", mock.Anything, "shard-0", "owner", "1", true).Run(func(args mock.Arguments) {
+		lck.Lock()
+		defer lck.Unlock()
+
+		parentFinished = true
+	}).Return(nil).Once()
+	checkpoints.On("Release", mock.Anything, "shard-1", "owner", "2", false).Return(nil).Once()
+
+	input := stream.NewKinesisInputWithInterfaces(logger, clock.NewFakeClock(), metric, client, checkpoints, uuidSource, &stream.KinesisInputSettings{
+		StreamName:         "stream",
+		ApplicationName:    "app",
+		StartingPosition:   stream.KinesisStartingPositionTrimHorizon,
+		MaxRecords:         100,
+		WaitTime:           time.Millisecond,
+		LeaseDuration:      time.Minute,
+		DiscoveryInterval:  time.Millisecond,
+		CheckpointInterval: time.Millisecond,
+	})
+
+	var err error
+	done := make(chan struct{})
+
+	go func() {
+		err = input.Run(context.Background())
+		close(done)
+	}()
+
+	received := make([]string, 0)
+	acknowledgeable := input.(stream.AcknowledgeableInput)
+
+	for msg := range input.Data() {
+		received = append(received, msg.Body)
+
+		// the parent shard is only released as finished and the child shard read after its message is acknowledged
+		assert.NoError(t, acknowledgeable.Ack(msg))
+
+		if len(received) == 2 {
+			input.Stop()
+		}
+	}
+
+	<-done
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{parentMsg.Body, childMsg.Body}, received)
+
+	client.AssertExpectations(t)
+	checkpoints.AssertExpectations(t)
+}
+
+func getSingleShardKinesisInput(records []*kinesis.Record, maxPendingRecords int) (stream.Input, *cloudMocks.KinesisAPI, *streamMocks.KinesisCheckpointStore) {
+	logger := monMocks.NewLoggerMockedAll()
+	metric := new(monMocks.MetricWriter)
+	metric.On("WriteOne", mock.Anything)
+
+	uuidSource := new(uuidMocks.Uuid)
+	uuidSource.On("NewV4").Return("owner")
+
+	client := new(cloudMocks.KinesisAPI)
+	client.On("ListShardsWithContext", mock.Anything, mock.Anything).Return(&kinesis.ListShardsOutput{
+		Shards: []*kinesis.Shard{
+			{ShardId: aws.String("shard-0")},
+		},
+	}, nil)
+	client.On("GetShardIteratorWithContext", mock.Anything, mock.Anything).Return(&kinesis.GetShardIteratorOutput{
+		ShardIterator: aws.String("iterator"),
+	}, nil).Once()
+	client.On("GetRecordsWithContext", mock.Anything, mock.Anything).Return(&kinesis.GetRecordsOutput{
+		NextShardIterator: aws.String("iterator"),
+		Records:           records,
+	}, nil).Once()
+	client.On("GetRecordsWithContext", mock.Anything, mock.Anything).Return(&kinesis.GetRecordsOutput{
+		NextShardIterator: aws.String("iterator"),
+	}, nil).Maybe()
+
+	checkpoints := new(streamMocks.KinesisCheckpointStore)
+	checkpoints.On("List", mock.Anything).Return([]*stream.KinesisShardCheckpoint{}, nil)
+	checkpoints.On("Acquire", mock.Anything, "shard-0", "owner", time.Minute).Return(&stream.KinesisShardCheckpoint{ShardId: "shard-0"}, true, nil).Once()
+
+	input := stream.NewKinesisInputWithInterfaces(logger, clock.NewFakeClock(), metric, client, checkpoints, uuidSource, &stream.KinesisInputSettings{
+		StreamName:         "stream",
+		ApplicationName:    "app",
+		StartingPosition:   stream.KinesisStartingPositionTrimHorizon,
+		MaxRecords:         100,
+		WaitTime:           time.Millisecond,
+		LeaseDuration:      time.Minute,
+		DiscoveryInterval:  time.Hour,
+		CheckpointInterval: time.Millisecond,
+		MaxPendingRecords:  maxPendingRecords,
+	})
+
+	return input, client, checkpoints
+}
+
+func TestKinesisInput_CheckpointAcknowledged(t *testing.T) {
+	first, _ := json.Marshal(stream.NewMessage("first"))
+	second, _ := json.Marshal(stream.NewMessage("second"))
+
+	input, _, checkpoints := getSingleShardKinesisInput([]*kinesis.Record{
+		{SequenceNumber: aws.String("1"), Data: first},
+		{SequenceNumber: aws.String("2"), Data: second},
+	}, 0)
+
+	// the second record is acknowledged, but the first one is still in progress, so nothing can be checkpointed
+	checkpoints.On("Renew", mock.Anything, "shard-0", "owner", "", time.Minute).Return(true, nil).Maybe()
+	checkpoints.On("Release", mock.Anything, "shard-0", "owner", "", false).Return(nil).Once()
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run(context.Background())
+	}()
+
+	<-input.Data()
+	msg := <-input.Data()
+
+	assert.Equal(t, "second", msg.Body)
+	assert.Equal(t, "shard-0", msg.Attributes[stream.AttributeKinesisShardId])
+	assert.Equal(t, "2", msg.Attributes[stream.AttributeKinesisSequenceNumber])
+	assert.NoError(t, input.(stream.AcknowledgeableInput).Ack(msg))
+
+	input.Stop()
+
+	for range input.Data() {
+	}
+
+	assert.NoError(t, <-done)
+	checkpoints.AssertExpectations(t)
+}
+
+func TestKinesisInput_LostLease(t *testing.T) {
+	first, _ := json.Marshal(stream.NewMessage("first"))
+
+	input, _, checkpoints := getSingleShardKinesisInput([]*kinesis.Record{
+		{SequenceNumber: aws.String("1"), Data: first},
+	}, 0)
+
+	lost := make(chan struct{})
+	checkpoints.On("Renew", mock.Anything, "shard-0", "owner", "", time.Minute).Run(func(args mock.Arguments) {
+		close(lost)
+	}).Return(false, nil).Once()
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run(context.Background())
+	}()
+
+	// the lease is lost while the consumer is still busy with the message, so the shard isn't released
+	msg := <-input.Data()
+	<-lost
+
+	assert.Equal(t, "first", msg.Body)
+
+	input.Stop()
+
+	for range input.Data() {
+	}
+
+	assert.NoError(t, <-done)
+	checkpoints.AssertExpectations(t)
+	checkpoints.AssertNotCalled(t, "Release", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestKinesisInput_MaxPendingRecords(t *testing.T) {
+	first, _ := json.Marshal(stream.NewMessage("first"))
+	second, _ := json.Marshal(stream.NewMessage("second"))
+
+	input, _, checkpoints := getSingleShardKinesisInput([]*kinesis.Record{
+		{SequenceNumber: aws.String("1"), Data: first},
+		{SequenceNumber: aws.String("2"), Data: second},
+	}, 1)
+
+	checkpoints.On("Renew", mock.Anything, "shard-0", "owner", mock.Anything, time.Minute).Return(true, nil).Maybe()
+	checkpoints.On("Release", mock.Anything, "shard-0", "owner", "2", false).Return(nil).Once()
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run(context.Background())
+	}()
+
+	msg := <-input.Data()
+	assert.Equal(t, "first", msg.Body)
+
+	// the shard is paused until the pending record is acknowledged
+	select {
+	case msg = <-input.Data():
+		assert.Fail(t, "the shard should be paused", "received message %s", msg.Body)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, input.(stream.AcknowledgeableInput).Ack(msg))
+
+	msg = <-input.Data()
+	assert.Equal(t, "second", msg.Body)
+	assert.NoError(t, input.(stream.AcknowledgeableInput).Ack(msg))
+
+	input.Stop()
+
+	for range input.Data() {
+	}
+
+	assert.NoError(t, <-done)
+	checkpoints.AssertExpectations(t)
+}
@@ -28,6 +28,7 @@ func (k *KinsumerSettings) GetResourceName() string {
 	return k.StreamName
 }
 
+// Deprecated: NewKinsumerInput is kept for existing applications, the kinesis input type uses NewKinesisInput instead.
 func NewKinsumerInput(config cfg.Config, logger mon.Logger, factory KinsumerFactory, settings KinsumerSettings) Input {
 	client := factory(config, logger, settings)
 
@@ -83,15 +84,24 @@ func (i *kinsumerInput) Run(ctx context.Context) error {
 			}
 
 		case rawMessage != nil: // rawMessage received
-			msg := Message{}
-			err := json.Unmarshal(rawMessage, &msg)
+			records, err := DeaggregateKinesisRecord(rawMessage)
 
 			if err != nil {
-				i.logger.Error(err, "could not unmarshal message")
+				i.logger.Error(err, "could not deaggregate record")
 				continue
 			}
 
-			i.channel <- &msg
+			for _, record := range records {
+				msg := Message{}
+				err := json.Unmarshal(record, &msg)
+
+				if err != nil {
+					i.logger.Error(err, "could not unmarshal message")
+					continue
+				}
+
+				i.channel <- &msg
+			}
 		}
 	}
 }
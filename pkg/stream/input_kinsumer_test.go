@@ -2,6 +2,7 @@ package stream_test
 
 import (
 	"context"
+	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
@@ -122,3 +123,59 @@ func TestReaderRestartTrigger(t *testing.T) {
 	assert.Equal(t, msg, out, "the messages should match")
 	kinsumerMock.AssertExpectations(t)
 }
+
+// kplAggregate builds a record in the KPL aggregation format using partition key "a" for
+// every record. It only supports records shorter than 120 bytes to keep the varints simple.
+func kplAggregate(records ...[]byte) []byte {
+	proto := []byte{0x0a, 0x01, 'a'}
+
+	for _, record := range records {
+		encoded := append([]byte{0x08, 0x00, 0x1a, byte(len(record))}, record...)
+		proto = append(proto, 0x1a, byte(len(encoded)))
+		proto = append(proto, encoded...)
+	}
+
+	checksum := md5.Sum(proto)
+
+	aggregated := append([]byte{0xF3, 0x89, 0x9A, 0xC2}, proto...)
+
+	return append(aggregated, checksum[:]...)
+}
+
+func TestReaderDeaggregation(t *testing.T) {
+	configMock := new(configMocks.Config)
+	loggerMock := new(monMocks.Logger)
+
+	msg1 := stream.NewMessage("foo")
+	msg2 := stream.NewMessage("bar")
+
+	bytes1, _ := json.Marshal(msg1)
+	bytes2, _ := json.Marshal(msg2)
+
+	kinsumerMock := new(streamMocks.Kinsumer)
+	kinsumerMock.On("Run").Return(nil)
+	kinsumerMock.On("Next").Return(kplAggregate(bytes1, bytes2), nil).Once()
+	kinsumerMock.On("Next").Return(nil, nil).Once()
+	kinsumerMock.On("Stop")
+
+	factory := func(config cfg.Config, logger mon.Logger, settings stream.KinsumerSettings) stream.Kinsumer {
+		return kinsumerMock
+	}
+
+	reader := stream.NewKinsumerInput(configMock, loggerMock, factory, stream.KinsumerSettings{})
+
+	go func() {
+		err := reader.Run(context.Background())
+		assert.NoError(t, err)
+	}()
+
+	out := make([]*stream.Message, 0)
+	for msg := range reader.Data() {
+		out = append(out, msg)
+	}
+
+	reader.Stop()
+
+	assert.Equal(t, []*stream.Message{msg1, msg2}, out)
+	kinsumerMock.AssertExpectations(t)
+}
@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"context"
+	"sync"
+)
+
+// inputPause implements the PausableInput interface for inputs which wait for the resume before fetching messages.
+type inputPause struct {
+	lck     sync.Mutex
+	resumed chan struct{}
+}
+
+func (p *inputPause) Pause() {
+	p.lck.Lock()
+	defer p.lck.Unlock()
+
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+}
+
+func (p *inputPause) Resume() {
+	p.lck.Lock()
+	defer p.lck.Unlock()
+
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+// waitWhilePaused blocks until the input is resumed or the context is done.
+func (p *inputPause) waitWhilePaused(ctx context.Context) {
+	p.lck.Lock()
+	resumed := p.resumed
+	p.lck.Unlock()
+
+	if resumed == nil {
+		return
+	}
+
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+	}
+}
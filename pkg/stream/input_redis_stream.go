@@ -0,0 +1,306 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/redis"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/twinj/uuid"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redisStreamMessageField = "message"
+
+type RedisStreamInputSettings struct {
+	cfg.AppId
+	ServerName string
+	Key        string
+	// Group is the consumer group, all inputs of a group share the messages of the stream. Defaults to the app name
+	Group string
+	// Consumer is the name of the input within the group, defaults to a random name
+	Consumer string
+	// StartId is the id of the first message read by a new group, $ reads only new messages
+	StartId   string
+	BatchSize int64
+	WaitTime  time.Duration
+	// ClaimInterval is the time between two scans of the pending messages of the other consumers
+	ClaimInterval time.Duration
+	// ClaimMinIdle is the time after which a message which is not acknowledged is claimed by another consumer
+	ClaimMinIdle time.Duration
+}
+
+// redisStreamInput reads the messages of a redis stream as a member of a consumer group. A message stays pending
+// until it is acknowledged, if a consumer dies while processing it, the message is claimed by another consumer
+// of the group after it was idle for ClaimMinIdle.
+type redisStreamInput struct {
+	logger   mon.Logger
+	client   redis.Client
+	settings *RedisStreamInputSettings
+
+	channel           chan *Message
+	stopOnce          sync.Once
+	stopped           chan struct{}
+	fullyQualifiedKey string
+	lastClaim         time.Time
+}
+
+func NewRedisStreamInput(config cfg.Config, logger mon.Logger, settings *RedisStreamInputSettings) Input {
+	settings.PadFromConfig(config)
+
+	if settings.Group == "" {
+		settings.Group = config.GetString("app_name")
+	}
+
+	client := redis.ProvideClient(config, logger, settings.ServerName)
+
+	return NewRedisStreamInputWithInterfaces(logger, client, settings)
+}
+
+func NewRedisStreamInputWithInterfaces(logger mon.Logger, client redis.Client, settings *RedisStreamInputSettings) *redisStreamInput {
+	if settings.Consumer == "" {
+		settings.Consumer = uuid.NewV4().String()
+	}
+
+	fullyQualifiedKey := redis.GetFullyQualifiedKey(settings.AppId, settings.Key)
+
+	return &redisStreamInput{
+		logger:            logger,
+		client:            client,
+		settings:          settings,
+		channel:           make(chan *Message),
+		stopped:           make(chan struct{}),
+		fullyQualifiedKey: fullyQualifiedKey,
+	}
+}
+
+func (i *redisStreamInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *redisStreamInput) Run(ctx context.Context) error {
+	defer close(i.channel)
+
+	if i.settings.WaitTime == 0 {
+		return errors.New("wait time should be bigger than 0")
+	}
+
+	if err := i.createGroup(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-i.stopped:
+			return nil
+		default:
+		}
+
+		if err := i.claimIfDue(ctx); err != nil {
+			i.logger.Error(err, "could not claim the pending messages of the redis stream")
+		}
+
+		streams, err := i.client.XReadGroup(&baseRedis.XReadGroupArgs{
+			Group:    i.settings.Group,
+			Consumer: i.settings.Consumer,
+			Streams:  []string{i.fullyQualifiedKey, ">"},
+			Count:    i.settings.BatchSize,
+			Block:    i.settings.WaitTime,
+		})
+
+		if err != nil && err.Error() != redis.Nil.Error() {
+			i.logger.Error(err, "could not XReadGroup from redis")
+			return err
+		}
+
+		for _, stream := range streams {
+			i.deliver(ctx, stream.Messages)
+		}
+	}
+}
+
+func (i *redisStreamInput) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stopped)
+	})
+}
+
+func (i *redisStreamInput) Ack(msg *Message) error {
+	return i.AckBatch([]*Message{msg})
+}
+
+func (i *redisStreamInput) AckBatch(msgs []*Message) error {
+	ids := make([]string, 0, len(msgs))
+
+	for _, msg := range msgs {
+		id, ok := msg.Attributes[AttributeRedisStreamId].(string)
+
+		if !ok || id == "" {
+			return fmt.Errorf("the message has no attribute %s", AttributeRedisStreamId)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if _, err := i.client.XAck(i.fullyQualifiedKey, i.settings.Group, ids...); err != nil {
+		return fmt.Errorf("could not XAck the messages: %w", err)
+	}
+
+	return nil
+}
+
+func (i *redisStreamInput) createGroup() error {
+	_, err := i.client.XGroupCreateMkStream(i.fullyQualifiedKey, i.settings.Group, i.settings.StartId)
+
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("could not create the consumer group %s of the redis stream %s: %w", i.settings.Group, i.fullyQualifiedKey, err)
+	}
+
+	return nil
+}
+
+// claimIfDue takes over the messages of the other consumers of the group which are pending for longer than
+// ClaimMinIdle. The pending entries are scanned page by page, so stale entries behind recent ones are found, too.
+func (i *redisStreamInput) claimIfDue(ctx context.Context) error {
+	if time.Since(i.lastClaim) < i.settings.ClaimInterval {
+		return nil
+	}
+
+	i.lastClaim = time.Now()
+	start := "-"
+
+	for {
+		pending, err := i.client.XPendingExt(&baseRedis.XPendingExtArgs{
+			Stream: i.fullyQualifiedKey,
+			Group:  i.settings.Group,
+			Start:  start,
+			End:    "+",
+			Count:  i.settings.BatchSize,
+		})
+
+		if err != nil {
+			return fmt.Errorf("could not XPending: %w", err)
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if err := i.claim(ctx, pending); err != nil {
+			return err
+		}
+
+		if int64(len(pending)) < i.settings.BatchSize {
+			return nil
+		}
+
+		if start, err = nextRedisStreamId(pending[len(pending)-1].Id); err != nil {
+			return err
+		}
+	}
+}
+
+func (i *redisStreamInput) claim(ctx context.Context, pending []baseRedis.XPendingExt) error {
+	ids := make([]string, 0, len(pending))
+
+	for _, entry := range pending {
+		// the messages of this consumer are in progress, they are pending because they are not acknowledged yet
+		if entry.Consumer == i.settings.Consumer || entry.Idle < i.settings.ClaimMinIdle {
+			continue
+		}
+
+		ids = append(ids, entry.Id)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	messages, err := i.client.XClaim(&baseRedis.XClaimArgs{
+		Stream:   i.fullyQualifiedKey,
+		Group:    i.settings.Group,
+		Consumer: i.settings.Consumer,
+		MinIdle:  i.settings.ClaimMinIdle,
+		Messages: ids,
+	})
+
+	if err != nil {
+		return fmt.Errorf("could not XClaim: %w", err)
+	}
+
+	i.logger.Infof("claimed %d pending messages of the redis stream %s", len(messages), i.fullyQualifiedKey)
+	i.deliver(ctx, messages)
+
+	return nil
+}
+
+// nextRedisStreamId returns the smallest id after the given one, so a range starting with it excludes the given id.
+func nextRedisStreamId(id string) (string, error) {
+	parts := strings.SplitN(id, "-", 2)
+
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid redis stream id %s", id)
+	}
+
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid redis stream id %s: %w", id, err)
+	}
+
+	return fmt.Sprintf("%s-%d", parts[0], seq+1), nil
+}
+
+func (i *redisStreamInput) deliver(ctx context.Context, messages []baseRedis.XMessage) {
+	for _, message := range messages {
+		msg, err := i.unmarshal(message)
+
+		if err != nil {
+			// a message which can't be unmarshalled is acknowledged, as it would be claimed over and over again otherwise
+			i.logger.Error(err, "could not unmarshal message")
+
+			if _, err := i.client.XAck(i.fullyQualifiedKey, i.settings.Group, message.ID); err != nil {
+				i.logger.Error(err, "could not XAck the invalid message")
+			}
+
+			continue
+		}
+
+		select {
+		case i.channel <- msg:
+		case <-ctx.Done():
+			return
+		case <-i.stopped:
+			return
+		}
+	}
+}
+
+func (i *redisStreamInput) unmarshal(message baseRedis.XMessage) (*Message, error) {
+	body, ok := message.Values[redisStreamMessageField].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("the entry %s has no field %s", message.ID, redisStreamMessageField)
+	}
+
+	msg := &Message{}
+
+	if err := msg.UnmarshalFromString(body); err != nil {
+		return nil, fmt.Errorf("can not unmarshal the entry %s: %w", message.ID, err)
+	}
+
+	if msg.Attributes == nil {
+		msg.Attributes = make(map[string]interface{})
+	}
+
+	msg.Attributes[AttributeRedisStreamId] = message.ID
+
+	return msg, nil
+}
@@ -0,0 +1,137 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/redis"
+	redisMocks "github.com/applike/gosoline/pkg/redis/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const redisStreamTestKey = "mcoins-test-analytics-app-my-stream"
+
+type redisStreamTestInput interface {
+	stream.Input
+	stream.AcknowledgeableInput
+}
+
+func getRedisStreamInput(redisMock *redisMocks.Client) redisStreamTestInput {
+	loggerMock := mocks.NewLoggerMockedAll()
+
+	return stream.NewRedisStreamInputWithInterfaces(loggerMock, redisMock, &stream.RedisStreamInputSettings{
+		AppId: cfg.AppId{
+			Project:     "mcoins",
+			Environment: "test",
+			Family:      "analytics",
+			Application: "app",
+		},
+		Key:           "my-stream",
+		Group:         "consumer-app",
+		Consumer:      "consumer-1",
+		StartId:       "$",
+		BatchSize:     2,
+		WaitTime:      time.Second,
+		ClaimInterval: time.Hour,
+		ClaimMinIdle:  time.Minute,
+	})
+}
+
+func TestRedisStreamInput_Run(t *testing.T) {
+	redisMock := new(redisMocks.Client)
+	input := getRedisStreamInput(redisMock)
+
+	redisMock.On("XGroupCreateMkStream", redisStreamTestKey, "consumer-app", "$").Return("", fmt.Errorf("BUSYGROUP Consumer Group name already exists")).Once()
+
+	// the own pending messages of the input are in progress and must not be claimed
+	redisMock.On("XPendingExt", &baseRedis.XPendingExtArgs{
+		Stream: redisStreamTestKey,
+		Group:  "consumer-app",
+		Start:  "-",
+		End:    "+",
+		Count:  2,
+	}).Return([]baseRedis.XPendingExt{
+		{Id: "0-5", Consumer: "consumer-1", Idle: 2 * time.Minute},
+		{Id: "0-6", Consumer: "consumer-0", Idle: time.Second},
+	}, nil).Once()
+
+	redisMock.On("XPendingExt", &baseRedis.XPendingExtArgs{
+		Stream: redisStreamTestKey,
+		Group:  "consumer-app",
+		Start:  "0-7",
+		End:    "+",
+		Count:  2,
+	}).Return([]baseRedis.XPendingExt{
+		{Id: "1-0", Consumer: "consumer-0", Idle: 2 * time.Minute},
+	}, nil).Once()
+
+	redisMock.On("XClaim", &baseRedis.XClaimArgs{
+		Stream:   redisStreamTestKey,
+		Group:    "consumer-app",
+		Consumer: "consumer-1",
+		MinIdle:  time.Minute,
+		Messages: []string{"1-0"},
+	}).Return([]baseRedis.XMessage{
+		{ID: "1-0", Values: map[string]interface{}{"message": `{"attributes":{},"body":"claimed"}`}},
+	}, nil).Once()
+
+	readArgs := &baseRedis.XReadGroupArgs{
+		Group:    "consumer-app",
+		Consumer: "consumer-1",
+		Streams:  []string{redisStreamTestKey, ">"},
+		Count:    2,
+		Block:    time.Second,
+	}
+
+	redisMock.On("XReadGroup", readArgs).Return([]baseRedis.XStream{
+		{
+			Stream: redisStreamTestKey,
+			Messages: []baseRedis.XMessage{
+				{ID: "3-0", Values: map[string]interface{}{"message": "invalid"}},
+				{ID: "4-0", Values: map[string]interface{}{"message": `{"attributes":{},"body":"read"}`}},
+			},
+		},
+	}, nil).Once()
+	redisMock.On("XReadGroup", readArgs).Return(nil, redis.Nil)
+
+	redisMock.On("XAck", redisStreamTestKey, "consumer-app", "3-0").Return(int64(1), nil).Once()
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run(context.Background())
+	}()
+
+	claimed := <-input.Data()
+	read := <-input.Data()
+	input.Stop()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, "claimed", claimed.Body)
+	assert.Equal(t, "1-0", claimed.Attributes[stream.AttributeRedisStreamId])
+	assert.Equal(t, "read", read.Body)
+	assert.Equal(t, "4-0", read.Attributes[stream.AttributeRedisStreamId])
+	redisMock.AssertExpectations(t)
+}
+
+func TestRedisStreamInput_AckBatch(t *testing.T) {
+	redisMock := new(redisMocks.Client)
+	input := getRedisStreamInput(redisMock)
+
+	redisMock.On("XAck", redisStreamTestKey, "consumer-app", "1-0", "2-0").Return(int64(2), nil).Once()
+
+	err := input.AckBatch([]*stream.Message{
+		stream.NewMessage("foo", map[string]interface{}{stream.AttributeRedisStreamId: "1-0"}),
+		stream.NewMessage("bar", map[string]interface{}{stream.AttributeRedisStreamId: "2-0"}),
+	})
+
+	assert.NoError(t, err)
+	redisMock.AssertExpectations(t)
+
+	err = input.Ack(stream.NewMessage("baz"))
+	assert.Error(t, err, "a message without a redis stream id can't be acknowledged")
+}
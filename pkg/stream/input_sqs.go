@@ -9,28 +9,35 @@ import (
 	"github.com/applike/gosoline/pkg/exec"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	awsSqs "github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/hashicorp/go-multierror"
 )
 
 type SqsInputSettings struct {
 	cfg.AppId
-	QueueId             string               `cfg:"queue_id"`
-	MaxNumberOfMessages int64                `cfg:"max_number_of_messages" default:"10" validate:"min=1,max=10"`
-	WaitTime            int64                `cfg:"wait_time"`
-	VisibilityTimeout   int                  `cfg:"visibility_timeout"`
-	RunnerCount         int                  `cfg:"runner_count"`
-	Fifo                sqs.FifoSettings     `cfg:"fifo"`
-	RedrivePolicy       sqs.RedrivePolicy    `cfg:"redrive_policy"`
-	Client              cloud.ClientSettings `cfg:"client"`
-	Backoff             exec.BackoffSettings `cfg:"backoff"`
-	Unmarshaller        string               `cfg:"unmarshaller" default:"msg"`
+	QueueId             string                    `cfg:"queue_id"`
+	MaxNumberOfMessages int64                     `cfg:"max_number_of_messages" default:"10" validate:"min=1,max=10"`
+	WaitTime            int64                     `cfg:"wait_time"`
+	VisibilityTimeout   int                       `cfg:"visibility_timeout"`
+	RunnerCount         int                       `cfg:"runner_count"`
+	Fifo                sqs.FifoSettings          `cfg:"fifo"`
+	RedrivePolicy       sqs.RedrivePolicy         `cfg:"redrive_policy"`
+	Client              cloud.ClientSettings      `cfg:"client"`
+	Backoff             exec.BackoffSettings      `cfg:"backoff"`
+	Unmarshaller        string                    `cfg:"unmarshaller" default:"msg"`
+	Heartbeat           SqsInputHeartbeatSettings `cfg:"heartbeat"`
+	Nack                SqsInputNackSettings      `cfg:"nack"`
 }
 
 type sqsInput struct {
+	inputPause
+
 	logger      mon.Logger
 	queue       sqs.Queue
 	settings    SqsInputSettings
 	unmarshaler UnmarshallerFunc
+	visibility  sqsVisibility
 
 	cfn     coffin.Coffin
 	channel chan *Message
@@ -64,6 +71,10 @@ func NewSqsInputWithInterfaces(logger mon.Logger, queue sqs.Queue, unmarshaller
 		s.RunnerCount = 1
 	}
 
+	if err := validateSqsInputHeartbeat(&s); err != nil {
+		logger.Fatal(err, "invalid heartbeat settings of the sqs input")
+	}
+
 	return &sqsInput{
 		logger:      logger,
 		queue:       queue,
@@ -84,6 +95,13 @@ func (i *sqsInput) Run(ctx context.Context) error {
 
 	i.logger.Infof("starting sqs input with %d runners", i.settings.RunnerCount)
 
+	if i.settings.Heartbeat.Enabled {
+		heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+		defer stopHeartbeat()
+
+		go i.runHeartbeat(heartbeatCtx)
+	}
+
 	for j := 0; j < i.settings.RunnerCount; j++ {
 		i.cfn.Gof(func() error {
 			return i.runLoop(ctx)
@@ -100,6 +118,8 @@ func (i *sqsInput) runLoop(ctx context.Context) error {
 	defer i.logger.Info("leaving sqs input runner")
 
 	for {
+		i.waitWhilePaused(ctx)
+
 		if i.stopped {
 			return nil
 		}
@@ -126,6 +146,14 @@ func (i *sqsInput) runLoop(ctx context.Context) error {
 			msg.Attributes[AttributeSqsMessageId] = *sqsMessage.MessageId
 			msg.Attributes[AttributeSqsReceiptHandle] = *sqsMessage.ReceiptHandle
 
+			if receiveCount, ok := sqsMessage.Attributes[awsSqs.MessageSystemAttributeNameApproximateReceiveCount]; ok {
+				msg.Attributes[AttributeSqsReceiveCount] = aws.StringValue(receiveCount)
+			}
+
+			if i.settings.Heartbeat.Enabled {
+				i.visibility.track(*sqsMessage.ReceiptHandle)
+			}
+
 			i.channel <- msg
 		}
 	}
@@ -133,26 +161,19 @@ func (i *sqsInput) runLoop(ctx context.Context) error {
 
 func (i *sqsInput) Stop() {
 	i.stopped = true
+	i.Resume()
 }
 
 func (i *sqsInput) Ack(msg *Message) error {
-	var ok bool
-	var receiptHandleInterface interface{}
-	var receiptHandleString string
+	receiptHandle, err := sqsReceiptHandle(msg)
 
-	if receiptHandleInterface, ok = msg.Attributes[AttributeSqsReceiptHandle]; !ok {
-		return fmt.Errorf("the message has no attribute %s", AttributeSqsReceiptHandle)
+	if err != nil {
+		return err
 	}
 
-	if receiptHandleString, ok = receiptHandleInterface.(string); !ok {
-		return fmt.Errorf("the attribute %s of the message should be string but instead is %T", AttributeSqsReceiptHandle, receiptHandleInterface)
-	}
+	i.visibility.untrack(receiptHandle)
 
-	if receiptHandleString == "" {
-		return fmt.Errorf("the attribute %s of the message should not be empty", AttributeSqsReceiptHandle)
-	}
-
-	return i.queue.DeleteMessage(receiptHandleString)
+	return i.queue.DeleteMessage(receiptHandle)
 }
 
 func (i *sqsInput) AckBatch(msgs []*Message) error {
@@ -160,35 +181,23 @@ func (i *sqsInput) AckBatch(msgs []*Message) error {
 	multiError := new(multierror.Error)
 
 	for _, msg := range msgs {
-		receiptHandleInterface, ok := msg.Attributes[AttributeSqsReceiptHandle]
-
-		if !ok {
-			multiError = multierror.Append(multiError, fmt.Errorf("the message has no attribute %s", AttributeSqsReceiptHandle))
-
-			continue
-		}
-
-		receiptHandleString, ok := receiptHandleInterface.(string)
+		receiptHandle, err := sqsReceiptHandle(msg)
 
-		if !ok {
-			multiError = multierror.Append(multiError, fmt.Errorf("the attribute %s of the message should be string but instead is %T", AttributeSqsReceiptHandle, receiptHandleInterface))
-
-			continue
-		}
-
-		if receiptHandleString == "" {
-			multiError = multierror.Append(multiError, fmt.Errorf("the attribute %s of the message must not be empty", AttributeSqsReceiptHandle))
+		if err != nil {
+			multiError = multierror.Append(multiError, err)
 
 			continue
 		}
 
-		receiptHandles = append(receiptHandles, receiptHandleString)
+		receiptHandles = append(receiptHandles, receiptHandle)
 	}
 
 	if len(receiptHandles) == 0 {
 		return multiError.ErrorOrNil()
 	}
 
+	i.visibility.untrack(receiptHandles...)
+
 	if err := i.queue.DeleteMessageBatch(receiptHandles); err != nil {
 		multiError = multierror.Append(multiError)
 	}
@@ -196,6 +205,26 @@ func (i *sqsInput) AckBatch(msgs []*Message) error {
 	return multiError.ErrorOrNil()
 }
 
+func sqsReceiptHandle(msg *Message) (string, error) {
+	receiptHandleInterface, ok := msg.Attributes[AttributeSqsReceiptHandle]
+
+	if !ok {
+		return "", fmt.Errorf("the message has no attribute %s", AttributeSqsReceiptHandle)
+	}
+
+	receiptHandle, ok := receiptHandleInterface.(string)
+
+	if !ok {
+		return "", fmt.Errorf("the attribute %s of the message should be string but instead is %T", AttributeSqsReceiptHandle, receiptHandleInterface)
+	}
+
+	if receiptHandle == "" {
+		return "", fmt.Errorf("the attribute %s of the message should not be empty", AttributeSqsReceiptHandle)
+	}
+
+	return receiptHandle, nil
+}
+
 func (i *sqsInput) SetUnmarshaler(unmarshaler UnmarshallerFunc) {
 	i.unmarshaler = unmarshaler
 }
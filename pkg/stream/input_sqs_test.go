@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func TestSqsInput_Run(t *testing.T) {
@@ -102,3 +103,132 @@ func TestSqsInput_Run_Failure(t *testing.T) {
 
 	<-waitRunDone
 }
+
+func TestSqsInput_Nack(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("ChangeMessageVisibility", "handle", int64(2)).Return(nil).Once()
+	queue.On("ChangeMessageVisibility", "handle", int64(8)).Return(nil).Once()
+	queue.On("ChangeMessageVisibility", "handle", int64(10)).Return(nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, stream.MessageUnmarshaller, stream.SqsInputSettings{
+		Nack: stream.SqsInputNackSettings{
+			Enabled:      true,
+			InitialDelay: 2 * time.Second,
+			MaxDelay:     10 * time.Second,
+		},
+	})
+
+	for _, receiveCount := range []string{"1", "3", "5"} {
+		err := input.Nack(&stream.Message{
+			Attributes: map[string]interface{}{
+				stream.AttributeSqsReceiptHandle: "handle",
+				stream.AttributeSqsReceiveCount:  receiveCount,
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	err := input.Nack(&stream.Message{})
+	assert.Error(t, err, "a message without receipt handle can't be nacked")
+
+	queue.AssertExpectations(t)
+}
+
+func TestSqsInput_Heartbeat(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	received := false
+	stopped := make(chan struct{})
+	extended := make(chan []string)
+
+	queue := new(sqsMocks.Queue)
+	queue.On("Receive", mock.Anything, int64(1), int64(3)).Return(func(_ context.Context, _ int64, _ int64) []*sqs.Message {
+		if received {
+			<-stopped
+			return []*sqs.Message{}
+		}
+
+		received = true
+
+		return []*sqs.Message{
+			{
+				Body:          aws.String(`{"body": "foobar"}`),
+				MessageId:     aws.String("id"),
+				ReceiptHandle: aws.String("handle"),
+				Attributes: map[string]*string{
+					sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("1"),
+				},
+			},
+		}
+	}, nil)
+	queue.On("ChangeMessageVisibilityBatch", mock.Anything, int64(30)).Run(func(args mock.Arguments) {
+		select {
+		case extended <- args.Get(0).([]string):
+		default:
+		}
+	}).Return(nil)
+	queue.On("DeleteMessage", "handle").Return(nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, stream.MessageUnmarshaller, stream.SqsInputSettings{
+		MaxNumberOfMessages: 1,
+		WaitTime:            3,
+		VisibilityTimeout:   30,
+		Heartbeat: stream.SqsInputHeartbeatSettings{
+			Enabled:     true,
+			Interval:    time.Millisecond,
+			MaxDuration: time.Minute,
+		},
+	})
+
+	runDone := make(chan error)
+
+	go func() {
+		runDone <- input.Run(context.Background())
+	}()
+
+	msg := <-input.Data()
+	assert.Equal(t, "1", msg.Attributes[stream.AttributeSqsReceiveCount])
+	assert.Equal(t, []string{"handle"}, <-extended, "the visibility of the message in progress should be extended")
+
+	err := input.Ack(msg)
+	assert.NoError(t, err)
+
+	input.Stop()
+	close(stopped)
+
+	assert.NoError(t, <-runDone)
+	queue.AssertExpectations(t)
+}
+
+func TestSqsInput_HeartbeatValidation(t *testing.T) {
+	for name, interval := range map[string]time.Duration{
+		"zero":        0,
+		"not shorter": 30 * time.Second,
+	} {
+		logger := new(monMocks.Logger)
+		logger.On("Fatal", mock.AnythingOfType("*errors.errorString"), "invalid heartbeat settings of the sqs input").Once()
+
+		stream.NewSqsInputWithInterfaces(logger, new(sqsMocks.Queue), stream.MessageUnmarshaller, stream.SqsInputSettings{
+			VisibilityTimeout: 30,
+			Heartbeat: stream.SqsInputHeartbeatSettings{
+				Enabled:  true,
+				Interval: interval,
+			},
+		})
+
+		assert.True(t, logger.AssertExpectations(t), "the interval %s should be rejected", name)
+	}
+
+	logger := new(monMocks.Logger)
+	stream.NewSqsInputWithInterfaces(logger, new(sqsMocks.Queue), stream.MessageUnmarshaller, stream.SqsInputSettings{
+		VisibilityTimeout: 30,
+		Heartbeat: stream.SqsInputHeartbeatSettings{
+			Enabled:  true,
+			Interval: 10 * time.Second,
+		},
+	})
+
+	logger.AssertExpectations(t)
+}
@@ -0,0 +1,161 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cast"
+	"math"
+	"sync"
+	"time"
+)
+
+const sqsMaxVisibilityTimeout = 12 * time.Hour
+
+// SqsInputHeartbeatSettings enable the extension of the visibility timeout of the received messages. Every interval,
+// the visibility of all messages which are neither acknowledged nor nacked yet is set to the visibility timeout again,
+// so messages consumed for longer than the visibility timeout don't reappear in the queue.
+type SqsInputHeartbeatSettings struct {
+	Enabled bool `cfg:"enabled" default:"false"`
+	// Interval has to be shorter than the visibility timeout
+	Interval time.Duration `cfg:"interval" default:"10s" validate:"min=1000000"`
+	// MaxDuration stops the extension of a message which is in progress for longer
+	MaxDuration time.Duration `cfg:"max_duration" default:"1h"`
+}
+
+// SqsInputNackSettings enable the backoff of nacked messages. The visibility of a message which was not
+// acknowledged is set to InitialDelay * 2^(receive count - 1), capped at MaxDelay, instead of waiting for
+// the full visibility timeout.
+type SqsInputNackSettings struct {
+	Enabled      bool          `cfg:"enabled" default:"false"`
+	InitialDelay time.Duration `cfg:"initial_delay" default:"1s"`
+	MaxDelay     time.Duration `cfg:"max_delay" default:"5m"`
+}
+
+// validateSqsInputHeartbeat checks that the visibility of the messages in progress is extended before it expires.
+func validateSqsInputHeartbeat(settings *SqsInputSettings) error {
+	if !settings.Heartbeat.Enabled {
+		return nil
+	}
+
+	if settings.Heartbeat.Interval <= 0 {
+		return fmt.Errorf("the heartbeat interval has to be greater than 0, but is %s", settings.Heartbeat.Interval)
+	}
+
+	visibilityTimeout := time.Duration(settings.VisibilityTimeout) * time.Second
+
+	if settings.Heartbeat.Interval >= visibilityTimeout {
+		return fmt.Errorf("the heartbeat interval %s has to be shorter than the visibility timeout %s", settings.Heartbeat.Interval, visibilityTimeout)
+	}
+
+	return nil
+}
+
+// sqsVisibility keeps track of the messages in progress to extend their visibility.
+type sqsVisibility struct {
+	lck      sync.Mutex
+	received map[string]time.Time
+}
+
+func (v *sqsVisibility) track(receiptHandle string) {
+	v.lck.Lock()
+	defer v.lck.Unlock()
+
+	if v.received == nil {
+		v.received = make(map[string]time.Time)
+	}
+
+	v.received[receiptHandle] = time.Now()
+}
+
+func (v *sqsVisibility) untrack(receiptHandles ...string) {
+	v.lck.Lock()
+	defer v.lck.Unlock()
+
+	for _, receiptHandle := range receiptHandles {
+		delete(v.received, receiptHandle)
+	}
+}
+
+// due returns the messages whose visibility should be extended and stops tracking the messages which exceeded maxDuration.
+func (v *sqsVisibility) due(maxDuration time.Duration) []string {
+	v.lck.Lock()
+	defer v.lck.Unlock()
+
+	receiptHandles := make([]string, 0, len(v.received))
+
+	for receiptHandle, received := range v.received {
+		if time.Since(received) > maxDuration {
+			delete(v.received, receiptHandle)
+			continue
+		}
+
+		receiptHandles = append(receiptHandles, receiptHandle)
+	}
+
+	return receiptHandles
+}
+
+func (i *sqsInput) runHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(i.settings.Heartbeat.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			receiptHandles := i.visibility.due(i.settings.Heartbeat.MaxDuration)
+
+			if len(receiptHandles) == 0 {
+				continue
+			}
+
+			if err := i.queue.ChangeMessageVisibilityBatch(receiptHandles, int64(i.settings.VisibilityTimeout)); err != nil {
+				i.logger.Error(err, "could not extend the visibility of the messages in progress")
+			}
+		}
+	}
+}
+
+// Nack makes a message which was not acknowledged visible again after a backoff delay if the nack backoff is enabled.
+// Otherwise, the message reappears after the visibility timeout.
+func (i *sqsInput) Nack(msg *Message) error {
+	receiptHandle, err := sqsReceiptHandle(msg)
+
+	if err != nil {
+		return err
+	}
+
+	i.visibility.untrack(receiptHandle)
+
+	if !i.settings.Nack.Enabled {
+		return nil
+	}
+
+	delay := i.nackDelay(msg)
+
+	return i.queue.ChangeMessageVisibility(receiptHandle, int64(delay/time.Second))
+}
+
+func (i *sqsInput) nackDelay(msg *Message) time.Duration {
+	receiveCount := cast.ToInt(msg.Attributes[AttributeSqsReceiveCount])
+
+	if receiveCount < 1 {
+		receiveCount = 1
+	}
+
+	maxDelay := i.settings.Nack.MaxDelay
+
+	if maxDelay > sqsMaxVisibilityTimeout {
+		maxDelay = sqsMaxVisibilityTimeout
+	}
+
+	delay := float64(i.settings.Nack.InitialDelay) * math.Pow(2, float64(receiveCount-1))
+
+	if delay > float64(maxDelay) {
+		return maxDelay
+	}
+
+	return time.Duration(delay)
+}
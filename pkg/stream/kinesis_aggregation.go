@@ -0,0 +1,409 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+// The KPL aggregation format is the magic number followed by a protobuf encoded
+// AggregatedRecord and the md5 checksum of the protobuf message:
+//
+//	message AggregatedRecord {
+//		repeated string partition_key_table     = 1;
+//		repeated string explicit_hash_key_table = 2;
+//		repeated Record records                 = 3;
+//	}
+//
+//	message Record {
+//		required uint64 partition_key_index     = 1;
+//		optional uint64 explicit_hash_key_index = 2;
+//		required bytes  data                    = 3;
+//		repeated Tag    tags                    = 4;
+//	}
+//
+// See https://github.com/awslabs/amazon-kinesis-producer/blob/master/aggregation-format.md
+var kplMagicNumber = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+const (
+	kplWireVarint  = 0
+	kplWireFixed64 = 1
+	kplWireBytes   = 2
+	kplWireFixed32 = 5
+
+	kplFieldPartitionKeyTable    = 1
+	kplFieldExplicitHashKeyTable = 2
+	kplFieldRecords              = 3
+
+	kplFieldRecordPartitionKeyIndex    = 1
+	kplFieldRecordExplicitHashKeyIndex = 2
+	kplFieldRecordData                 = 3
+)
+
+type KinesisAggregationSettings struct {
+	Enabled    bool `cfg:"enabled" default:"false"`
+	MaxRecords int  `cfg:"max_records" default:"1000" validate:"min=1"`
+	MaxSize    int  `cfg:"max_size" default:"51200" validate:"min=1,max=1048576"`
+}
+
+type kinesisAggregate struct {
+	partitionKeys    []string
+	partitionIndex   map[string]uint64
+	explicitHashKeys []string
+	explicitIndex    map[string]uint64
+	records          []kplRecord
+	size             int
+}
+
+type kplRecord struct {
+	partitionKeyIndex    uint64
+	explicitHashKeyIndex *uint64
+	data                 []byte
+}
+
+func newKinesisAggregate() *kinesisAggregate {
+	return &kinesisAggregate{
+		partitionKeys:    make([]string, 0),
+		partitionIndex:   make(map[string]uint64),
+		explicitHashKeys: make([]string, 0),
+		explicitIndex:    make(map[string]uint64),
+		records:          make([]kplRecord, 0),
+		size:             len(kplMagicNumber) + md5.Size,
+	}
+}
+
+// sizeWith returns the size of the encoded aggregate if the entry would be added.
+func (a *kinesisAggregate) sizeWith(entry *kinesis.PutRecordsRequestEntry) int {
+	size := a.size
+	partitionKeyIndex := uint64(len(a.partitionKeys))
+	explicitHashKeyIndex := uint64(len(a.explicitHashKeys))
+
+	if index, ok := a.partitionIndex[*entry.PartitionKey]; ok {
+		partitionKeyIndex = index
+	} else {
+		size += kplBytesFieldSize(kplFieldPartitionKeyTable, len(*entry.PartitionKey))
+	}
+
+	var explicitHashKeyIndexPtr *uint64
+
+	if entry.ExplicitHashKey != nil {
+		if index, ok := a.explicitIndex[*entry.ExplicitHashKey]; ok {
+			explicitHashKeyIndex = index
+		} else {
+			size += kplBytesFieldSize(kplFieldExplicitHashKeyTable, len(*entry.ExplicitHashKey))
+		}
+
+		explicitHashKeyIndexPtr = &explicitHashKeyIndex
+	}
+
+	recordSize := kplRecordSize(partitionKeyIndex, explicitHashKeyIndexPtr, len(entry.Data))
+
+	return size + kplBytesFieldSize(kplFieldRecords, recordSize)
+}
+
+func (a *kinesisAggregate) add(entry *kinesis.PutRecordsRequestEntry) {
+	a.size = a.sizeWith(entry)
+
+	record := kplRecord{
+		data: entry.Data,
+	}
+
+	if index, ok := a.partitionIndex[*entry.PartitionKey]; ok {
+		record.partitionKeyIndex = index
+	} else {
+		record.partitionKeyIndex = uint64(len(a.partitionKeys))
+		a.partitionIndex[*entry.PartitionKey] = record.partitionKeyIndex
+		a.partitionKeys = append(a.partitionKeys, *entry.PartitionKey)
+	}
+
+	if entry.ExplicitHashKey != nil {
+		index, ok := a.explicitIndex[*entry.ExplicitHashKey]
+
+		if !ok {
+			index = uint64(len(a.explicitHashKeys))
+			a.explicitIndex[*entry.ExplicitHashKey] = index
+			a.explicitHashKeys = append(a.explicitHashKeys, *entry.ExplicitHashKey)
+		}
+
+		record.explicitHashKeyIndex = &index
+	}
+
+	a.records = append(a.records, record)
+}
+
+// entry encodes the aggregate into a single record. It uses the partition key and explicit
+// hash key of the first record, so the aggregate is written to the shard of the first record.
+func (a *kinesisAggregate) entry() *kinesis.PutRecordsRequestEntry {
+	buf := make([]byte, 0, a.size)
+
+	for _, key := range a.partitionKeys {
+		buf = kplAppendBytesField(buf, kplFieldPartitionKeyTable, []byte(key))
+	}
+
+	for _, key := range a.explicitHashKeys {
+		buf = kplAppendBytesField(buf, kplFieldExplicitHashKeyTable, []byte(key))
+	}
+
+	for _, record := range a.records {
+		encoded := make([]byte, 0, kplRecordSize(record.partitionKeyIndex, record.explicitHashKeyIndex, len(record.data)))
+		encoded = kplAppendVarintField(encoded, kplFieldRecordPartitionKeyIndex, record.partitionKeyIndex)
+
+		if record.explicitHashKeyIndex != nil {
+			encoded = kplAppendVarintField(encoded, kplFieldRecordExplicitHashKeyIndex, *record.explicitHashKeyIndex)
+		}
+
+		encoded = kplAppendBytesField(encoded, kplFieldRecordData, record.data)
+		buf = kplAppendBytesField(buf, kplFieldRecords, encoded)
+	}
+
+	checksum := md5.Sum(buf)
+
+	data := make([]byte, 0, len(kplMagicNumber)+len(buf)+len(checksum))
+	data = append(data, kplMagicNumber...)
+	data = append(data, buf...)
+	data = append(data, checksum[:]...)
+
+	entry := &kinesis.PutRecordsRequestEntry{
+		Data:         data,
+		PartitionKey: aws.String(a.partitionKeys[a.records[0].partitionKeyIndex]),
+	}
+
+	if index := a.records[0].explicitHashKeyIndex; index != nil {
+		entry.ExplicitHashKey = aws.String(a.explicitHashKeys[*index])
+	}
+
+	return entry
+}
+
+type kinesisAggregator struct {
+	settings KinesisAggregationSettings
+	// records with different keys can only be aggregated if the keys don't matter for the
+	// ordering of the records, as an aggregate ends up on the shard of its first record
+	groupByKey bool
+}
+
+func newKinesisAggregator(settings KinesisAggregationSettings, groupByKey bool) *kinesisAggregator {
+	return &kinesisAggregator{
+		settings:   settings,
+		groupByKey: groupByKey,
+	}
+}
+
+// Aggregate packs the records into as few aggregated records as possible while keeping
+// the order of records with the same keys.
+func (a *kinesisAggregator) Aggregate(entries []*kinesis.PutRecordsRequestEntry) []*kinesis.PutRecordsRequestEntry {
+	result := make([]*kinesis.PutRecordsRequestEntry, 0)
+	aggregates := make(map[string]*kinesisAggregate)
+	order := make([]string, 0)
+
+	for _, entry := range entries {
+		group := ""
+
+		if a.groupByKey {
+			group = *entry.PartitionKey
+
+			if entry.ExplicitHashKey != nil {
+				group = fmt.Sprintf("%s/%s", group, *entry.ExplicitHashKey)
+			}
+		}
+
+		aggregate, ok := aggregates[group]
+
+		if ok && (len(aggregate.records) >= a.settings.MaxRecords || aggregate.sizeWith(entry) > a.settings.MaxSize) {
+			result = append(result, aggregate.entry())
+			ok = false
+		}
+
+		if !ok {
+			aggregate = newKinesisAggregate()
+			aggregates[group] = aggregate
+			order = append(order, group)
+		}
+
+		aggregate.add(entry)
+	}
+
+	written := make(map[*kinesisAggregate]bool)
+
+	for _, group := range order {
+		aggregate := aggregates[group]
+
+		if written[aggregate] {
+			continue
+		}
+
+		written[aggregate] = true
+		result = append(result, aggregate.entry())
+	}
+
+	return result
+}
+
+// DeaggregateKinesisRecord returns the data of all records contained in a record using
+// the KPL aggregation format. Records not using the format are returned as they are.
+func DeaggregateKinesisRecord(data []byte) ([][]byte, error) {
+	if !IsKinesisAggregatedRecord(data) {
+		return [][]byte{data}, nil
+	}
+
+	buf := data[len(kplMagicNumber) : len(data)-md5.Size]
+	records := make([][]byte, 0)
+
+	err := kplDecodeFields(buf, func(field uint64, wireType uint64, value []byte) error {
+		if field != kplFieldRecords {
+			return nil
+		}
+
+		if wireType != kplWireBytes {
+			return fmt.Errorf("invalid wire type %d of aggregated record", wireType)
+		}
+
+		var recordData []byte
+
+		err := kplDecodeFields(value, func(field uint64, wireType uint64, value []byte) error {
+			if field == kplFieldRecordData && wireType == kplWireBytes {
+				recordData = value
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if recordData == nil {
+			return fmt.Errorf("aggregated record %d has no data", len(records))
+		}
+
+		records = append(records, recordData)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("can not decode aggregated kinesis record: %w", err)
+	}
+
+	return records, nil
+}
+
+// IsKinesisAggregatedRecord checks the magic number and the checksum of the data. Like the
+// KCL does, records with an invalid checksum are not treated as aggregated records.
+func IsKinesisAggregatedRecord(data []byte) bool {
+	if len(data) < len(kplMagicNumber)+md5.Size || !bytes.HasPrefix(data, kplMagicNumber) {
+		return false
+	}
+
+	buf := data[len(kplMagicNumber) : len(data)-md5.Size]
+	checksum := md5.Sum(buf)
+
+	return bytes.Equal(checksum[:], data[len(data)-md5.Size:])
+}
+
+func kplDecodeFields(buf []byte, handle func(field uint64, wireType uint64, value []byte) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+
+		buf = buf[n:]
+		field, wireType := key>>3, key&0x7
+
+		var value []byte
+
+		switch wireType {
+		case kplWireVarint:
+			if _, n = binary.Uvarint(buf); n <= 0 {
+				return fmt.Errorf("invalid varint of field %d", field)
+			}
+
+			buf = buf[n:]
+
+		case kplWireFixed64, kplWireFixed32:
+			size := 8
+
+			if wireType == kplWireFixed32 {
+				size = 4
+			}
+
+			if len(buf) < size {
+				return fmt.Errorf("unexpected end of field %d", field)
+			}
+
+			value, buf = buf[:size], buf[size:]
+
+		case kplWireBytes:
+			length, n := binary.Uvarint(buf)
+
+			if n <= 0 || uint64(len(buf)-n) < length {
+				return fmt.Errorf("invalid length of field %d", field)
+			}
+
+			value, buf = buf[n:n+int(length)], buf[n+int(length):]
+
+		default:
+			return fmt.Errorf("unsupported wire type %d of field %d", wireType, field)
+		}
+
+		if err := handle(field, wireType, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func kplRecordSize(partitionKeyIndex uint64, explicitHashKeyIndex *uint64, dataLen int) int {
+	size := kplVarintFieldSize(kplFieldRecordPartitionKeyIndex, partitionKeyIndex)
+
+	if explicitHashKeyIndex != nil {
+		size += kplVarintFieldSize(kplFieldRecordExplicitHashKeyIndex, *explicitHashKeyIndex)
+	}
+
+	return size + kplBytesFieldSize(kplFieldRecordData, dataLen)
+}
+
+func kplVarintFieldSize(field uint64, value uint64) int {
+	return kplVarintSize(field<<3|kplWireVarint) + kplVarintSize(value)
+}
+
+func kplBytesFieldSize(field uint64, length int) int {
+	return kplVarintSize(field<<3|kplWireBytes) + kplVarintSize(uint64(length)) + length
+}
+
+func kplVarintSize(value uint64) int {
+	size := 1
+
+	for value >= 0x80 {
+		value >>= 7
+		size++
+	}
+
+	return size
+}
+
+func kplAppendVarintField(buf []byte, field uint64, value uint64) []byte {
+	buf = kplAppendVarint(buf, field<<3|kplWireVarint)
+
+	return kplAppendVarint(buf, value)
+}
+
+func kplAppendBytesField(buf []byte, field uint64, value []byte) []byte {
+	buf = kplAppendVarint(buf, field<<3|kplWireBytes)
+	buf = kplAppendVarint(buf, uint64(len(value)))
+
+	return append(buf, value...)
+}
+
+func kplAppendVarint(buf []byte, value uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], value)
+
+	return append(buf, tmp[:n]...)
+}
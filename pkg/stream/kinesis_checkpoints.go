@@ -0,0 +1,159 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/ddb"
+	"github.com/applike/gosoline/pkg/exec"
+	"github.com/applike/gosoline/pkg/mdl"
+	"github.com/applike/gosoline/pkg/mon"
+	"time"
+)
+
+type KinesisShardCheckpoint struct {
+	// the application and the stream the checkpoint belongs to
+	Namespace string `json:"namespace" ddb:"key=hash"`
+	ShardId   string `json:"shardId" ddb:"key=range"`
+	// the consumer currently holding the lease of the shard
+	Owner          string `json:"owner"`
+	LeaseExpiresAt int64  `json:"leaseExpiresAt"`
+	// the sequence number of the last record handed to the consumer
+	SequenceNumber string `json:"sequenceNumber"`
+	// a finished shard has been closed by a reshard and read until its end
+	Finished bool `json:"finished"`
+}
+
+// IsLeased reports whether the shard is owned by a consumer with an active lease.
+func (c *KinesisShardCheckpoint) IsLeased(now time.Time) bool {
+	return c.Owner != "" && c.LeaseExpiresAt > now.Unix()
+}
+
+//go:generate mockery -name KinesisCheckpointStore
+type KinesisCheckpointStore interface {
+	List(ctx context.Context) ([]*KinesisShardCheckpoint, error)
+	Acquire(ctx context.Context, shardId string, owner string, leaseDuration time.Duration) (*KinesisShardCheckpoint, bool, error)
+	Renew(ctx context.Context, shardId string, owner string, sequenceNumber string, leaseDuration time.Duration) (bool, error)
+	Release(ctx context.Context, shardId string, owner string, sequenceNumber string, finished bool) error
+}
+
+type KinesisCheckpointStoreSettings struct {
+	StreamName      string
+	ApplicationName string
+	Backoff         exec.BackoffSettings
+}
+
+type ddbKinesisCheckpointStore struct {
+	repo      ddb.Repository
+	clock     clock.Clock
+	namespace string
+}
+
+func NewDdbKinesisCheckpointStore(config cfg.Config, logger mon.Logger, settings *KinesisCheckpointStoreSettings) KinesisCheckpointStore {
+	repo := ddb.NewRepository(config, logger, &ddb.Settings{
+		ModelId: mdl.ModelId{
+			Name: "kinesis-checkpoints",
+		},
+		Backoff: settings.Backoff,
+		Main: ddb.MainSettings{
+			Model:              &KinesisShardCheckpoint{},
+			ReadCapacityUnits:  1,
+			WriteCapacityUnits: 1,
+		},
+	})
+
+	return NewDdbKinesisCheckpointStoreWithInterfaces(repo, clock.NewRealClock(), settings)
+}
+
+func NewDdbKinesisCheckpointStoreWithInterfaces(repo ddb.Repository, clock clock.Clock, settings *KinesisCheckpointStoreSettings) KinesisCheckpointStore {
+	return &ddbKinesisCheckpointStore{
+		repo:      repo,
+		clock:     clock,
+		namespace: fmt.Sprintf("%s-%s", settings.ApplicationName, settings.StreamName),
+	}
+}
+
+func (s *ddbKinesisCheckpointStore) List(ctx context.Context) ([]*KinesisShardCheckpoint, error) {
+	checkpoints := make([]*KinesisShardCheckpoint, 0)
+	qb := s.repo.QueryBuilder().WithHash(s.namespace)
+
+	if _, err := s.repo.Query(ctx, qb, &checkpoints); err != nil {
+		return nil, fmt.Errorf("can not list checkpoints of %s: %w", s.namespace, err)
+	}
+
+	return checkpoints, nil
+}
+
+// Acquire takes the lease of the shard if it has no owner, its lease expired or it is already owned by the owner.
+func (s *ddbKinesisCheckpointStore) Acquire(ctx context.Context, shardId string, owner string, leaseDuration time.Duration) (*KinesisShardCheckpoint, bool, error) {
+	now := s.clock.Now()
+	checkpoint := &KinesisShardCheckpoint{
+		Namespace: s.namespace,
+		ShardId:   shardId,
+	}
+
+	ub := s.repo.UpdateItemBuilder().
+		Set("owner", owner).
+		Set("leaseExpiresAt", now.Add(leaseDuration).Unix()).
+		WithCondition(ddb.AttributeNotExists("shardId").Or(ddb.Lt("leaseExpiresAt", now.Unix())).Or(ddb.Eq("owner", owner))).
+		ReturnAllNew()
+
+	result, err := s.repo.UpdateItem(ctx, ub, checkpoint)
+
+	if err != nil {
+		return nil, false, fmt.Errorf("can not acquire lease of shard %s: %w", shardId, err)
+	}
+
+	if result.ConditionalCheckFailed {
+		return nil, false, nil
+	}
+
+	return checkpoint, true, nil
+}
+
+// Renew extends the lease and stores the sequence number, if there is one. It returns false if the lease was lost.
+func (s *ddbKinesisCheckpointStore) Renew(ctx context.Context, shardId string, owner string, sequenceNumber string, leaseDuration time.Duration) (bool, error) {
+	ub := s.repo.UpdateItemBuilder().
+		Set("leaseExpiresAt", s.clock.Now().Add(leaseDuration).Unix()).
+		WithCondition(ddb.Eq("owner", owner))
+
+	if sequenceNumber != "" {
+		ub = ub.Set("sequenceNumber", sequenceNumber)
+	}
+
+	result, err := s.repo.UpdateItem(ctx, ub, &KinesisShardCheckpoint{
+		Namespace: s.namespace,
+		ShardId:   shardId,
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("can not renew lease of shard %s: %w", shardId, err)
+	}
+
+	return !result.ConditionalCheckFailed, nil
+}
+
+// Release gives up the lease and stores the sequence number, if there is one. A finished shard won't be leased again.
+func (s *ddbKinesisCheckpointStore) Release(ctx context.Context, shardId string, owner string, sequenceNumber string, finished bool) error {
+	ub := s.repo.UpdateItemBuilder().
+		Set("owner", "").
+		Set("leaseExpiresAt", 0).
+		Set("finished", finished).
+		WithCondition(ddb.Eq("owner", owner))
+
+	if sequenceNumber != "" {
+		ub = ub.Set("sequenceNumber", sequenceNumber)
+	}
+
+	_, err := s.repo.UpdateItem(ctx, ub, &KinesisShardCheckpoint{
+		Namespace: s.namespace,
+		ShardId:   shardId,
+	})
+
+	if err != nil {
+		return fmt.Errorf("can not release lease of shard %s: %w", shardId, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/kernel"
+	"github.com/applike/gosoline/pkg/mon"
+	"sync"
+	"time"
+)
+
+const (
+	MetricNameLoggerHookDropped = "LoggerHookDropped"
+
+	// every log line written by the logger of the hook carries this field,
+	// so the hook can ignore these lines instead of shipping its own logs recursively
+	loggerHookRecursionField = "stream_logger_hook"
+)
+
+type loggerHookContextKey struct{}
+
+// loggerHookContext marks the context of the writes of the hook. The log lines of any logger the output uses
+// with this context are ignored, even if the logger was not created by the hook, e.g. the logger of a shared client.
+func loggerHookContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loggerHookContextKey{}, true)
+}
+
+func isLoggerHookLine(data *mon.Metadata) bool {
+	if _, ok := data.Fields[loggerHookRecursionField]; ok {
+		return true
+	}
+
+	return data.Context != nil && data.Context.Value(loggerHookContextKey{}) != nil
+}
+
+type OutputLoggerHookSettings struct {
+	Enabled    bool          `cfg:"enabled" default:"false"`
+	Output     string        `cfg:"output" default:"logs"`
+	Level      string        `cfg:"level" default:"info"`
+	BatchSize  int           `cfg:"batch_size" default:"100" validate:"min=1"`
+	BufferSize int           `cfg:"buffer_size" default:"10000" validate:"min=1"`
+	Interval   time.Duration `cfg:"interval" default:"1s" validate:"min=1000000"`
+	RateLimit  int           `cfg:"rate_limit" default:"1000" validate:"min=0"`
+}
+
+type LogEntry struct {
+	Timestamp     time.Time              `json:"timestamp"`
+	Level         string                 `json:"level"`
+	Channel       string                 `json:"channel"`
+	Message       string                 `json:"message"`
+	Error         string                 `json:"error,omitempty"`
+	Fields        map[string]interface{} `json:"fields"`
+	ContextFields map[string]interface{} `json:"context"`
+	Tags          map[string]interface{} `json:"tags"`
+}
+
+// OutputLoggerHook ships log entries to a configurable output. Entries are buffered
+// and written in batches by the module, which has to be added to the kernel. At most
+// RateLimit entries per second are accepted (0 disables the limit), every entry
+// exceeding the rate limit or the buffer size is dropped and counted in a metric.
+type OutputLoggerHook struct {
+	kernel.BackgroundModule
+	kernel.EssentialStage
+
+	logger   mon.Logger
+	clock    clock.Clock
+	metric   mon.MetricWriter
+	output   Output
+	settings *OutputLoggerHookSettings
+	level    int
+
+	lck         sync.Mutex
+	entries     []*LogEntry
+	dropped     int
+	windowStart time.Time
+	windowCount int
+	flush       chan struct{}
+}
+
+func NewOutputLoggerHook(settings *OutputLoggerHookSettings) *OutputLoggerHook {
+	return &OutputLoggerHook{
+		clock:    clock.NewRealClock(),
+		settings: settings,
+		level:    mon.LevelPriority(settings.Level),
+		entries:  make([]*LogEntry, 0, settings.BatchSize),
+		flush:    make(chan struct{}, 1),
+	}
+}
+
+func (h *OutputLoggerHook) Boot(config cfg.Config, logger mon.Logger) error {
+	logger = logger.WithChannel("stream-logger-hook").WithFields(mon.Fields{
+		loggerHookRecursionField: true,
+	})
+
+	defaults := getOutputLoggerHookDefaultMetrics()
+	metric := mon.NewMetricDaemonWriter(defaults...)
+	output := NewConfigurableOutput(config, logger, h.settings.Output)
+
+	return h.BootWithInterfaces(logger, clock.NewRealClock(), metric, output)
+}
+
+func (h *OutputLoggerHook) BootWithInterfaces(logger mon.Logger, clock clock.Clock, metric mon.MetricWriter, output Output) error {
+	h.lck.Lock()
+	defer h.lck.Unlock()
+
+	h.logger = logger
+	h.clock = clock
+	h.metric = metric
+	h.output = output
+
+	return nil
+}
+
+func (h *OutputLoggerHook) Run(ctx context.Context) error {
+	ticker := time.NewTicker(h.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.write(context.Background())
+			return nil
+
+		case <-ticker.C:
+			h.write(ctx)
+
+		case <-h.flush:
+			h.write(ctx)
+		}
+	}
+}
+
+func (h *OutputLoggerHook) Fire(level string, msg string, err error, data *mon.Metadata) error {
+	if isLoggerHookLine(data) {
+		return nil
+	}
+
+	if mon.LevelPriority(level) < h.level {
+		return nil
+	}
+
+	h.lck.Lock()
+	defer h.lck.Unlock()
+
+	now := h.clock.Now()
+
+	if !h.allow(now) || len(h.entries) >= h.settings.BufferSize {
+		h.dropped++
+		return nil
+	}
+
+	entry := &LogEntry{
+		Timestamp:     now,
+		Level:         level,
+		Channel:       data.Channel,
+		Message:       msg,
+		Fields:        copyLogFields(data.Fields),
+		ContextFields: copyLogFields(data.ContextFields),
+		Tags:          copyLogFields(data.Tags),
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	h.entries = append(h.entries, entry)
+
+	if len(h.entries) >= h.settings.BatchSize {
+		select {
+		case h.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// allow implements a simple fixed window rate limit of RateLimit entries per second.
+func (h *OutputLoggerHook) allow(now time.Time) bool {
+	if h.settings.RateLimit <= 0 {
+		return true
+	}
+
+	if now.Sub(h.windowStart) >= time.Second {
+		h.windowStart = now
+		h.windowCount = 0
+	}
+
+	if h.windowCount >= h.settings.RateLimit {
+		return false
+	}
+
+	h.windowCount++
+
+	return true
+}
+
+func (h *OutputLoggerHook) write(ctx context.Context) {
+	h.lck.Lock()
+
+	entries := h.entries
+	dropped := h.dropped
+
+	h.entries = make([]*LogEntry, 0, h.settings.BatchSize)
+	h.dropped = 0
+
+	h.lck.Unlock()
+
+	if dropped > 0 {
+		h.metric.WriteOne(&mon.MetricDatum{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNameLoggerHookDropped,
+			Unit:       mon.UnitCount,
+			Value:      float64(dropped),
+		})
+	}
+
+	for start := 0; start < len(entries); start += h.settings.BatchSize {
+		end := start + h.settings.BatchSize
+
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		if err := h.writeBatch(ctx, entries[start:end]); err != nil {
+			h.logger.Errorf(err, "can not write %d log entries to output %s", end-start, h.settings.Output)
+		}
+	}
+}
+
+func (h *OutputLoggerHook) writeBatch(ctx context.Context, entries []*LogEntry) error {
+	messages := make([]*Message, 0, len(entries))
+
+	for _, entry := range entries {
+		msg, err := MarshalJsonMessage(entry)
+
+		if err != nil {
+			return fmt.Errorf("can not encode log entry: %w", err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return h.output.Write(loggerHookContext(ctx), messages)
+}
+
+func copyLogFields(fields map[string]interface{}) map[string]interface{} {
+	cpy := make(map[string]interface{}, len(fields))
+
+	for k, v := range fields {
+		cpy[k] = v
+	}
+
+	return cpy
+}
+
+func getOutputLoggerHookDefaultMetrics() mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNameLoggerHookDropped,
+			Unit:       mon.UnitCount,
+			Value:      0.0,
+		},
+	}
+}
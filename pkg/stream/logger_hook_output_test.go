@@ -0,0 +1,109 @@
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func getOutputLoggerHook(t *testing.T, settings *stream.OutputLoggerHookSettings) (mon.GosoLog, *stream.OutputLoggerHook, *streamMocks.Output, *monMocks.MetricWriter) {
+	clk := clock.NewFakeClockAt(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	logger := mon.NewLoggerWithInterfaces(clk, &bytes.Buffer{})
+
+	output := new(streamMocks.Output)
+	metric := new(monMocks.MetricWriter)
+
+	hook := stream.NewOutputLoggerHook(settings)
+	err := hook.BootWithInterfaces(logger.WithFields(mon.Fields{"stream_logger_hook": true}), clk, metric, output)
+	assert.NoError(t, err)
+
+	err = logger.Option(mon.WithHook(hook), mon.WithTags(mon.Tags{"application": "test"}))
+	assert.NoError(t, err)
+
+	return logger, hook, output, metric
+}
+
+func TestOutputLoggerHook_Run(t *testing.T) {
+	logger, hook, output, metric := getOutputLoggerHook(t, &stream.OutputLoggerHookSettings{
+		Level:      mon.Info,
+		BatchSize:  2,
+		BufferSize: 10,
+		Interval:   time.Minute,
+		RateLimit:  3,
+	})
+
+	var written []*stream.Message
+	output.On("Write", mock.Anything, mock.AnythingOfType("[]*stream.Message")).Run(func(args mock.Arguments) {
+		written = append(written, args.Get(1).([]*stream.Message)...)
+	}).Return(nil).Twice()
+
+	metric.On("WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == stream.MetricNameLoggerHookDropped && datum.Value == 1.0
+	})).Once()
+
+	logger.Debug("not shipped")
+	logger.WithChannel("http").WithFields(mon.Fields{"path": "/"}).Info("msg1")
+	logger.Warn("msg2")
+	logger.Info("msg3")
+	logger.Info("rate limited")
+	logger.WithFields(mon.Fields{"stream_logger_hook": true}).Info("recursive")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := hook.Run(ctx)
+	assert.NoError(t, err)
+
+	assert.Len(t, written, 3)
+
+	entry := &stream.LogEntry{}
+	err = json.Unmarshal([]byte(written[0].Body), entry)
+	assert.NoError(t, err)
+
+	assert.Equal(t, &stream.LogEntry{
+		Timestamp:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Level:         mon.Info,
+		Channel:       "http",
+		Message:       "msg1",
+		Fields:        map[string]interface{}{"application": "test", "path": "/"},
+		ContextFields: map[string]interface{}{},
+		Tags:          map[string]interface{}{"application": "test"},
+	}, entry)
+
+	output.AssertExpectations(t)
+	metric.AssertExpectations(t)
+}
+
+func TestOutputLoggerHook_RecursionViaContext(t *testing.T) {
+	logger, hook, output, metric := getOutputLoggerHook(t, &stream.OutputLoggerHookSettings{
+		Level:      mon.Info,
+		BatchSize:  10,
+		BufferSize: 10,
+		Interval:   time.Minute,
+	})
+
+	// the output logs with a logger which was not created by the hook, but uses the context of the write
+	output.On("Write", mock.Anything, mock.AnythingOfType("[]*stream.Message")).Run(func(args mock.Arguments) {
+		logger.WithContext(args.Get(0).(context.Context)).Warn("written by the output")
+	}).Return(nil).Once()
+
+	logger.Info("msg1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, hook.Run(ctx))
+	assert.NoError(t, hook.Run(ctx))
+
+	output.AssertExpectations(t)
+	metric.AssertExpectations(t)
+}
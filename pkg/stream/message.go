@@ -2,11 +2,18 @@ package stream
 
 import (
 	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/spf13/cast"
+	"time"
 )
 
 const (
 	AttributeSqsMessageId     = "sqsMessageId"
 	AttributeSqsReceiptHandle = "sqsReceiptHandle"
+	AttributeSqsReceiveCount  = "sqsReceiveCount"
+	// AttributeRedisStreamId is the id of the entry of a redis stream a message was read from
+	AttributeRedisStreamId = "redisStreamId"
+	// AttributeDeliverAt is the unix timestamp in seconds at which the message should be delivered
+	AttributeDeliverAt = "deliverAt"
 )
 
 type Message struct {
@@ -26,6 +33,23 @@ func (m *Message) GetReceiptHandler() interface{} {
 	return nil
 }
 
+// GetDeliverAt returns the time at which the message should be delivered and false if it should be delivered immediately.
+func (m *Message) GetDeliverAt() (time.Time, bool) {
+	deliverAt, ok := m.Attributes[AttributeDeliverAt]
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	seconds, err := cast.ToInt64E(deliverAt)
+
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(seconds, 0), true
+}
+
 func (m *Message) MarshalToString() (string, error) {
 	bytes, err := json.Marshal(*m)
 
@@ -43,3 +67,16 @@ func (m *Message) UnmarshalFromBytes(data []byte) error {
 func (m *Message) UnmarshalFromString(data string) error {
 	return m.UnmarshalFromBytes([]byte(data))
 }
+
+// DeliverAt returns the attributes to delay the delivery of a message until the given time, which are passed
+// to the producer like producer.WriteOne(ctx, model, stream.DeliverAt(at)). Delays of sqs outputs up to 15 minutes are applied by sqs, longer delays and other outputs require a scheduler output.
+func DeliverAt(at time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		AttributeDeliverAt: at.Unix(),
+	}
+}
+
+// DeliverAfter returns the attributes to delay the delivery of a message by the given duration.
+func DeliverAfter(delay time.Duration) map[string]interface{} {
+	return DeliverAt(time.Now().Add(delay))
+}
@@ -11,7 +11,16 @@ type EncodeHandler interface {
 	Decode(ctx context.Context, data interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error)
 }
 
-var defaultEncodeHandlers = make([]EncodeHandler, 0)
+// A MessageEncodeHandler is an encode handler which is applied to the message after its body
+// has been encoded and compressed and all encode handlers have been applied.
+type MessageEncodeHandler interface {
+	EncodeMessage(ctx context.Context, msg *Message) (context.Context, error)
+}
+
+var defaultEncodeHandlers = []EncodeHandler{
+	NewPartitionKeyEncodeHandler(),
+	NewModelVersionEncodeHandler(),
+}
 
 func AddDefaultEncodeHandler(handler EncodeHandler) {
 	defaultEncodeHandlers = append(defaultEncodeHandlers, handler)
@@ -82,6 +91,18 @@ func (e *messageEncoder) Encode(ctx context.Context, data interface{}, attribute
 		Body:       string(body),
 	}
 
+	for _, handler := range e.encodeHandlers {
+		messageHandler, ok := handler.(MessageEncodeHandler)
+
+		if !ok {
+			continue
+		}
+
+		if _, err = messageHandler.EncodeMessage(ctx, msg); err != nil {
+			return nil, fmt.Errorf("can not apply encoding handler on encoded message: %w", err)
+		}
+	}
+
 	return msg, nil
 }
 
@@ -96,7 +117,15 @@ func (e *messageEncoder) encodeBody(attributes map[string]interface{}, data inte
 		return nil, fmt.Errorf("there is no message body encoder available for encoding '%s'", e.encoding)
 	}
 
-	body, err := encoder.Encode(data)
+	var err error
+	var body []byte
+	var schemaId string
+
+	if schemaEncoder, ok := encoder.(SchemaMessageBodyEncoder); ok {
+		body, schemaId, err = schemaEncoder.EncodeWithSchema(data)
+	} else {
+		body, err = encoder.Encode(data)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("can not encode message body with encoding '%s': %w", e.encoding, err)
@@ -104,6 +133,10 @@ func (e *messageEncoder) encodeBody(attributes map[string]interface{}, data inte
 
 	attributes[AttributeEncoding] = e.encoding
 
+	if schemaId != "" {
+		attributes[AttributeSchemaId] = schemaId
+	}
+
 	return body, nil
 }
 
@@ -218,13 +251,28 @@ func (e *messageEncoder) decodeBody(attributes map[string]interface{}, body []by
 		return fmt.Errorf("there is no message body decoder available for encoding '%s'", encoding)
 	}
 
-	err := encoder.Decode(body, out)
+	var err error
+
+	if schemaEncoder, ok := encoder.(SchemaMessageBodyEncoder); ok {
+		var schemaId string
+
+		if attrSchemaId, ok := attributes[AttributeSchemaId]; ok {
+			if schemaId, ok = attrSchemaId.(string); !ok {
+				return fmt.Errorf("the schema id attribute '%v' should be of type string but instead is '%T'", attrSchemaId, attrSchemaId)
+			}
+		}
+
+		err = schemaEncoder.DecodeWithSchema(body, schemaId, out)
+	} else {
+		err = encoder.Decode(body, out)
+	}
 
 	if err != nil {
 		return fmt.Errorf("can not decode message body with encoding '%s': %w", encoding, err)
 	}
 
 	delete(attributes, AttributeEncoding)
+	delete(attributes, AttributeSchemaId)
 
 	return nil
 }
@@ -251,6 +251,45 @@ func (s *MessageEncoderSuite) TestDecode() {
 	}
 }
 
+func (s *MessageEncoderSuite) TestCompressionRoundTrip() {
+	data := encodingTestStruct{
+		Id:        3,
+		Text:      "example",
+		CreatedAt: s.clock.Now(),
+	}
+
+	compressions := []string{
+		stream.CompressionGZip,
+		stream.CompressionLz4,
+		stream.CompressionSnappy,
+		stream.CompressionZstd,
+	}
+
+	for _, compression := range compressions {
+		s.Run(compression, func() {
+			encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+				Encoding:    stream.EncodingJson,
+				Compression: compression,
+			})
+
+			msg, err := encoder.Encode(context.Background(), data)
+			s.NoError(err)
+			s.Equal(compression, msg.Attributes[stream.AttributeCompression])
+
+			// the decoder picks the decompressor from the compression attribute
+			decoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{})
+			out := encodingTestStruct{}
+			_, attributes, err := decoder.Decode(context.Background(), msg, &out)
+
+			s.NoError(err)
+			s.NotContains(attributes, stream.AttributeCompression)
+			s.Equal(data.Id, out.Id)
+			s.Equal(data.Text, out.Text)
+			s.True(data.CreatedAt.Equal(out.CreatedAt))
+		})
+	}
+}
+
 func TestMessageEncoderSuite(t *testing.T) {
 	suite.Run(t, new(MessageEncoderSuite))
 }
@@ -0,0 +1,252 @@
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/kernel"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/twinj/uuid"
+	"sync"
+	"time"
+)
+
+// archiveRotationCheckInterval is the time between two checks whether the current file is due
+const archiveRotationCheckInterval = time.Second
+
+type ArchiveOutputSettings struct {
+	Storage ArchiveStorageSettings
+	Gzip    bool
+	// RotationInterval is the maximum time a file stays open before it is written to the storage
+	RotationInterval time.Duration
+	// RotationSize is the maximum uncompressed size of a file in bytes
+	RotationSize int
+	// MaxBufferSize is the maximum uncompressed size of the buffered messages in bytes. The buffer grows beyond
+	// the rotation size while the storage is failing, writes are rejected once it is full
+	MaxBufferSize int
+}
+
+// An archiveOutput writes the messages into rotating, time partitioned files which can be replayed by
+// the archive input. As files can't be appended to in s3, the current file is buffered in memory. The
+// output is a kernel module as well, which rotates the files in time and writes the last one on shutdown.
+type archiveOutput struct {
+	kernel.BackgroundModule
+	kernel.EssentialStage
+
+	logger   mon.Logger
+	clock    clock.Clock
+	storage  ArchiveStorage
+	settings *ArchiveOutputSettings
+
+	lck   sync.Mutex
+	buf   *bytes.Buffer
+	start time.Time
+}
+
+var archiveOutputs = struct {
+	sync.Mutex
+	instances map[string]*archiveOutput
+}{
+	instances: map[string]*archiveOutput{},
+}
+
+// ProvideArchiveOutput returns the archive output with the given name, which is shared by the producers writing
+// to it and the kernel module rotating its files.
+func ProvideArchiveOutput(config cfg.Config, logger mon.Logger, name string) *archiveOutput {
+	archiveOutputs.Lock()
+	defer archiveOutputs.Unlock()
+
+	if output, ok := archiveOutputs.instances[name]; ok {
+		return output
+	}
+
+	configuration := readArchiveOutputConfiguration(config, name)
+
+	archiveOutputs.instances[name] = NewArchiveOutput(config, logger, &ArchiveOutputSettings{
+		Storage:          configuration.Storage,
+		Gzip:             configuration.Gzip,
+		RotationInterval: configuration.RotationInterval,
+		RotationSize:     configuration.RotationSize,
+		MaxBufferSize:    configuration.MaxBufferSize,
+	})
+
+	return archiveOutputs.instances[name]
+}
+
+// ArchiveOutputModuleFactory creates the kernel modules of all configured archive outputs.
+func ArchiveOutputModuleFactory(config cfg.Config, logger mon.Logger) (map[string]kernel.Module, error) {
+	modules := make(map[string]kernel.Module)
+	outputs := config.GetStringMap("stream.output", map[string]interface{}{})
+
+	for name := range outputs {
+		key := fmt.Sprintf("%s.type", ConfigurableOutputKey(name))
+
+		if config.GetString(key) != OutputTypeArchive {
+			continue
+		}
+
+		moduleName := fmt.Sprintf("archive-output-%s", name)
+		modules[moduleName] = ProvideArchiveOutput(config, logger, name)
+	}
+
+	return modules, nil
+}
+
+func NewArchiveOutput(config cfg.Config, logger mon.Logger, settings *ArchiveOutputSettings) *archiveOutput {
+	storage := NewArchiveStorage(config, &settings.Storage)
+
+	return NewArchiveOutputWithInterfaces(logger, clock.NewRealClock(), storage, settings)
+}
+
+func NewArchiveOutputWithInterfaces(logger mon.Logger, clock clock.Clock, storage ArchiveStorage, settings *ArchiveOutputSettings) *archiveOutput {
+	return &archiveOutput{
+		logger:   logger.WithChannel("archive-output"),
+		clock:    clock,
+		storage:  storage,
+		settings: settings,
+		buf:      &bytes.Buffer{},
+	}
+}
+
+func (o *archiveOutput) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (o *archiveOutput) Run(ctx context.Context) error {
+	ticker := time.NewTicker(archiveRotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := o.Flush(context.Background()); err != nil {
+				o.logger.Error(err, "can not write the last archive file")
+			}
+
+			return nil
+
+		case <-ticker.C:
+			o.lck.Lock()
+			err := o.rotateIfDue(ctx)
+			o.lck.Unlock()
+
+			if err != nil {
+				o.logger.Error(err, "can not rotate the archive file")
+			}
+		}
+	}
+}
+
+func (o *archiveOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+// Write buffers the messages and rotates the file if it is due. Once the messages are buffered, they are written
+// with the next successful rotation, so a failing rotation is only logged. Retrying the write would duplicate them.
+func (o *archiveOutput) Write(ctx context.Context, batch []*Message) error {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	now := o.clock.Now()
+	lines := make([][]byte, len(batch))
+	size := 0
+
+	for i, msg := range batch {
+		data, err := json.Marshal(archiveRecord{
+			Timestamp:  now,
+			Attributes: msg.Attributes,
+			Body:       msg.Body,
+		})
+
+		if err != nil {
+			return fmt.Errorf("can not marshal message: %w", err)
+		}
+
+		lines[i] = data
+		size += len(data) + 1
+	}
+
+	if o.settings.MaxBufferSize > 0 && o.buf.Len()+size > o.settings.MaxBufferSize {
+		return fmt.Errorf("can not buffer %d messages of %d bytes, the buffer of %d bytes is full", len(batch), size, o.buf.Len())
+	}
+
+	var err error
+
+	for _, data := range lines {
+		if o.buf.Len() == 0 {
+			o.start = now
+		}
+
+		o.buf.Write(data)
+		o.buf.WriteByte('\n')
+
+		// after a failed rotation, the remaining messages are buffered and the rotation is retried with the next write
+		if err == nil && o.buf.Len() >= o.settings.RotationSize {
+			err = o.rotate(ctx)
+		}
+	}
+
+	if err == nil {
+		err = o.rotateIfDue(ctx)
+	}
+
+	if err != nil {
+		o.logger.Error(err, "can not rotate the archive file")
+	}
+
+	return nil
+}
+
+// Flush writes the current file to the storage, even if it is not due yet.
+func (o *archiveOutput) Flush(ctx context.Context) error {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	return o.rotate(ctx)
+}
+
+func (o *archiveOutput) rotateIfDue(ctx context.Context) error {
+	if o.buf.Len() == 0 || o.clock.Now().Sub(o.start) < o.settings.RotationInterval {
+		return nil
+	}
+
+	return o.rotate(ctx)
+}
+
+// rotate writes the current file to the storage. If the write fails, the buffer is kept and written with the next rotation.
+func (o *archiveOutput) rotate(ctx context.Context) error {
+	if o.buf.Len() == 0 {
+		return nil
+	}
+
+	body := o.buf.Bytes()
+
+	if o.settings.Gzip {
+		compressed := &bytes.Buffer{}
+		writer := gzip.NewWriter(compressed)
+
+		if _, err := writer.Write(body); err != nil {
+			return fmt.Errorf("can not compress the archive file: %w", err)
+		}
+
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("can not compress the archive file: %w", err)
+		}
+
+		body = compressed.Bytes()
+	}
+
+	key := archiveFileKey(o.settings.Storage.Prefix, o.start, uuid.NewV4().String(), o.settings.Gzip)
+
+	if err := o.storage.Write(ctx, key, body); err != nil {
+		return fmt.Errorf("can not write the archive file %s: %w", key, err)
+	}
+
+	o.buf = &bytes.Buffer{}
+
+	return nil
+}
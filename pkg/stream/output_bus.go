@@ -0,0 +1,34 @@
+package stream
+
+import (
+	"context"
+)
+
+type BusOutputSettings struct {
+	Topic string
+}
+
+// busOutput writes to an in-process bus topic, which fans the messages out to all subscribed bus inputs.
+type busOutput struct {
+	topic *busTopic
+}
+
+func NewBusOutput(settings *BusOutputSettings) *busOutput {
+	return &busOutput{
+		topic: provideBusTopic(settings.Topic),
+	}
+}
+
+func (o *busOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+func (o *busOutput) Write(ctx context.Context, batch []*Message) error {
+	for _, msg := range batch {
+		if err := o.topic.publish(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
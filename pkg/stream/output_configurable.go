@@ -7,42 +7,122 @@ import (
 	"github.com/applike/gosoline/pkg/exec"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/sqs"
+	"time"
 )
 
 const (
-	OutputTypeFile     = "file"
-	OutputTypeInMemory = "inMemory"
-	OutputTypeKinesis  = "kinesis"
-	OutputTypeMultiple = "multiple"
-	OutputTypeRedis    = "redis"
-	OutputTypeSns      = "sns"
-	OutputTypeSqs      = "sqs"
+	OutputTypeArchive     = "archive"
+	OutputTypeBus         = "bus"
+	OutputTypeFile        = "file"
+	OutputTypeFirehose    = "firehose"
+	OutputTypeInMemory    = "inMemory"
+	OutputTypeKinesis     = "kinesis"
+	OutputTypeMultiple    = "multiple"
+	OutputTypeRedis       = "redis"
+	OutputTypeRedisStream = "redis_stream"
+	OutputTypeRouter      = "router"
+	OutputTypeScheduler   = "scheduler"
+	OutputTypeSns         = "sns"
+	OutputTypeSqs         = "sqs"
 )
 
+type OutputFactory func(config cfg.Config, logger mon.Logger, name string) Output
+
+var outputFactories = map[string]OutputFactory{
+	OutputTypeArchive:     newArchiveOutputFromConfig,
+	OutputTypeBus:         newBusOutputFromConfig,
+	OutputTypeFile:        newFileOutputFromConfig,
+	OutputTypeFirehose:    newFirehoseOutputFromConfig,
+	OutputTypeInMemory:    newInMemoryOutputFromConfig,
+	OutputTypeKinesis:     newKinesisOutputFromConfig,
+	OutputTypeRedis:       newRedisListOutputFromConfig,
+	OutputTypeRedisStream: newRedisStreamOutputFromConfig,
+	OutputTypeSns:         newSnsOutputFromConfig,
+	OutputTypeSqs:         newSqsOutputFromConfig,
+}
+
+func SetOutputFactory(typ string, factory OutputFactory) {
+	outputFactories[typ] = factory
+}
+
+func init() {
+	// the multiple, router and scheduler outputs create their outputs using the registry itself,
+	// so they can't be part of the initializer of outputFactories
+	outputFactories[OutputTypeMultiple] = newMultipleOutput
+	outputFactories[OutputTypeRouter] = newRouterOutputFromConfig
+	outputFactories[OutputTypeScheduler] = newSchedulerOutputFromConfig
+}
+
+var outputs = map[string]Output{}
+
+func ProvideConfigurableOutput(config cfg.Config, logger mon.Logger, name string) Output {
+	if output, ok := outputs[name]; ok {
+		return output
+	}
+
+	outputs[name] = NewConfigurableOutput(config, logger, name)
+
+	return outputs[name]
+}
+
 func NewConfigurableOutput(config cfg.Config, logger mon.Logger, name string) Output {
 	key := fmt.Sprintf("%s.type", ConfigurableOutputKey(name))
 	t := config.GetString(key)
 
-	switch t {
-	case OutputTypeFile:
-		return newFileOutputFromConfig(config, logger, name)
-	case OutputTypeInMemory:
-		return ProvideInMemoryOutput(name)
-	case OutputTypeKinesis:
-		return newKinesisOutputFromConfig(config, logger, name)
-	case OutputTypeMultiple:
-		return newMultipleOutput(config, logger, name)
-	case OutputTypeRedis:
-		return newRedisListOutputFromConfig(config, logger, name)
-	case OutputTypeSns:
-		return newSnsOutputFromConfig(config, logger, name)
-	case OutputTypeSqs:
-		return newSqsOutputFromConfig(config, logger, name)
-	default:
-		logger.Fatalf(fmt.Errorf("invalid input %s of type %s", name, t), "invalid input %s of type %s", name, t)
+	factory, ok := outputFactories[t]
+
+	if !ok {
+		logger.Fatalf(fmt.Errorf("invalid output %s of type %s", name, t), "invalid output %s of type %s", name, t)
+	}
+
+	return factory(config, logger, name)
+}
+
+type archiveOutputConfiguration struct {
+	Storage          ArchiveStorageSettings `cfg:"storage"`
+	Gzip             bool                   `cfg:"gzip" default:"true"`
+	RotationInterval time.Duration          `cfg:"rotation_interval" default:"5m"`
+	RotationSize     int                    `cfg:"rotation_size" default:"67108864" validate:"min=1"`
+	MaxBufferSize    int                    `cfg:"max_buffer_size" default:"268435456" validate:"min=1"`
+}
+
+func readArchiveOutputConfiguration(config cfg.Config, name string) *archiveOutputConfiguration {
+	key := ConfigurableOutputKey(name)
+	configuration := &archiveOutputConfiguration{}
+	config.UnmarshalKey(key, configuration)
+
+	if configuration.Storage.Prefix == "" {
+		configuration.Storage.Prefix = name
 	}
 
-	return nil
+	return configuration
+}
+
+func newArchiveOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	return ProvideArchiveOutput(config, logger, name)
+}
+
+type schedulerOutputConfiguration struct {
+	// Output is the target output of the due messages
+	Output    string                 `cfg:"output" validate:"required"`
+	Store     SchedulerStoreSettings `cfg:"store"`
+	Threshold time.Duration          `cfg:"threshold" default:"0s"`
+	Interval  time.Duration          `cfg:"interval" default:"1s"`
+	BatchSize int                    `cfg:"batch_size" default:"100" validate:"min=1"`
+	// Lease is the time an instance has to write the due messages it claimed before another instance may claim them
+	Lease time.Duration `cfg:"lease" default:"1m" validate:"min=1000000000"`
+}
+
+func readSchedulerOutputConfiguration(config cfg.Config, name string) *schedulerOutputConfiguration {
+	key := ConfigurableOutputKey(name)
+	configuration := &schedulerOutputConfiguration{}
+	config.UnmarshalKey(key, configuration)
+
+	return configuration
+}
+
+func newSchedulerOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	return ProvideSchedulerOutput(config, logger, name)
 }
 
 func newFileOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -54,8 +134,34 @@ func newFileOutputFromConfig(config cfg.Config, logger mon.Logger, name string)
 }
 
 type kinesisOutputConfiguration struct {
-	StreamName string               `cfg:"stream_name"`
-	Backoff    exec.BackoffSettings `cfg:"backoff"`
+	StreamName   string                     `cfg:"stream_name"`
+	Backoff      exec.BackoffSettings       `cfg:"backoff"`
+	PartitionKey PartitionKeySettings       `cfg:"partition_key"`
+	Aggregation  KinesisAggregationSettings `cfg:"aggregation"`
+}
+
+type firehoseOutputConfiguration struct {
+	DeliveryStreamName string               `cfg:"delivery_stream_name" validate:"required"`
+	NewlineDelimited   bool                 `cfg:"newline_delimited" default:"false"`
+	Client             cloud.ClientSettings `cfg:"client"`
+	Backoff            exec.BackoffSettings `cfg:"backoff"`
+}
+
+func newFirehoseOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := ConfigurableOutputKey(name)
+	configuration := &firehoseOutputConfiguration{}
+	config.UnmarshalKey(key, configuration)
+
+	return NewFirehoseOutput(config, logger, &FirehoseOutputSettings{
+		DeliveryStreamName: configuration.DeliveryStreamName,
+		NewlineDelimited:   configuration.NewlineDelimited,
+		Client:             configuration.Client,
+		Backoff:            configuration.Backoff,
+	})
+}
+
+func newInMemoryOutputFromConfig(_ cfg.Config, _ mon.Logger, name string) Output {
+	return ProvideInMemoryOutput(name)
 }
 
 func newKinesisOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -64,8 +170,29 @@ func newKinesisOutputFromConfig(config cfg.Config, logger mon.Logger, name strin
 	config.UnmarshalKey(key, settings)
 
 	return NewKinesisOutput(config, logger, &KinesisOutputSettings{
-		StreamName: settings.StreamName,
-		Backoff:    settings.Backoff,
+		StreamName:   settings.StreamName,
+		Backoff:      settings.Backoff,
+		PartitionKey: settings.PartitionKey,
+		Aggregation:  settings.Aggregation,
+	})
+}
+
+type busOutputConfiguration struct {
+	Topic string `cfg:"topic"`
+}
+
+func newBusOutputFromConfig(config cfg.Config, _ mon.Logger, name string) Output {
+	key := ConfigurableOutputKey(name)
+
+	configuration := busOutputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	if configuration.Topic == "" {
+		configuration.Topic = name
+	}
+
+	return NewBusOutput(&BusOutputSettings{
+		Topic: configuration.Topic,
 	})
 }
 
@@ -100,6 +227,35 @@ func newRedisListOutputFromConfig(config cfg.Config, logger mon.Logger, name str
 	})
 }
 
+type redisStreamOutputConfiguration struct {
+	Project     string `cfg:"project"`
+	Family      string `cfg:"family"`
+	Application string `cfg:"application"`
+	ServerName  string `cfg:"server_name" default:"default" validate:"required,min=1"`
+	Key         string `cfg:"key" validate:"required,min=1"`
+	MaxLen      int64  `cfg:"max_len" default:"0"`
+	MaxLenExact bool   `cfg:"max_len_exact" default:"false"`
+}
+
+func newRedisStreamOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := ConfigurableOutputKey(name)
+
+	configuration := redisStreamOutputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	return NewRedisStreamOutput(config, logger, &RedisStreamOutputSettings{
+		AppId: cfg.AppId{
+			Project:     configuration.Project,
+			Family:      configuration.Family,
+			Application: configuration.Application,
+		},
+		ServerName:  configuration.ServerName,
+		Key:         configuration.Key,
+		MaxLen:      configuration.MaxLen,
+		MaxLenExact: configuration.MaxLenExact,
+	})
+}
+
 type SnsOutputConfiguration struct {
 	Type        string               `cfg:"type"`
 	Project     string               `cfg:"project"`
@@ -129,15 +285,17 @@ func newSnsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) O
 }
 
 type sqsOutputConfiguration struct {
-	Project           string               `cfg:"project"`
-	Family            string               `cfg:"family"`
-	Application       string               `cfg:"application"`
-	QueueId           string               `cfg:"queue_id" validate:"required"`
-	VisibilityTimeout int                  `cfg:"visibility_timeout" default:"30" validate:"gt=0"`
-	RedrivePolicy     sqs.RedrivePolicy    `cfg:"redrive_policy"`
-	Client            cloud.ClientSettings `cfg:"client"`
-	Backoff           exec.BackoffSettings `cfg:"backoff"`
-	Fifo              sqs.FifoSettings     `cfg:"fifo"`
+	Project                string               `cfg:"project"`
+	Family                 string               `cfg:"family"`
+	Application            string               `cfg:"application"`
+	QueueId                string               `cfg:"queue_id" validate:"required"`
+	VisibilityTimeout      int                  `cfg:"visibility_timeout" default:"30" validate:"gt=0"`
+	RedrivePolicy          sqs.RedrivePolicy    `cfg:"redrive_policy"`
+	Client                 cloud.ClientSettings `cfg:"client"`
+	Backoff                exec.BackoffSettings `cfg:"backoff"`
+	Fifo                   sqs.FifoSettings     `cfg:"fifo"`
+	MessageGroupId         PartitionKeySettings `cfg:"message_group_id"`
+	MessageDeduplicationId PartitionKeySettings `cfg:"message_deduplication_id"`
 }
 
 func newSqsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -152,12 +310,14 @@ func newSqsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) O
 			Family:      configuration.Family,
 			Application: configuration.Application,
 		},
-		QueueId:           configuration.QueueId,
-		VisibilityTimeout: configuration.VisibilityTimeout,
-		RedrivePolicy:     configuration.RedrivePolicy,
-		Client:            configuration.Client,
-		Backoff:           configuration.Backoff,
-		Fifo:              configuration.Fifo,
+		QueueId:                configuration.QueueId,
+		VisibilityTimeout:      configuration.VisibilityTimeout,
+		RedrivePolicy:          configuration.RedrivePolicy,
+		Client:                 configuration.Client,
+		Backoff:                configuration.Backoff,
+		Fifo:                   configuration.Fifo,
+		MessageGroupId:         configuration.MessageGroupId,
+		MessageDeduplicationId: configuration.MessageDeduplicationId,
 	})
 }
 
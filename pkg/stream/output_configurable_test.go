@@ -0,0 +1,53 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type webhookOutput struct {
+	url string
+}
+
+func (o *webhookOutput) WriteOne(_ context.Context, _ *stream.Message) error {
+	return nil
+}
+
+func (o *webhookOutput) Write(_ context.Context, _ []*stream.Message) error {
+	return nil
+}
+
+func TestSetOutputFactory(t *testing.T) {
+	config := cfg.New()
+	err := config.Option(cfg.WithConfigMap(map[string]interface{}{
+		"stream": map[string]interface{}{
+			"output": map[string]interface{}{
+				"hooks": map[string]interface{}{
+					"type": "webhook",
+					"url":  "http://localhost/hook",
+				},
+			},
+		},
+	}))
+	assert.NoError(t, err)
+
+	stream.SetOutputFactory("webhook", func(config cfg.Config, _ mon.Logger, name string) stream.Output {
+		return &webhookOutput{
+			url: config.GetString(stream.ConfigurableOutputKey(name) + ".url"),
+		}
+	})
+
+	logger := monMocks.NewLoggerMockedAll()
+
+	output := stream.NewConfigurableOutput(config, logger, "hooks")
+	assert.Equal(t, &webhookOutput{url: "http://localhost/hook"}, output)
+
+	provided := stream.ProvideConfigurableOutput(config, logger, "hooks")
+	assert.NotSame(t, output, provided)
+	assert.Same(t, provided, stream.ProvideConfigurableOutput(config, logger, "hooks"))
+}
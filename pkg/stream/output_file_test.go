@@ -8,15 +8,18 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
 	"sync"
 	"testing"
 )
 
 func TestOutputFile_ConcurrentWrite(t *testing.T) {
-	fileName := "testdata/output_file_test.output.txt"
+	directory, err := ioutil.TempDir("", "stream-output-file")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
 
-	_ = os.Remove(fileName)
+	fileName := path.Join(directory, "output.txt")
 
 	logger := mon.NewLogger()
 	output := stream.NewFileOutput(nil, logger, &stream.FileOutputSettings{
@@ -0,0 +1,188 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/cloud"
+	gosoAws "github.com/applike/gosoline/pkg/cloud/aws"
+	"github.com/applike/gosoline/pkg/exec"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/cenkalti/backoff"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+const (
+	firehoseBatchSizeMax   = 500
+	firehoseBatchBytesMax  = 4 * 1024 * 1024
+	firehoseRecordBytesMax = 1000 * 1024
+)
+
+type FirehoseOutputSettings struct {
+	DeliveryStreamName string
+	// NewlineDelimited appends a newline to every record, so the files written by
+	// firehose contain one message per line and can be queried directly
+	NewlineDelimited bool
+	Client           cloud.ClientSettings
+	Backoff          exec.BackoffSettings
+}
+
+func (s *FirehoseOutputSettings) GetResourceName() string {
+	return s.DeliveryStreamName
+}
+
+type firehoseOutput struct {
+	logger   mon.Logger
+	client   firehoseiface.FirehoseAPI
+	executor gosoAws.Executor
+	settings *FirehoseOutputSettings
+}
+
+func NewFirehoseOutput(config cfg.Config, logger mon.Logger, settings *FirehoseOutputSettings) Output {
+	if settings.Backoff.Enabled {
+		settings.Client.MaxRetries = 0
+	}
+
+	awsConfig := cloud.GetAwsConfig(config, logger, "firehose", &settings.Client)
+	sess := session.Must(session.NewSession(awsConfig))
+	client := firehose.New(sess)
+
+	res := &exec.ExecutableResource{
+		Type: "firehose",
+		Name: settings.DeliveryStreamName,
+	}
+
+	executor := gosoAws.NewExecutor(logger, res, &settings.Backoff)
+
+	return NewFirehoseOutputWithInterfaces(logger, client, executor, settings)
+}
+
+func NewFirehoseOutputWithInterfaces(logger mon.Logger, client firehoseiface.FirehoseAPI, executor gosoAws.Executor, settings *FirehoseOutputSettings) Output {
+	return &firehoseOutput{
+		logger:   logger,
+		client:   client,
+		executor: executor,
+		settings: settings,
+	}
+}
+
+func (o *firehoseOutput) WriteOne(ctx context.Context, record *Message) error {
+	return o.Write(ctx, []*Message{record})
+}
+
+func (o *firehoseOutput) Write(ctx context.Context, batch []*Message) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0)
+	records, err := o.buildRecords(batch)
+
+	if err != nil {
+		o.logger.Error(err, "could not build records for all messages")
+		errs = append(errs, err)
+	}
+
+	chunks := BuildSizedChunks(len(records), firehoseBatchSizeMax, firehoseBatchBytesMax, func(i int) int {
+		return len(records[i].Data)
+	})
+
+	for _, chunk := range chunks {
+		if err = o.writeBatch(ctx, records[chunk.Start:chunk.End:chunk.End]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errors.Wrap(errs[0], fmt.Sprintf("there were %v write errors to %v", len(errs), o.settings.DeliveryStreamName))
+}
+
+func (o *firehoseOutput) buildRecords(batch []*Message) ([]*firehose.Record, error) {
+	var result error
+	records := make([]*firehose.Record, 0, len(batch))
+
+	for _, msg := range batch {
+		data, err := msg.MarshalToBytes()
+
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("can not marshal message: %w", err))
+			continue
+		}
+
+		if o.settings.NewlineDelimited {
+			data = append(data, '\n')
+		}
+
+		if len(data) > firehoseRecordBytesMax {
+			result = multierror.Append(result, fmt.Errorf("the message of %d bytes exceeds the maximum record size of %d bytes", len(data), firehoseRecordBytesMax))
+			continue
+		}
+
+		records = append(records, &firehose.Record{
+			Data: data,
+		})
+	}
+
+	return records, result
+}
+
+func (o *firehoseOutput) writeBatch(ctx context.Context, records []*firehose.Record) error {
+	backoffConfig := backoff.WithContext(exec.NewExponentialBackOff(&o.settings.Backoff), ctx)
+
+	err := backoff.Retry(func() (err error) {
+		records, err = o.putRecordBatchAndCollectFailed(ctx, records)
+
+		return err
+	}, backoffConfig)
+
+	if err != nil {
+		o.logger.Error(err, "error putting records")
+	}
+
+	return err
+}
+
+func (o *firehoseOutput) putRecordBatchAndCollectFailed(ctx context.Context, records []*firehose.Record) ([]*firehose.Record, error) {
+	input := firehose.PutRecordBatchInput{
+		DeliveryStreamName: aws.String(o.settings.DeliveryStreamName),
+		Records:            records,
+	}
+
+	output, err := o.executor.Execute(ctx, func() (*request.Request, interface{}) {
+		return o.client.PutRecordBatchRequest(&input)
+	})
+
+	// failed requests have already been retried by the executor, only failed records are retried here
+	if err != nil {
+		return records, backoff.Permanent(err)
+	}
+
+	failedRecords := make([]*firehose.Record, 0)
+	putRecordBatchOutput := output.(*firehose.PutRecordBatchOutput)
+
+	for i, response := range putRecordBatchOutput.RequestResponses {
+		if response.ErrorCode != nil {
+			failedRecords = append(failedRecords, records[i])
+		}
+	}
+
+	o.logger.WithFields(mon.Fields{
+		"failed_records": len(failedRecords),
+		"total_records":  len(records),
+	}).Debug("put record batch to delivery stream")
+
+	if len(failedRecords) > 0 {
+		return failedRecords, fmt.Errorf("%d of %d records failed", len(failedRecords), len(records))
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package stream_test
+
+import (
+	"context"
+	gosoAws "github.com/applike/gosoline/pkg/cloud/aws"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type firehoseClientMock struct {
+	firehoseiface.FirehoseAPI
+	mock.Mock
+}
+
+func (m *firehoseClientMock) PutRecordBatchRequest(input *firehose.PutRecordBatchInput) (*request.Request, *firehose.PutRecordBatchOutput) {
+	args := m.Called(input)
+
+	return nil, args.Get(1).(*firehose.PutRecordBatchOutput)
+}
+
+func TestFirehoseOutput_Write(t *testing.T) {
+	client := new(firehoseClientMock)
+	executor := gosoAws.NewTestableExecutor(&client.Mock)
+
+	inputs := make([]*firehose.PutRecordBatchInput, 0)
+	matchInput := mock.MatchedBy(func(input *firehose.PutRecordBatchInput) bool {
+		inputs = append(inputs, input)
+		return true
+	})
+
+	executor.ExpectExecution("PutRecordBatchRequest", matchInput, &firehose.PutRecordBatchOutput{
+		FailedPutCount: aws.Int64(1),
+		RequestResponses: []*firehose.PutRecordBatchResponseEntry{
+			{RecordId: aws.String("1")},
+			{ErrorCode: aws.String("ServiceUnavailableException")},
+		},
+	}, nil)
+
+	executor.ExpectExecution("PutRecordBatchRequest", matchInput, &firehose.PutRecordBatchOutput{
+		FailedPutCount: aws.Int64(0),
+		RequestResponses: []*firehose.PutRecordBatchResponseEntry{
+			{RecordId: aws.String("2")},
+		},
+	}, nil)
+
+	logger := monMocks.NewLoggerMockedAll()
+	output := stream.NewFirehoseOutputWithInterfaces(logger, client, executor, &stream.FirehoseOutputSettings{
+		DeliveryStreamName: "events",
+		NewlineDelimited:   true,
+	})
+
+	err := output.Write(context.Background(), []*stream.Message{
+		stream.NewMessage("1"),
+		stream.NewMessage("2"),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, inputs, 2)
+
+	firstInput, retryInput := inputs[0], inputs[1]
+	assert.Equal(t, "events", *firstInput.DeliveryStreamName)
+	assert.Len(t, firstInput.Records, 2)
+	assert.Equal(t, "{\"attributes\":{},\"body\":\"1\"}\n", string(firstInput.Records[0].Data))
+
+	assert.Len(t, retryInput.Records, 1, "only the failed record should be retried")
+	assert.Equal(t, "{\"attributes\":{},\"body\":\"2\"}\n", string(retryInput.Records[0].Data))
+
+	executor.AssertExpectations(t)
+}
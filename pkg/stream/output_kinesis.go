@@ -13,16 +13,22 @@ import (
 	"github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 	"github.com/cenkalti/backoff"
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/twinj/uuid"
 	"time"
 )
 
-const kinesisBatchSizeMax = 500
+const (
+	kinesisBatchSizeMax  = 500
+	kinesisBatchBytesMax = 5 * 1024 * 1024
+)
 
 type KinesisOutputSettings struct {
-	StreamName string
-	Backoff    exec.BackoffSettings
+	StreamName   string
+	Backoff      exec.BackoffSettings
+	PartitionKey PartitionKeySettings
+	Aggregation  KinesisAggregationSettings
 }
 
 func (k *KinesisOutputSettings) GetResourceName() string {
@@ -30,10 +36,12 @@ func (k *KinesisOutputSettings) GetResourceName() string {
 }
 
 type kinesisOutput struct {
-	logger   mon.Logger
-	client   kinesisiface.KinesisAPI
-	executor gosoAws.Executor
-	settings *KinesisOutputSettings
+	logger       mon.Logger
+	client       kinesisiface.KinesisAPI
+	executor     gosoAws.Executor
+	partitionKey *partitionKeyBuilder
+	aggregator   *kinesisAggregator
+	settings     *KinesisOutputSettings
 }
 
 func NewKinesisOutput(config cfg.Config, logger mon.Logger, settings *KinesisOutputSettings) Output {
@@ -52,11 +60,20 @@ func NewKinesisOutput(config cfg.Config, logger mon.Logger, settings *KinesisOut
 }
 
 func NewKinesisOutputWithInterfaces(logger mon.Logger, client kinesisiface.KinesisAPI, executor gosoAws.Executor, settings *KinesisOutputSettings) Output {
+	partitionKey := newPartitionKeyBuilder(settings.PartitionKey, PartitionKeyStrategyRandom)
+
+	var aggregator *kinesisAggregator
+	if settings.Aggregation.Enabled {
+		aggregator = newKinesisAggregator(settings.Aggregation, partitionKey.strategy != PartitionKeyStrategyRandom)
+	}
+
 	return &kinesisOutput{
-		client:   client,
-		settings: settings,
-		executor: executor,
-		logger:   logger,
+		client:       client,
+		settings:     settings,
+		executor:     executor,
+		partitionKey: partitionKey,
+		aggregator:   aggregator,
+		logger:       logger,
 	}
 }
 
@@ -70,16 +87,23 @@ func (o *kinesisOutput) Write(ctx context.Context, batch []*Message) error {
 	}
 
 	errs := make([]error, 0)
-	chunks, err := BuildChunks(batch, kinesisBatchSizeMax)
+	records, err := o.buildRecords(batch)
 
 	if err != nil {
-		o.logger.Error(err, "could not batch all messages")
+		o.logger.Error(err, "could not build records for all messages")
+		errs = append(errs, err)
 	}
 
-	for _, chunk := range chunks {
-		err := o.writeBatch(ctx, chunk)
+	if o.aggregator != nil {
+		records = o.aggregator.Aggregate(records)
+	}
 
-		if err != nil {
+	chunks := BuildSizedChunks(len(records), kinesisBatchSizeMax, kinesisBatchBytesMax, func(i int) int {
+		return len(records[i].Data) + len(*records[i].PartitionKey)
+	})
+
+	for _, chunk := range chunks {
+		if err = o.writeBatch(ctx, records[chunk.Start:chunk.End:chunk.End]); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -91,18 +115,63 @@ func (o *kinesisOutput) Write(ctx context.Context, batch []*Message) error {
 	return errors.Wrap(errs[0], fmt.Sprintf("there were %v write errors to %v", len(errs), o.settings.StreamName))
 }
 
-func (o *kinesisOutput) writeBatch(ctx context.Context, batch [][]byte) error {
+func (o *kinesisOutput) buildRecords(batch []*Message) ([]*kinesis.PutRecordsRequestEntry, error) {
+	var result error
 	records := make([]*kinesis.PutRecordsRequestEntry, 0, len(batch))
 
-	for _, data := range batch {
-		req := &kinesis.PutRecordsRequestEntry{
-			Data:         data,
-			PartitionKey: aws.String(uuid.NewV4().String()),
+	for _, msg := range batch {
+		record, err := o.buildRecord(msg)
+
+		if err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, result
+}
+
+func (o *kinesisOutput) buildRecord(msg *Message) (*kinesis.PutRecordsRequestEntry, error) {
+	var err error
+	var partitionKey, explicitHashKey *string
+
+	if partitionKey, err = o.partitionKey.Build(msg); err != nil {
+		return nil, fmt.Errorf("can not build partition key: %w", err)
+	}
+
+	// kinesis requires a partition key for every record
+	if partitionKey == nil {
+		partitionKey = aws.String(uuid.NewV4().String())
+	}
+
+	if d, ok := msg.Attributes[AttributeExplicitHashKey]; ok {
+		hashKey, ok := d.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("the type of the %s attribute should be string but instead is %T", AttributeExplicitHashKey, d)
 		}
 
-		records = append(records, req)
+		explicitHashKey = aws.String(hashKey)
 	}
 
+	data, err := msg.MarshalToBytes()
+
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal message: %w", err)
+	}
+
+	record := &kinesis.PutRecordsRequestEntry{
+		Data:            data,
+		PartitionKey:    partitionKey,
+		ExplicitHashKey: explicitHashKey,
+	}
+
+	return record, nil
+}
+
+func (o *kinesisOutput) writeBatch(ctx context.Context, records []*kinesis.PutRecordsRequestEntry) error {
 	backoffConfig := backoff.NewExponentialBackOff()
 	backoffConfig.MaxElapsedTime = 15 * time.Minute
 
@@ -2,6 +2,7 @@ package stream_test
 
 import (
 	"context"
+	"crypto/md5"
 	gosoAws "github.com/applike/gosoline/pkg/cloud/aws"
 	cloudMocks "github.com/applike/gosoline/pkg/cloud/mocks"
 	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
@@ -44,3 +45,167 @@ func TestWriter_WriteEvents(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+type partitionKeyedModel struct {
+	UserId string
+}
+
+func (m partitionKeyedModel) GetPartitionKey() string {
+	return m.UserId
+}
+
+func TestWriter_WritePartitionKeys(t *testing.T) {
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	exec := gosoAws.NewTestableExecutor(&kinesisClient.Mock)
+
+	var records []*kinesis.PutRecordsRequestEntry
+	exec.ExpectExecution("PutRecordsRequest", mock.MatchedBy(func(input *kinesis.PutRecordsInput) bool {
+		records = input.Records
+		return true
+	}), &kinesis.PutRecordsOutput{Records: []*kinesis.PutRecordsResultEntry{}}, nil)
+
+	logger := monMocks.NewLoggerMockedAll()
+	writer := stream.NewKinesisOutputWithInterfaces(logger, kinesisClient, exec, &stream.KinesisOutputSettings{
+		StreamName: "streamName",
+		PartitionKey: stream.PartitionKeySettings{
+			Strategy: stream.PartitionKeyStrategyAttribute,
+		},
+	})
+
+	encoder := stream.NewMessageEncoder(&stream.MessageEncoderSettings{
+		Encoding: stream.EncodingJson,
+	})
+
+	keyed, err := encoder.Encode(context.Background(), partitionKeyedModel{UserId: "user-1"})
+	assert.NoError(t, err)
+
+	explicit, err := encoder.Encode(context.Background(), partitionKeyedModel{UserId: "user-2"}, map[string]interface{}{
+		stream.AttributeExplicitHashKey: "42",
+	})
+	assert.NoError(t, err)
+
+	err = writer.Write(context.Background(), []*stream.Message{keyed, explicit, stream.NewMessage("no key")})
+	assert.Error(t, err, "the message without partition key should not be written")
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, "user-1", *records[0].PartitionKey)
+	assert.Nil(t, records[0].ExplicitHashKey)
+	assert.Equal(t, "user-2", *records[1].PartitionKey)
+	assert.Equal(t, "42", *records[1].ExplicitHashKey)
+
+	exec.AssertExpectations(t)
+}
+
+func TestWriter_WriteHashedPartitionKeys(t *testing.T) {
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	exec := gosoAws.NewTestableExecutor(&kinesisClient.Mock)
+
+	var records []*kinesis.PutRecordsRequestEntry
+	exec.ExpectExecution("PutRecordsRequest", mock.MatchedBy(func(input *kinesis.PutRecordsInput) bool {
+		records = input.Records
+		return true
+	}), &kinesis.PutRecordsOutput{Records: []*kinesis.PutRecordsResultEntry{}}, nil)
+
+	logger := monMocks.NewLoggerMockedAll()
+	writer := stream.NewKinesisOutputWithInterfaces(logger, kinesisClient, exec, &stream.KinesisOutputSettings{
+		StreamName: "streamName",
+		PartitionKey: stream.PartitionKeySettings{
+			Strategy:  stream.PartitionKeyStrategyHashed,
+			Attribute: "userId",
+		},
+	})
+
+	// both events of the user get the same key although their bodies differ
+	err := writer.Write(context.Background(), []*stream.Message{
+		stream.NewMessage("created", map[string]interface{}{"userId": 1}),
+		stream.NewMessage("updated", map[string]interface{}{"userId": "1"}),
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b", *records[0].PartitionKey)
+	assert.Equal(t, *records[0].PartitionKey, *records[1].PartitionKey)
+
+	exec.AssertExpectations(t)
+}
+
+func TestWriter_WriteAggregated(t *testing.T) {
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	exec := gosoAws.NewTestableExecutor(&kinesisClient.Mock)
+
+	var records []*kinesis.PutRecordsRequestEntry
+	exec.ExpectExecution("PutRecordsRequest", mock.MatchedBy(func(input *kinesis.PutRecordsInput) bool {
+		records = input.Records
+		return true
+	}), &kinesis.PutRecordsOutput{Records: []*kinesis.PutRecordsResultEntry{}}, nil)
+
+	logger := monMocks.NewLoggerMockedAll()
+	writer := stream.NewKinesisOutputWithInterfaces(logger, kinesisClient, exec, &stream.KinesisOutputSettings{
+		StreamName: "streamName",
+		PartitionKey: stream.PartitionKeySettings{
+			Strategy: stream.PartitionKeyStrategyAttribute,
+		},
+		Aggregation: stream.KinesisAggregationSettings{
+			Enabled:    true,
+			MaxRecords: 2,
+			MaxSize:    1024,
+		},
+	})
+
+	batch := []*stream.Message{
+		stream.NewMessage("1", map[string]interface{}{stream.AttributePartitionKey: "a"}),
+		stream.NewMessage("2", map[string]interface{}{stream.AttributePartitionKey: "b"}),
+		stream.NewMessage("3", map[string]interface{}{stream.AttributePartitionKey: "a"}),
+		stream.NewMessage("4", map[string]interface{}{stream.AttributePartitionKey: "a"}),
+	}
+
+	err := writer.Write(context.Background(), batch)
+	assert.NoError(t, err)
+
+	assert.Len(t, records, 3, "records of the same key should be aggregated up to max records")
+	assert.Equal(t, []string{"a", "a", "b"}, []string{*records[0].PartitionKey, *records[1].PartitionKey, *records[2].PartitionKey})
+
+	expectedBodies := [][]string{{"1", "3"}, {"4"}, {"2"}}
+
+	for i, record := range records {
+		assert.True(t, stream.IsKinesisAggregatedRecord(record.Data))
+
+		data, err := stream.DeaggregateKinesisRecord(record.Data)
+		assert.NoError(t, err)
+
+		bodies := make([]string, len(data))
+		for j, d := range data {
+			msg := &stream.Message{}
+			assert.NoError(t, msg.UnmarshalFromBytes(d))
+			bodies[j] = msg.Body
+		}
+
+		assert.Equal(t, expectedBodies[i], bodies)
+	}
+
+	exec.AssertExpectations(t)
+}
+
+func TestDeaggregateKinesisRecord(t *testing.T) {
+	// aggregated record with partition key "a" and the single record "x"
+	proto := []byte{0x0a, 0x01, 0x61, 0x1a, 0x05, 0x08, 0x00, 0x1a, 0x01, 0x78}
+	checksum := md5.Sum(proto)
+
+	aggregated := append([]byte{0xF3, 0x89, 0x9A, 0xC2}, proto...)
+	aggregated = append(aggregated, checksum[:]...)
+
+	data, err := stream.DeaggregateKinesisRecord(aggregated)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("x")}, data)
+
+	corrupted := append([]byte{}, aggregated...)
+	corrupted[len(corrupted)-1]++
+
+	data, err = stream.DeaggregateKinesisRecord(corrupted)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{corrupted}, data, "records with an invalid checksum should not be deaggregated")
+
+	data, err = stream.DeaggregateKinesisRecord([]byte(`{"body":"x"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"body":"x"}`)}, data)
+}
@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/redis"
+	"github.com/applike/gosoline/pkg/tracing"
+	baseRedis "github.com/go-redis/redis"
+)
+
+type RedisStreamOutputSettings struct {
+	cfg.AppId
+	ServerName string
+	Key        string
+	// MaxLen trims the stream to about this number of messages on every write, 0 disables trimming
+	MaxLen int64
+	// MaxLenExact trims the stream exactly instead of the more efficient approximate trimming
+	MaxLenExact bool
+}
+
+type redisStreamOutput struct {
+	logger            mon.Logger
+	tracer            tracing.Tracer
+	client            redis.Client
+	settings          *RedisStreamOutputSettings
+	fullyQualifiedKey string
+}
+
+func NewRedisStreamOutput(config cfg.Config, logger mon.Logger, settings *RedisStreamOutputSettings) Output {
+	settings.PadFromConfig(config)
+
+	tracer := tracing.ProviderTracer(config, logger)
+	client := redis.ProvideClient(config, logger, settings.ServerName)
+
+	return NewRedisStreamOutputWithInterfaces(logger, tracer, client, settings)
+}
+
+func NewRedisStreamOutputWithInterfaces(logger mon.Logger, tracer tracing.Tracer, client redis.Client, settings *RedisStreamOutputSettings) Output {
+	fullyQualifiedKey := redis.GetFullyQualifiedKey(settings.AppId, settings.Key)
+
+	return &redisStreamOutput{
+		logger:            logger,
+		tracer:            tracer,
+		client:            client,
+		settings:          settings,
+		fullyQualifiedKey: fullyQualifiedKey,
+	}
+}
+
+func (o *redisStreamOutput) WriteOne(ctx context.Context, record *Message) error {
+	return o.Write(ctx, []*Message{record})
+}
+
+func (o *redisStreamOutput) Write(ctx context.Context, batch []*Message) error {
+	spanName := fmt.Sprintf("redis-stream-output-%v-%v-%v", o.settings.Family, o.settings.Application, o.settings.Key)
+
+	_, trans := o.tracer.StartSubSpan(ctx, spanName)
+	defer trans.Finish()
+
+	for _, msg := range batch {
+		body, err := msg.MarshalToString()
+
+		if err != nil {
+			return fmt.Errorf("can not marshal the message: %w", err)
+		}
+
+		args := &baseRedis.XAddArgs{
+			Stream: o.fullyQualifiedKey,
+			ID:     "*",
+			Values: map[string]interface{}{
+				redisStreamMessageField: body,
+			},
+		}
+
+		if o.settings.MaxLenExact {
+			args.MaxLen = o.settings.MaxLen
+		} else {
+			args.MaxLenApprox = o.settings.MaxLen
+		}
+
+		if _, err := o.client.XAdd(args); err != nil {
+			return fmt.Errorf("could not XAdd the message to the redis stream %s: %w", o.fullyQualifiedKey, err)
+		}
+	}
+
+	return nil
+}
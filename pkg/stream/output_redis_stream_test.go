@@ -0,0 +1,56 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon/mocks"
+	redisMocks "github.com/applike/gosoline/pkg/redis/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/tracing"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestRedisStreamOutput_Write(t *testing.T) {
+	loggerMock := mocks.NewLoggerMockedAll()
+	tracer := tracing.NewNoopTracer()
+	redisMock := new(redisMocks.Client)
+
+	output := stream.NewRedisStreamOutputWithInterfaces(loggerMock, tracer, redisMock, &stream.RedisStreamOutputSettings{
+		AppId: cfg.AppId{
+			Project:     "mcoins",
+			Environment: "test",
+			Family:      "analytics",
+			Application: "app",
+		},
+		Key:    "my-stream",
+		MaxLen: 1000,
+	})
+
+	bodies := make([]string, 0)
+
+	redisMock.On("XAdd", mock.AnythingOfType("*redis.XAddArgs")).Run(func(args mock.Arguments) {
+		addArgs := args.Get(0).(*baseRedis.XAddArgs)
+
+		assert.Equal(t, "mcoins-test-analytics-app-my-stream", addArgs.Stream)
+		assert.Equal(t, "*", addArgs.ID)
+		assert.Equal(t, int64(0), addArgs.MaxLen)
+		assert.Equal(t, int64(1000), addArgs.MaxLenApprox)
+
+		bodies = append(bodies, addArgs.Values["message"].(string))
+	}).Return("1-0", nil).Twice()
+
+	batch := []*stream.Message{
+		stream.NewMessage("foo"),
+		stream.NewMessage("bar"),
+	}
+	err := output.Write(context.Background(), batch)
+
+	assert.NoError(t, err)
+	assert.Len(t, bodies, 2)
+	assert.JSONEq(t, `{"attributes":{},"body":"foo"}`, bodies[0])
+	assert.JSONEq(t, `{"attributes":{},"body":"bar"}`, bodies[1])
+	redisMock.AssertExpectations(t)
+}
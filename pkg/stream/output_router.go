@@ -0,0 +1,242 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/cast"
+	"regexp"
+)
+
+const (
+	RouterOperatorEquals = "equals"
+	RouterOperatorExists = "exists"
+	RouterOperatorIn     = "in"
+	RouterOperatorRegex  = "regex"
+)
+
+// A RouterCondition compares an attribute of the message, like type, version or modelId, as string.
+type RouterCondition struct {
+	Attribute string   `cfg:"attribute" validate:"required"`
+	Operator  string   `cfg:"operator" default:"equals" validate:"oneof=equals exists in regex"`
+	Value     string   `cfg:"value"`
+	Values    []string `cfg:"values"`
+}
+
+// A RouterRoute sends the messages matching all of its conditions to the output.
+type RouterRoute struct {
+	Output     string            `cfg:"output" validate:"required"`
+	Conditions []RouterCondition `cfg:"conditions"`
+}
+
+type RouterOutputSettings struct {
+	Routes []RouterRoute `cfg:"routes"`
+	// Default is the output of messages without a matching route. If there is no default,
+	// writing a message without a matching route fails.
+	Default string `cfg:"default"`
+}
+
+type routerPredicate func(attributes map[string]interface{}) bool
+
+type routerRoute struct {
+	output    string
+	predicate routerPredicate
+}
+
+type routerOutput struct {
+	outputs  map[string]Output
+	routes   []*routerRoute
+	settings *RouterOutputSettings
+}
+
+func newRouterOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := ConfigurableOutputKey(name)
+	settings := &RouterOutputSettings{}
+	config.UnmarshalKey(key, settings)
+
+	return NewRouterOutput(config, logger, settings)
+}
+
+// NewRouterOutput creates an output sending every message to the output of the first route it matches.
+func NewRouterOutput(config cfg.Config, logger mon.Logger, settings *RouterOutputSettings) Output {
+	outputs := make(map[string]Output)
+
+	for _, route := range settings.Routes {
+		outputs[route.Output] = ProvideConfigurableOutput(config, logger, route.Output)
+	}
+
+	if settings.Default != "" {
+		outputs[settings.Default] = ProvideConfigurableOutput(config, logger, settings.Default)
+	}
+
+	output, err := NewRouterOutputWithInterfaces(outputs, settings)
+
+	if err != nil {
+		logger.Fatal(err, "can not create router output")
+	}
+
+	return output
+}
+
+func NewRouterOutputWithInterfaces(outputs map[string]Output, settings *RouterOutputSettings) (Output, error) {
+	routes := make([]*routerRoute, len(settings.Routes))
+
+	for i, route := range settings.Routes {
+		if _, ok := outputs[route.Output]; !ok {
+			return nil, fmt.Errorf("there is no output %s for route %d", route.Output, i)
+		}
+
+		predicate, err := buildRouterPredicate(route.Conditions)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid conditions of route %d to %s: %w", i, route.Output, err)
+		}
+
+		routes[i] = &routerRoute{
+			output:    route.Output,
+			predicate: predicate,
+		}
+	}
+
+	if _, ok := outputs[settings.Default]; settings.Default != "" && !ok {
+		return nil, fmt.Errorf("there is no default output %s", settings.Default)
+	}
+
+	return &routerOutput{
+		outputs:  outputs,
+		routes:   routes,
+		settings: settings,
+	}, nil
+}
+
+func (o *routerOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+func (o *routerOutput) Write(ctx context.Context, batch []*Message) error {
+	var result error
+
+	names := make([]string, 0)
+	batches := make(map[string][]*Message)
+
+	for _, msg := range batch {
+		name, err := o.route(msg)
+
+		if err != nil {
+			result = multierror.Append(result, err)
+			continue
+		}
+
+		if _, ok := batches[name]; !ok {
+			names = append(names, name)
+		}
+
+		batches[name] = append(batches[name], msg)
+	}
+
+	for _, name := range names {
+		if err := o.outputs[name].Write(ctx, batches[name]); err != nil {
+			result = multierror.Append(result, fmt.Errorf("can not write %d messages to output %s: %w", len(batches[name]), name, err))
+		}
+	}
+
+	return result
+}
+
+func (o *routerOutput) route(msg *Message) (string, error) {
+	for _, route := range o.routes {
+		if route.predicate(msg.Attributes) {
+			return route.output, nil
+		}
+	}
+
+	if o.settings.Default != "" {
+		return o.settings.Default, nil
+	}
+
+	return "", fmt.Errorf("there is no route for the message with attributes %v", msg.Attributes)
+}
+
+func buildRouterPredicate(conditions []RouterCondition) (routerPredicate, error) {
+	predicates := make([]routerPredicate, len(conditions))
+
+	for i, condition := range conditions {
+		predicate, err := buildRouterConditionPredicate(condition)
+
+		if err != nil {
+			return nil, err
+		}
+
+		predicates[i] = predicate
+	}
+
+	return func(attributes map[string]interface{}) bool {
+		for _, predicate := range predicates {
+			if !predicate(attributes) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
+
+func buildRouterConditionPredicate(condition RouterCondition) (routerPredicate, error) {
+	attribute := func(attributes map[string]interface{}) (string, bool) {
+		value, ok := attributes[condition.Attribute]
+
+		if !ok {
+			return "", false
+		}
+
+		str, err := cast.ToStringE(value)
+
+		return str, err == nil
+	}
+
+	switch condition.Operator {
+	case RouterOperatorEquals, "":
+		return func(attributes map[string]interface{}) bool {
+			value, ok := attribute(attributes)
+
+			return ok && value == condition.Value
+		}, nil
+
+	case RouterOperatorExists:
+		return func(attributes map[string]interface{}) bool {
+			_, ok := attributes[condition.Attribute]
+
+			return ok
+		}, nil
+
+	case RouterOperatorIn:
+		values := make(map[string]bool, len(condition.Values))
+
+		for _, value := range condition.Values {
+			values[value] = true
+		}
+
+		return func(attributes map[string]interface{}) bool {
+			value, ok := attribute(attributes)
+
+			return ok && values[value]
+		}, nil
+
+	case RouterOperatorRegex:
+		regex, err := regexp.Compile(condition.Value)
+
+		if err != nil {
+			return nil, fmt.Errorf("can not compile regex of attribute %s: %w", condition.Attribute, err)
+		}
+
+		return func(attributes map[string]interface{}) bool {
+			value, ok := attribute(attributes)
+
+			return ok && regex.MatchString(value)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s for attribute %s", condition.Operator, condition.Attribute)
+}
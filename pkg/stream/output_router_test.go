@@ -0,0 +1,108 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRouterOutput_Write(t *testing.T) {
+	config := cfg.New()
+	err := config.Option(cfg.WithConfigMap(map[string]interface{}{
+		"stream": map[string]interface{}{
+			"output": map[string]interface{}{
+				"router": map[string]interface{}{
+					"type": "router",
+					"routes": []interface{}{
+						map[string]interface{}{
+							"output": "router-orders",
+							"conditions": []interface{}{
+								map[string]interface{}{"attribute": "modelId", "value": "shop.order"},
+								map[string]interface{}{"attribute": "type", "operator": "in", "values": []interface{}{"create", "update"}},
+							},
+						},
+						map[string]interface{}{
+							"output": "router-shop",
+							"conditions": []interface{}{
+								map[string]interface{}{"attribute": "modelId", "operator": "regex", "value": "^shop\\."},
+								map[string]interface{}{"attribute": "version", "operator": "exists"},
+							},
+						},
+					},
+					"default": "router-default",
+				},
+				"router-orders":  map[string]interface{}{"type": "inMemory"},
+				"router-shop":    map[string]interface{}{"type": "inMemory"},
+				"router-default": map[string]interface{}{"type": "inMemory"},
+			},
+		},
+	}))
+	assert.NoError(t, err)
+
+	logger := monMocks.NewLoggerMockedAll()
+	output := stream.NewConfigurableOutput(config, logger, "router")
+
+	create := stream.NewMessage("create", map[string]interface{}{"modelId": "shop.order", "type": "create", "version": 1})
+	remove := stream.NewMessage("delete", map[string]interface{}{"modelId": "shop.order", "type": "delete", "version": 1})
+	unversioned := stream.NewMessage("unversioned", map[string]interface{}{"modelId": "shop.order", "type": "delete"})
+	other := stream.NewMessage("other", map[string]interface{}{"modelId": "blog.post", "type": "create", "version": 2.0})
+
+	err = output.Write(context.Background(), []*stream.Message{create, remove, unversioned, other})
+	assert.NoError(t, err)
+
+	assertRouted := func(name string, expected ...*stream.Message) {
+		out := stream.ProvideInMemoryOutput(name)
+		assert.Equal(t, len(expected), out.Len(), "output %s", name)
+
+		for i, msg := range expected {
+			written, _ := out.Get(i)
+			assert.Same(t, msg, written, "output %s", name)
+		}
+	}
+
+	assertRouted("router-orders", create)
+	assertRouted("router-shop", remove)
+	assertRouted("router-default", unversioned, other)
+}
+
+func TestRouterOutput_WithoutDefault(t *testing.T) {
+	orders := stream.ProvideInMemoryOutput("router-without-default")
+
+	output, err := stream.NewRouterOutputWithInterfaces(map[string]stream.Output{"orders": orders}, &stream.RouterOutputSettings{
+		Routes: []stream.RouterRoute{
+			{
+				Output: "orders",
+				Conditions: []stream.RouterCondition{
+					{Attribute: "version", Operator: stream.RouterOperatorEquals, Value: "2"},
+				},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	err = output.Write(context.Background(), []*stream.Message{
+		stream.NewMessage("v1", map[string]interface{}{"version": 1}),
+		stream.NewMessage("v2", map[string]interface{}{"version": 2.0}),
+	})
+
+	assert.EqualError(t, err, "1 error occurred:\n\t* there is no route for the message with attributes map[version:1]\n\n")
+	assert.Equal(t, 1, orders.Len())
+
+	_, err = stream.NewRouterOutputWithInterfaces(map[string]stream.Output{}, &stream.RouterOutputSettings{
+		Routes: []stream.RouterRoute{{Output: "missing"}},
+	})
+	assert.EqualError(t, err, "there is no output missing for route 0")
+
+	_, err = stream.NewRouterOutputWithInterfaces(map[string]stream.Output{"orders": orders}, &stream.RouterOutputSettings{
+		Routes: []stream.RouterRoute{
+			{
+				Output:     "orders",
+				Conditions: []stream.RouterCondition{{Attribute: "type", Operator: stream.RouterOperatorRegex, Value: "("}},
+			},
+		},
+	})
+	assert.EqualError(t, err, "invalid conditions of route 0 to orders: can not compile regex of attribute type: error parsing regexp: missing closing ): `(`")
+}
@@ -0,0 +1,201 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/kernel"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/twinj/uuid"
+	"sync"
+	"time"
+)
+
+type SchedulerOutputSettings struct {
+	// Threshold is the delay up to which messages are written to the target output immediately, e.g. 15m for sqs
+	// outputs which apply these delays themselves
+	Threshold time.Duration
+	// Interval is the time between two checks for due messages
+	Interval time.Duration
+	// BatchSize is the number of due messages released at once
+	BatchSize int
+	// Lease is the time an instance has to write the due messages it claimed before another instance may claim them
+	Lease time.Duration
+}
+
+// A schedulerOutput delays the delivery of the messages with a deliverAt attribute. Messages which are due later
+// than the threshold are stored and written to the target output by the kernel module of the output once they
+// are due. A message is removed from the store only after it was written, so it is delivered at least once,
+// even across restarts. Every instance of an application releases the due messages, so a message is claimed for
+// the lease before it is written and only the instance holding the claim writes it.
+type schedulerOutput struct {
+	kernel.BackgroundModule
+	kernel.EssentialStage
+
+	logger   mon.Logger
+	clock    clock.Clock
+	store    SchedulerStore
+	target   Output
+	settings *SchedulerOutputSettings
+}
+
+var schedulerOutputs = struct {
+	sync.Mutex
+	instances map[string]*schedulerOutput
+}{
+	instances: map[string]*schedulerOutput{},
+}
+
+// ProvideSchedulerOutput returns the scheduler output with the given name, which is shared by the producers writing
+// to it and the kernel module releasing the due messages.
+func ProvideSchedulerOutput(config cfg.Config, logger mon.Logger, name string) *schedulerOutput {
+	schedulerOutputs.Lock()
+	defer schedulerOutputs.Unlock()
+
+	if output, ok := schedulerOutputs.instances[name]; ok {
+		return output
+	}
+
+	configuration := readSchedulerOutputConfiguration(config, name)
+
+	store := NewSchedulerStore(config, logger, name, &configuration.Store)
+	target := NewConfigurableOutput(config, logger, configuration.Output)
+
+	schedulerOutputs.instances[name] = NewSchedulerOutputWithInterfaces(logger, clock.NewRealClock(), store, target, &SchedulerOutputSettings{
+		Threshold: configuration.Threshold,
+		Interval:  configuration.Interval,
+		BatchSize: configuration.BatchSize,
+		Lease:     configuration.Lease,
+	})
+
+	return schedulerOutputs.instances[name]
+}
+
+// SchedulerOutputModuleFactory creates the kernel modules of all configured scheduler outputs.
+func SchedulerOutputModuleFactory(config cfg.Config, logger mon.Logger) (map[string]kernel.Module, error) {
+	modules := make(map[string]kernel.Module)
+	outputs := config.GetStringMap("stream.output", map[string]interface{}{})
+
+	for name := range outputs {
+		key := fmt.Sprintf("%s.type", ConfigurableOutputKey(name))
+
+		if config.GetString(key) != OutputTypeScheduler {
+			continue
+		}
+
+		moduleName := fmt.Sprintf("scheduler-output-%s", name)
+		modules[moduleName] = ProvideSchedulerOutput(config, logger, name)
+	}
+
+	return modules, nil
+}
+
+func NewSchedulerOutputWithInterfaces(logger mon.Logger, clock clock.Clock, store SchedulerStore, target Output, settings *SchedulerOutputSettings) *schedulerOutput {
+	return &schedulerOutput{
+		logger:   logger.WithChannel("scheduler-output"),
+		clock:    clock,
+		store:    store,
+		target:   target,
+		settings: settings,
+	}
+}
+
+func (o *schedulerOutput) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (o *schedulerOutput) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			if err := o.Release(ctx); err != nil {
+				o.logger.Error(err, "can not release the due messages")
+			}
+		}
+	}
+}
+
+func (o *schedulerOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+func (o *schedulerOutput) Write(ctx context.Context, batch []*Message) error {
+	due := make([]*Message, 0, len(batch))
+	threshold := o.clock.Now().Add(o.settings.Threshold)
+
+	for _, msg := range batch {
+		deliverAt, ok := msg.GetDeliverAt()
+
+		if !ok || !deliverAt.After(threshold) {
+			due = append(due, msg)
+			continue
+		}
+
+		scheduled := &ScheduledMessage{
+			Id:        uuid.NewV4().String(),
+			DeliverAt: deliverAt,
+			Message:   msg,
+		}
+
+		if err := o.store.Add(ctx, scheduled); err != nil {
+			return fmt.Errorf("can not schedule the message: %w", err)
+		}
+	}
+
+	if len(due) == 0 {
+		return nil
+	}
+
+	return o.target.Write(ctx, due)
+}
+
+// Release writes all messages which are due within the threshold to the target output.
+func (o *schedulerOutput) Release(ctx context.Context) error {
+	for {
+		scheduled, err := o.store.Due(ctx, o.clock.Now().Add(o.settings.Threshold), o.settings.BatchSize)
+
+		if err != nil {
+			return err
+		}
+
+		if len(scheduled) == 0 {
+			return nil
+		}
+
+		// the messages claimed by other instances are released by them
+		claimed, err := o.store.Claim(ctx, scheduled, o.clock.Now(), o.settings.Lease)
+
+		if err != nil {
+			return fmt.Errorf("can not claim the due messages: %w", err)
+		}
+
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		batch := make([]*Message, len(claimed))
+
+		for i, msg := range claimed {
+			batch[i] = msg.Message
+		}
+
+		if err := o.target.Write(ctx, batch); err != nil {
+			return fmt.Errorf("can not write the due messages to the target output: %w", err)
+		}
+
+		if err := o.store.Remove(ctx, claimed); err != nil {
+			return fmt.Errorf("can not remove the released messages: %w", err)
+		}
+
+		if len(scheduled) < o.settings.BatchSize {
+			return nil
+		}
+	}
+}
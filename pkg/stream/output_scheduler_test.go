@@ -0,0 +1,279 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/alicebob/miniredis"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/ddb"
+	ddbMocks "github.com/applike/gosoline/pkg/ddb/mocks"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/exec"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/redis"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func newSchedulerRedisStore(t *testing.T) (stream.SchedulerStore, *miniredis.Miniredis) {
+	server, err := miniredis.Run()
+	assert.NoError(t, err)
+
+	baseClient := baseRedis.NewClient(&baseRedis.Options{
+		Addr: server.Addr(),
+	})
+	client := redis.NewClientWithInterfaces(monMocks.NewLoggerMockedAll(), baseClient, exec.NewDefaultExecutor(), &redis.Settings{})
+
+	return stream.NewSchedulerRedisStoreWithInterfaces(client, cfg.AppId{}, "test"), server
+}
+
+func TestSchedulerOutput_WriteAndRelease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 4, 21, 13, 0, 0, 0, time.UTC)
+	clk := clock.NewFakeClockAt(now)
+	target := stream.ProvideInMemoryOutput("scheduler-target")
+
+	store, server := newSchedulerRedisStore(t)
+	defer server.Close()
+
+	output := stream.NewSchedulerOutputWithInterfaces(monMocks.NewLoggerMockedAll(), clk, store, target, &stream.SchedulerOutputSettings{
+		Threshold: 15 * time.Minute,
+		Interval:  time.Second,
+		BatchSize: 2,
+		Lease:     time.Minute,
+	})
+
+	err := output.Write(ctx, []*stream.Message{
+		stream.NewMessage("immediately"),
+		stream.NewMessage("in 10 minutes", stream.DeliverAt(now.Add(10*time.Minute))),
+		stream.NewMessage("in 3 hours", stream.DeliverAt(now.Add(3*time.Hour))),
+		stream.NewMessage("in 1 hour", stream.DeliverAt(now.Add(time.Hour))),
+		stream.NewMessage("in 2 hours", stream.DeliverAt(now.Add(2*time.Hour))),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"immediately", "in 10 minutes"}, inMemoryBodies(target), "messages due within the threshold should be written immediately")
+
+	clk.Advance(50 * time.Minute)
+	assert.NoError(t, output.Release(ctx))
+	assert.Equal(t, []string{"immediately", "in 10 minutes", "in 1 hour"}, inMemoryBodies(target))
+
+	clk.Advance(3 * time.Hour)
+	assert.NoError(t, output.Release(ctx))
+	assert.Equal(t, []string{"immediately", "in 10 minutes", "in 1 hour", "in 2 hours", "in 3 hours"}, inMemoryBodies(target))
+
+	assert.NoError(t, output.Release(ctx))
+	assert.Equal(t, 5, target.Len(), "released messages should be removed from the store")
+}
+
+func TestSchedulerOutput_Release_TargetFailure(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 4, 21, 13, 0, 0, 0, time.UTC)
+
+	scheduled := []*stream.ScheduledMessage{
+		{
+			Id:        "1",
+			DeliverAt: now,
+			Message:   stream.NewMessage("due"),
+		},
+	}
+
+	store := new(streamMocks.SchedulerStore)
+	store.On("Due", ctx, now, 10).Return(scheduled, nil)
+	store.On("Claim", ctx, scheduled, now, time.Minute).Return(scheduled, nil)
+
+	target := new(streamMocks.Output)
+	target.On("Write", ctx, mock.Anything).Return(fmt.Errorf("target is down"))
+
+	output := stream.NewSchedulerOutputWithInterfaces(monMocks.NewLoggerMockedAll(), clock.NewFakeClockAt(now), store, target, &stream.SchedulerOutputSettings{
+		Interval:  time.Second,
+		BatchSize: 10,
+		Lease:     time.Minute,
+	})
+
+	err := output.Release(ctx)
+	assert.Error(t, err)
+
+	// the message is not removed from the store, so it is released again with the next attempt
+	store.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything)
+	store.AssertExpectations(t)
+}
+
+func TestSchedulerOutput_Release_MultipleInstances(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 4, 21, 13, 0, 0, 0, time.UTC)
+	clk := clock.NewFakeClockAt(now)
+
+	store, server := newSchedulerRedisStore(t)
+	defer server.Close()
+
+	settings := &stream.SchedulerOutputSettings{
+		Interval:  time.Second,
+		BatchSize: 10,
+		Lease:     time.Minute,
+	}
+
+	failing := new(streamMocks.Output)
+	failing.On("Write", ctx, mock.Anything).Return(fmt.Errorf("target is down"))
+
+	target := stream.ProvideInMemoryOutput("scheduler-target-multiple-instances")
+	crashed := stream.NewSchedulerOutputWithInterfaces(monMocks.NewLoggerMockedAll(), clk, store, failing, settings)
+	first := stream.NewSchedulerOutputWithInterfaces(monMocks.NewLoggerMockedAll(), clk, store, target, settings)
+	second := stream.NewSchedulerOutputWithInterfaces(monMocks.NewLoggerMockedAll(), clk, store, target, settings)
+
+	err := first.Write(ctx, []*stream.Message{
+		stream.NewMessage("in 1 hour", stream.DeliverAt(now.Add(time.Hour))),
+		stream.NewMessage("in 2 hours", stream.DeliverAt(now.Add(2*time.Hour))),
+	})
+	assert.NoError(t, err)
+
+	clk.Advance(time.Hour)
+	assert.Error(t, crashed.Release(ctx))
+	assert.NoError(t, first.Release(ctx))
+	assert.NoError(t, second.Release(ctx))
+	assert.Empty(t, inMemoryBodies(target), "the message claimed by the failed instance should not be released before its lease ended")
+
+	clk.Advance(time.Hour)
+	server.FastForward(time.Minute)
+	assert.NoError(t, first.Release(ctx))
+	assert.NoError(t, second.Release(ctx))
+	assert.Equal(t, []string{"in 1 hour", "in 2 hours"}, inMemoryBodies(target), "every message should be released exactly once")
+}
+
+func inMemoryBodies(output *stream.InMemoryOutput) []string {
+	bodies := make([]string, 0, output.Len())
+
+	for i := 0; i < output.Len(); i++ {
+		msg, _ := output.Get(i)
+		bodies = append(bodies, msg.Body)
+	}
+
+	return bodies
+}
+
+// schedulerDdbTestItem mirrors the item of the ddb scheduler store, which is converted by its json representation
+type schedulerDdbTestItem struct {
+	Scheduler string `json:"scheduler"`
+	Key       string `json:"key"`
+	Message   string `json:"message,omitempty"`
+}
+
+func convertSchedulerDdbItems(t *testing.T, in interface{}, out interface{}) {
+	data, err := json.Marshal(in)
+	assert.NoError(t, err)
+
+	err = json.Unmarshal(data, out)
+	assert.NoError(t, err)
+}
+
+func TestSchedulerDdbStore_Shards(t *testing.T) {
+	ctx := context.Background()
+	shards := map[string][]schedulerDdbTestItem{}
+	hash := ""
+
+	qb := new(ddbMocks.QueryBuilder)
+	qb.On("WithHash", mock.Anything).Run(func(args mock.Arguments) {
+		hash = args.String(0)
+	}).Return(qb)
+	qb.On("WithRangeLt", mock.Anything).Return(qb)
+	qb.On("WithLimit", 5).Return(qb)
+
+	repository := new(ddbMocks.Repository)
+	repository.On("QueryBuilder").Return(qb)
+	repository.On("PutItem", ctx, nil, mock.Anything).Run(func(args mock.Arguments) {
+		item := schedulerDdbTestItem{}
+		convertSchedulerDdbItems(t, args.Get(2), &item)
+		shards[item.Scheduler] = append(shards[item.Scheduler], item)
+	}).Return(nil, nil)
+	repository.On("Query", ctx, qb, mock.Anything).Run(func(args mock.Arguments) {
+		convertSchedulerDdbItems(t, shards[hash], args.Get(2))
+	}).Return(nil, nil)
+
+	deleted := make([]schedulerDdbTestItem, 0)
+	repository.On("BatchDeleteItems", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		convertSchedulerDdbItems(t, args.Get(1), &deleted)
+	}).Return(nil, nil)
+
+	store := stream.NewSchedulerDdbStoreWithInterfaces(repository, "test", 4)
+	deliverAt := time.Unix(1587474000, 0)
+
+	for i := 0; i < 20; i++ {
+		err := store.Add(ctx, &stream.ScheduledMessage{
+			Id:        fmt.Sprintf("msg-%02d", i),
+			DeliverAt: deliverAt.Add(time.Duration(i) * time.Second),
+			Message:   stream.NewMessage(fmt.Sprintf("%d", i)),
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.Greater(t, len(shards), 1, "the messages should be spread over the shards")
+
+	for name := range shards {
+		assert.Regexp(t, `^test-[0-3]$`, name)
+	}
+
+	due, err := store.Due(ctx, deliverAt.Add(time.Minute), 5)
+	assert.NoError(t, err)
+
+	ids := make([]string, len(due))
+
+	for i, msg := range due {
+		ids[i] = msg.Id
+	}
+
+	assert.Equal(t, []string{"msg-00", "msg-01", "msg-02", "msg-03", "msg-04"}, ids, "the messages due first of all shards should be returned")
+
+	err = store.Remove(ctx, due)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 5)
+
+	for _, item := range deleted {
+		keys := make([]string, 0)
+
+		for _, stored := range shards[item.Scheduler] {
+			keys = append(keys, stored.Key)
+		}
+
+		assert.Contains(t, keys, item.Key, "the message should be deleted from the shard it was stored in")
+	}
+}
+
+func TestSchedulerDdbStore_Claim(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1587474000, 0)
+
+	msgs := []*stream.ScheduledMessage{
+		{
+			Id:        "claimed-by-someone-else",
+			DeliverAt: now,
+			Message:   stream.NewMessage("1"),
+		},
+		{
+			Id:        "unclaimed",
+			DeliverAt: now,
+			Message:   stream.NewMessage("2"),
+		},
+	}
+
+	ub := new(ddbMocks.UpdateItemBuilder)
+	ub.On("Set", "claimedUntil", now.Add(time.Minute).Unix()).Return(ub)
+	ub.On("WithCondition", mock.Anything).Return(ub)
+
+	repository := new(ddbMocks.Repository)
+	repository.On("UpdateItemBuilder").Return(ub)
+	repository.On("UpdateItem", ctx, ub, mock.Anything).Return(&ddb.UpdateItemResult{ConditionalCheckFailed: true}, nil).Once()
+	repository.On("UpdateItem", ctx, ub, mock.Anything).Return(&ddb.UpdateItemResult{}, nil).Once()
+
+	store := stream.NewSchedulerDdbStoreWithInterfaces(repository, "test", 4)
+
+	claimed, err := store.Claim(ctx, msgs, now, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, msgs[1:], claimed, "only the messages without a claim should be claimed")
+
+	repository.AssertExpectations(t)
+}
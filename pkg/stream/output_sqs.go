@@ -13,6 +13,8 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/thoas/go-funk"
+	"math"
+	"time"
 )
 
 const sqsOutputBatchSize = 10
@@ -25,13 +27,19 @@ type SqsOutputSettings struct {
 	RedrivePolicy     sqs.RedrivePolicy
 	Client            cloud.ClientSettings
 	Backoff           exec.BackoffSettings
+	// MessageGroupId and MessageDeduplicationId are only used for fifo queues and
+	// if the message has no sqsMessageGroupId or sqsMessageDeduplicationId attribute
+	MessageGroupId         PartitionKeySettings
+	MessageDeduplicationId PartitionKeySettings
 }
 
 type sqsOutput struct {
-	logger   mon.Logger
-	tracer   tracing.Tracer
-	queue    sqs.Queue
-	settings SqsOutputSettings
+	logger          mon.Logger
+	tracer          tracing.Tracer
+	queue           sqs.Queue
+	groupId         *partitionKeyBuilder
+	deduplicationId *partitionKeyBuilder
+	settings        SqsOutputSettings
 }
 
 func NewSqsOutput(config cfg.Config, logger mon.Logger, s SqsOutputSettings) Output {
@@ -54,10 +62,12 @@ func NewSqsOutput(config cfg.Config, logger mon.Logger, s SqsOutputSettings) Out
 
 func NewSqsOutputWithInterfaces(logger mon.Logger, tracer tracing.Tracer, queue sqs.Queue, s SqsOutputSettings) Output {
 	return &sqsOutput{
-		logger:   logger,
-		tracer:   tracer,
-		queue:    queue,
-		settings: s,
+		logger:          logger,
+		tracer:          tracer,
+		queue:           queue,
+		groupId:         newPartitionKeyBuilder(s.MessageGroupId, PartitionKeyStrategyNone),
+		deduplicationId: newPartitionKeyBuilder(s.MessageDeduplicationId, PartitionKeyStrategyNone),
+		settings:        s,
 	}
 }
 
@@ -130,6 +140,7 @@ func (o *sqsOutput) buildSqsMessages(ctx context.Context, messages []*Message) (
 }
 
 func (o *sqsOutput) buildSqsMessage(ctx context.Context, msg *Message) (*sqs.Message, error) {
+	var err error
 	var delay *int64
 	var messageGroupId *string
 	var messageDeduplicationId *string
@@ -142,6 +153,12 @@ func (o *sqsOutput) buildSqsMessage(ctx context.Context, msg *Message) (*sqs.Mes
 		}
 	}
 
+	if deliverAt, ok := msg.GetDeliverAt(); ok && delay == nil {
+		if delay, err = sqsDelaySeconds(deliverAt); err != nil {
+			return nil, err
+		}
+	}
+
 	if d, ok := msg.Attributes[sqs.AttributeSqsMessageGroupId]; ok {
 		if groupIdString, ok := d.(string); ok {
 			messageGroupId = mdl.String(groupIdString)
@@ -158,6 +175,18 @@ func (o *sqsOutput) buildSqsMessage(ctx context.Context, msg *Message) (*sqs.Mes
 		}
 	}
 
+	if o.settings.Fifo.Enabled && messageGroupId == nil {
+		if messageGroupId, err = o.groupId.Build(msg); err != nil {
+			return nil, fmt.Errorf("can not build message group id: %w", err)
+		}
+	}
+
+	if o.settings.Fifo.Enabled && messageDeduplicationId == nil {
+		if messageDeduplicationId, err = o.deduplicationId.Build(msg); err != nil {
+			return nil, fmt.Errorf("can not build message deduplication id: %w", err)
+		}
+	}
+
 	if o.settings.Fifo.ContentBasedDeduplication && messageDeduplicationId == nil {
 		o.logger.WithContext(ctx).WithFields(mon.Fields{
 			"stacktrace": mon.GetStackTrace(0),
@@ -179,3 +208,19 @@ func (o *sqsOutput) buildSqsMessage(ctx context.Context, msg *Message) (*sqs.Mes
 
 	return sqsMessage, nil
 }
+
+// sqsDelaySeconds returns the delay of a message which should be delivered at the given time. It fails if the delay
+// exceeds the maximum delay of sqs, as these messages have to be written to a scheduler output instead.
+func sqsDelaySeconds(deliverAt time.Time) (*int64, error) {
+	delay := int64(math.Ceil(time.Until(deliverAt).Seconds()))
+
+	if delay <= 0 {
+		return nil, nil
+	}
+
+	if delay > sqs.MaxDelaySeconds {
+		return nil, fmt.Errorf("the delivery of the message is delayed by %d seconds, which is longer than the maximum delay of %d seconds of sqs: use a scheduler output instead", delay, sqs.MaxDelaySeconds)
+	}
+
+	return mdl.Int64(delay), nil
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/applike/gosoline/pkg/stream"
 	"github.com/applike/gosoline/pkg/tracing"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func TestSqsOutput_WriteOne(t *testing.T) {
@@ -108,3 +110,65 @@ func TestSqsOutput_WriteOne(t *testing.T) {
 		})
 	}
 }
+
+func TestSqsOutput_WriteOne_Fifo(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	tracer := tracing.NewNoopTracer()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("SendBatch", context.Background(), []*sqs.Message{
+		{
+			MessageGroupId:         mdl.String("user-1"),
+			MessageDeduplicationId: mdl.String("e3efd8637bdd1c1a5ee4d5ca163fa6ba8ab279b189621894a2731aefd672cdff"),
+			Body:                   mdl.String(`{"attributes":{"encoding":"application/json","partitionKey":"user-1"},"body":"{\"Foo\":\"bar\"}"}`),
+		},
+	}).Return(nil)
+
+	msg, err := stream.MarshalJsonMessage(map[string]string{"Foo": "bar"}, map[string]interface{}{
+		stream.AttributePartitionKey: "user-1",
+	})
+	assert.NoError(t, err)
+
+	output := stream.NewSqsOutputWithInterfaces(logger, tracer, queue, stream.SqsOutputSettings{
+		Fifo: sqs.FifoSettings{
+			Enabled: true,
+		},
+		MessageGroupId: stream.PartitionKeySettings{
+			Strategy: stream.PartitionKeyStrategyAttribute,
+		},
+		MessageDeduplicationId: stream.PartitionKeySettings{
+			Strategy: stream.PartitionKeyStrategyContent,
+		},
+	})
+	err = output.WriteOne(context.Background(), msg)
+
+	assert.NoError(t, err)
+
+	queue.AssertExpectations(t)
+}
+
+func TestSqsOutput_WriteOne_DeliverAt(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	tracer := tracing.NewNoopTracer()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("SendBatch", context.Background(), mock.MatchedBy(func(messages []*sqs.Message) bool {
+		return len(messages) == 1 && messages[0].DelaySeconds != nil && *messages[0].DelaySeconds == 120
+	})).Return(nil).Once()
+	queue.On("SendBatch", context.Background(), mock.MatchedBy(func(messages []*sqs.Message) bool {
+		return len(messages) == 1 && messages[0].DelaySeconds == nil
+	})).Return(nil).Once()
+
+	output := stream.NewSqsOutputWithInterfaces(logger, tracer, queue, stream.SqsOutputSettings{})
+
+	err := output.WriteOne(context.Background(), stream.NewMessage("delayed", stream.DeliverAfter(2*time.Minute)))
+	assert.NoError(t, err)
+
+	err = output.WriteOne(context.Background(), stream.NewMessage("due", stream.DeliverAt(time.Now().Add(-time.Minute))))
+	assert.NoError(t, err)
+
+	err = output.WriteOne(context.Background(), stream.NewMessage("too late", stream.DeliverAfter(time.Hour)))
+	assert.Error(t, err, "delays longer than 15 minutes can't be applied by sqs")
+
+	queue.AssertExpectations(t)
+}
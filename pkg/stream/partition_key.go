@@ -0,0 +1,155 @@
+package stream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/cast"
+	"github.com/twinj/uuid"
+)
+
+const (
+	AttributePartitionKey    = "partitionKey"
+	AttributeExplicitHashKey = "explicitHashKey"
+
+	// An empty strategy selects the default of the output: random for kinesis, none for sqs.
+	PartitionKeyStrategyNone      = "none"
+	PartitionKeyStrategyRandom    = "random"
+	PartitionKeyStrategyAttribute = "attribute"
+	PartitionKeyStrategyHashed    = "hashed"
+	PartitionKeyStrategyContent   = "content"
+)
+
+// A PartitionKeyed model provides the partition key of its messages. The key is stored
+// in the partitionKey attribute when the model is encoded by a producer.
+type PartitionKeyed interface {
+	GetPartitionKey() string
+}
+
+// PartitionKeySettings define how the key of a message is built. With the attribute strategy
+// the key is read from the configured attribute and the hashed strategy uses the sha256 of this
+// attribute, so all messages of an entity get the same key of a fixed length. The content strategy
+// uses the sha256 of the message body, e.g. as deduplication id, and the random strategy creates
+// a new uuid for every message.
+type PartitionKeySettings struct {
+	Strategy  string `cfg:"strategy"`
+	Attribute string `cfg:"attribute" default:"partitionKey"`
+}
+
+type partitionKeyBuilder struct {
+	strategy  string
+	attribute string
+}
+
+func newPartitionKeyBuilder(settings PartitionKeySettings, defaultStrategy string) *partitionKeyBuilder {
+	builder := &partitionKeyBuilder{
+		strategy:  settings.Strategy,
+		attribute: settings.Attribute,
+	}
+
+	if builder.strategy == "" {
+		builder.strategy = defaultStrategy
+	}
+
+	if builder.attribute == "" {
+		builder.attribute = AttributePartitionKey
+	}
+
+	return builder
+}
+
+// Build returns the key of the message or nil if the strategy doesn't provide any key.
+func (b *partitionKeyBuilder) Build(msg *Message) (*string, error) {
+	var key string
+
+	switch b.strategy {
+	case PartitionKeyStrategyNone:
+		return nil, nil
+
+	case PartitionKeyStrategyRandom:
+		key = uuid.NewV4().String()
+
+	case PartitionKeyStrategyAttribute:
+		attribute, err := b.attributeValue(msg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key = attribute
+
+	case PartitionKeyStrategyHashed:
+		attribute, err := b.attributeValue(msg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key = sha256Hex(attribute)
+
+	case PartitionKeyStrategyContent:
+		key = sha256Hex(msg.Body)
+
+	default:
+		return nil, fmt.Errorf("unknown partition key strategy %s", b.strategy)
+	}
+
+	return &key, nil
+}
+
+// attributeValue returns the key attribute as string. Like the conditions of the router output,
+// it accepts every attribute which can be cast to a string, e.g. int ids.
+func (b *partitionKeyBuilder) attributeValue(msg *Message) (string, error) {
+	value, ok := msg.Attributes[b.attribute]
+
+	if !ok {
+		return "", fmt.Errorf("the message has no attribute %s to use as key", b.attribute)
+	}
+
+	key, err := cast.ToStringE(value)
+
+	if err != nil {
+		return "", fmt.Errorf("can not use the attribute %s of type %T as key: %w", b.attribute, value, err)
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("the attribute %s to use as key is empty", b.attribute)
+	}
+
+	return key, nil
+}
+
+func sha256Hex(value string) string {
+	hash := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(hash[:])
+}
+
+type partitionKeyEncodeHandler struct{}
+
+// NewPartitionKeyEncodeHandler returns an encode handler which stores the partition key
+// of every PartitionKeyed model in the partitionKey attribute of the message.
+func NewPartitionKeyEncodeHandler() EncodeHandler {
+	return partitionKeyEncodeHandler{}
+}
+
+func (h partitionKeyEncodeHandler) Encode(ctx context.Context, data interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error) {
+	keyed, ok := data.(PartitionKeyed)
+
+	if !ok {
+		return ctx, attributes, nil
+	}
+
+	if _, ok := attributes[AttributePartitionKey]; ok {
+		return ctx, attributes, nil
+	}
+
+	attributes[AttributePartitionKey] = keyed.GetPartitionKey()
+
+	return ctx, attributes, nil
+}
+
+func (h partitionKeyEncodeHandler) Decode(ctx context.Context, _ interface{}, attributes map[string]interface{}) (context.Context, map[string]interface{}, error) {
+	return ctx, attributes, nil
+}
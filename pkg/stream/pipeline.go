@@ -2,16 +2,30 @@ package stream
 
 import (
 	"context"
+	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
 	"github.com/applike/gosoline/pkg/coffin"
 	"github.com/applike/gosoline/pkg/kernel"
 	"github.com/applike/gosoline/pkg/mon"
+	"strings"
 	"sync"
 	"time"
 )
 
-const MetricNamePipelineReceivedCount = "PipelineReceivedCount"
-const MetricNamePipelineProcessedCount = "PipelineProcessedCount"
+const (
+	MetricNamePipelineReceivedCount   = "PipelineReceivedCount"
+	MetricNamePipelineProcessedCount  = "PipelineProcessedCount"
+	MetricNamePipelineStageLatency    = "PipelineStageLatency"
+	MetricNamePipelineStageErrorCount = "PipelineStageErrorCount"
+
+	// PipelineErrorPolicyAbort neither writes nor acknowledges the batch, so the input redelivers it later
+	PipelineErrorPolicyAbort = "abort"
+	// PipelineErrorPolicyDrop acknowledges and removes the failing messages, the rest of the batch continues
+	PipelineErrorPolicyDrop = "drop"
+	// PipelineErrorPolicyDeadLetter writes the failing messages to the dead letter output before dropping them
+	PipelineErrorPolicyDeadLetter = "dead_letter"
+)
 
 //go:generate mockery -name PipelineCallback
 type PipelineCallback interface {
@@ -19,9 +33,48 @@ type PipelineCallback interface {
 	Process(ctx context.Context, messages []*Message) ([]*Message, error)
 }
 
+// PipelineMessageErrors is returned by a stage which failed to process only some messages of the batch.
+// The stage returns the messages it processed successfully, the failing ones are handled by the error
+// policy of the stage. Any other error of a stage counts as failure of the whole batch.
+type PipelineMessageErrors struct {
+	Messages []*Message
+	Errors   []error
+}
+
+func (e *PipelineMessageErrors) Add(msg *Message, err error) {
+	e.Messages = append(e.Messages, msg)
+	e.Errors = append(e.Errors, err)
+}
+
+func (e *PipelineMessageErrors) Len() int {
+	return len(e.Messages)
+}
+
+func (e *PipelineMessageErrors) Error() string {
+	errs := make([]string, len(e.Errors))
+
+	for i, err := range e.Errors {
+		errs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d messages failed: %s", len(e.Errors), strings.Join(errs, "; "))
+}
+
+type PipelineStageSettings struct {
+	Name        string `cfg:"name"`
+	ErrorPolicy string `cfg:"error_policy" validate:"omitempty,oneof=abort drop dead_letter"`
+}
+
 type PipelineSettings struct {
-	Interval  time.Duration
-	BatchSize int
+	Input      string        `cfg:"input"`
+	Output     string        `cfg:"output"`
+	DeadLetter string        `cfg:"dead_letter"`
+	Interval   time.Duration `cfg:"interval" default:"10s"`
+	BatchSize  int           `cfg:"batch_size" default:"100" validate:"min=1"`
+	// ErrorPolicy is used by all stages without an error policy of their own
+	ErrorPolicy string `cfg:"error_policy" default:"abort" validate:"oneof=abort drop dead_letter"`
+	// Stages are matched to the stages of the pipeline by their position
+	Stages []PipelineStageSettings `cfg:"stages"`
 }
 
 type Pipeline struct {
@@ -29,19 +82,24 @@ type Pipeline struct {
 	kernel.ApplicationStage
 	ConsumerAcknowledge
 
-	logger   mon.Logger
-	metric   mon.MetricWriter
-	cfn      coffin.Coffin
-	lck      sync.Mutex
-	output   Output
-	ticker   *time.Ticker
-	batch    []*Message
-	stages   []PipelineCallback
-	settings *PipelineSettings
+	name       string
+	logger     mon.Logger
+	clock      clock.Clock
+	metric     mon.MetricWriter
+	cfn        coffin.Coffin
+	lck        sync.Mutex
+	output     Output
+	deadLetter Output
+	ticker     *time.Ticker
+	batch      []*Message
+	stages     []PipelineCallback
+	settings   *PipelineSettings
 }
 
-func NewPipeline(stages ...PipelineCallback) *Pipeline {
+func NewPipeline(name string, stages ...PipelineCallback) *Pipeline {
 	return &Pipeline{
+		name:   name,
+		clock:  clock.NewRealClock(),
 		cfn:    coffin.New(),
 		stages: stages,
 	}
@@ -56,25 +114,55 @@ func (p *Pipeline) Boot(config cfg.Config, logger mon.Logger) error {
 		}
 	}
 
-	defaults := getDefaultPipelineMetrics()
+	settings := &PipelineSettings{}
+	config.UnmarshalKey(ConfigurablePipelineKey(p.name), settings)
+
+	if settings.Input == "" {
+		settings.Input = p.name
+	}
+
+	if settings.Output == "" {
+		settings.Output = p.name
+	}
+
+	logger = logger.WithChannel("pipeline").WithFields(mon.Fields{
+		"pipeline": p.name,
+	})
+
+	defaults := getDefaultPipelineMetrics(p.name)
+
+	for i := range p.stages {
+		defaults = append(defaults, getDefaultPipelineStageMetrics(p.name, p.stageSettings(settings, i).Name)...)
+	}
+
 	metric := mon.NewMetricDaemonWriter(defaults...)
 
-	input := NewConfigurableInput(config, logger, "pipeline")
-	output := NewConfigurableOutput(config, logger, "pipeline")
+	input := NewConfigurableInput(config, logger, settings.Input)
+	output := NewConfigurableOutput(config, logger, settings.Output)
+
+	var deadLetter Output
 
-	settings := &PipelineSettings{
-		Interval:  config.GetDuration("pipeline_interval") * time.Second,
-		BatchSize: config.GetInt("pipeline_batch_size"),
+	if settings.DeadLetter != "" {
+		deadLetter = NewConfigurableOutput(config, logger, settings.DeadLetter)
 	}
 
-	return p.BootWithInterfaces(logger, metric, input, output, settings)
+	return p.BootWithInterfaces(logger, metric, input, output, deadLetter, settings)
 }
 
-func (p *Pipeline) BootWithInterfaces(logger mon.Logger, metric mon.MetricWriter, input Input, output Output, settings *PipelineSettings) error {
+func (p *Pipeline) BootWithInterfaces(logger mon.Logger, metric mon.MetricWriter, input Input, output Output, deadLetter Output, settings *PipelineSettings) error {
+	for i := range p.stages {
+		policy := p.stageSettings(settings, i).ErrorPolicy
+
+		if policy == PipelineErrorPolicyDeadLetter && deadLetter == nil {
+			return fmt.Errorf("stage %d of pipeline %s uses the error policy %s, but there is no dead letter output", i, p.name, policy)
+		}
+	}
+
+	p.ConsumerAcknowledge = NewConsumerAcknowledgeWithInterfaces(logger, input)
 	p.logger = logger
 	p.metric = metric
-	p.input = input
 	p.output = output
+	p.deadLetter = deadLetter
 	p.ticker = time.NewTicker(settings.Interval)
 	p.batch = make([]*Message, 0, settings.BatchSize)
 	p.settings = settings
@@ -141,7 +229,10 @@ func (p *Pipeline) process(ctx context.Context, force bool) {
 
 	p.metric.WriteOne(&mon.MetricDatum{
 		MetricName: MetricNamePipelineReceivedCount,
-		Value:      float64(batchSize),
+		Dimensions: map[string]string{
+			"Pipeline": p.name,
+		},
+		Value: float64(batchSize),
 	})
 
 	defer func() {
@@ -159,17 +250,17 @@ func (p *Pipeline) process(ctx context.Context, force bool) {
 
 	p.ticker.Stop()
 
-	var err error
+	batch := p.batch
 
-	for _, stage := range p.stages {
-		p.batch, err = stage.Process(ctx, p.batch)
+	for i, stage := range p.stages {
+		var ok bool
 
-		if err != nil {
-			p.logger.Error(err, "could not process the batch")
+		if batch, ok = p.processStage(ctx, i, stage, batch); !ok {
+			return
 		}
 	}
 
-	err = p.output.Write(ctx, p.batch)
+	err := p.output.Write(ctx, batch)
 
 	if err != nil {
 		p.logger.Error(err, "could not write messages to output")
@@ -177,29 +268,148 @@ func (p *Pipeline) process(ctx context.Context, force bool) {
 		return
 	}
 
-	p.AcknowledgeBatch(ctx, p.batch)
+	p.AcknowledgeBatch(ctx, batch)
 
-	processedCount := len(p.batch)
+	processedCount := len(batch)
 
 	p.logger.Infof("pipeline processed %d of %d messages", processedCount, batchSize)
 	p.metric.WriteOne(&mon.MetricDatum{
 		MetricName: MetricNamePipelineProcessedCount,
-		Value:      float64(processedCount),
+		Dimensions: map[string]string{
+			"Pipeline": p.name,
+		},
+		Value: float64(processedCount),
+	})
+}
+
+// processStage runs a single stage and applies its error policy. It returns false if the batch has to be aborted.
+func (p *Pipeline) processStage(ctx context.Context, index int, stage PipelineCallback, batch []*Message) ([]*Message, bool) {
+	settings := p.stageSettings(p.settings, index)
+
+	start := p.clock.Now()
+	processed, err := stage.Process(ctx, batch)
+	latency := p.clock.Now().Sub(start)
+
+	p.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: MetricNamePipelineStageLatency,
+		Dimensions: map[string]string{
+			"Pipeline": p.name,
+			"Stage":    settings.Name,
+		},
+		Unit:  mon.UnitMilliseconds,
+		Value: float64(latency.Milliseconds()),
+	})
+
+	if err == nil {
+		return processed, true
+	}
+
+	failed := batch
+
+	if messageErrors, ok := err.(*PipelineMessageErrors); ok {
+		failed = messageErrors.Messages
+	} else {
+		processed = nil
+	}
+
+	p.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: MetricNamePipelineStageErrorCount,
+		Dimensions: map[string]string{
+			"Pipeline": p.name,
+			"Stage":    settings.Name,
+		},
+		Unit:  mon.UnitCount,
+		Value: float64(len(failed)),
 	})
+
+	switch settings.ErrorPolicy {
+	case PipelineErrorPolicyDrop:
+		p.logger.Warnf("stage %s dropped %d failing messages: %s", settings.Name, len(failed), err.Error())
+
+	case PipelineErrorPolicyDeadLetter:
+		if dlErr := p.deadLetter.Write(ctx, failed); dlErr != nil {
+			p.logger.Errorf(dlErr, "stage %s could not write %d failing messages to the dead letter output, aborting the batch: %s", settings.Name, len(failed), err.Error())
+			return nil, false
+		}
+
+		p.logger.Warnf("stage %s wrote %d failing messages to the dead letter output: %s", settings.Name, len(failed), err.Error())
+
+	default:
+		p.logger.Errorf(err, "stage %s could not process the batch, aborting it", settings.Name)
+		return nil, false
+	}
+
+	p.AcknowledgeBatch(ctx, failed)
+
+	return processed, true
 }
 
-func getDefaultPipelineMetrics() mon.MetricData {
+func (p *Pipeline) stageSettings(settings *PipelineSettings, index int) PipelineStageSettings {
+	stage := PipelineStageSettings{}
+
+	if index < len(settings.Stages) {
+		stage = settings.Stages[index]
+	}
+
+	if stage.Name == "" {
+		stage.Name = fmt.Sprintf("stage-%d", index)
+	}
+
+	if stage.ErrorPolicy == "" {
+		stage.ErrorPolicy = settings.ErrorPolicy
+	}
+
+	return stage
+}
+
+func ConfigurablePipelineKey(name string) string {
+	return fmt.Sprintf("stream.pipeline.%s", name)
+}
+
+func getDefaultPipelineMetrics(name string) mon.MetricData {
 	return mon.MetricData{
 		{
 			Priority:   mon.PriorityHigh,
 			MetricName: MetricNamePipelineReceivedCount,
-			Unit:       mon.UnitCount,
-			Value:      0.0,
+			Dimensions: map[string]string{
+				"Pipeline": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
 		}, {
 			Priority:   mon.PriorityHigh,
 			MetricName: MetricNamePipelineProcessedCount,
-			Unit:       mon.UnitCount,
-			Value:      0.0,
+			Dimensions: map[string]string{
+				"Pipeline": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+	}
+}
+
+func getDefaultPipelineStageMetrics(name string, stage string) mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNamePipelineStageLatency,
+			Dimensions: map[string]string{
+				"Pipeline": name,
+				"Stage":    stage,
+			},
+			Unit:  mon.UnitMilliseconds,
+			Value: 0.0,
+		}, {
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNamePipelineStageErrorCount,
+			Dimensions: map[string]string{
+				"Pipeline": name,
+				"Stage":    stage,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
 		},
 	}
 }
@@ -2,11 +2,14 @@ package stream_test
 
 import (
 	"context"
+	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/mon/mocks"
 	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"testing"
 	"time"
 )
@@ -36,9 +39,9 @@ func runPipelineWithSettings(t *testing.T, settings *stream.PipelineSettings, ct
 	output := stream.NewOutputMemory()
 
 	callback := &callback{}
-	pipe := stream.NewPipeline(callback)
+	pipe := stream.NewPipeline("test", callback)
 
-	err := pipe.BootWithInterfaces(logger, metric, input, output, settings)
+	err := pipe.BootWithInterfaces(logger, metric, input, output, nil, settings)
 	assert.NoError(t, err, "the pipeline should boot without an error")
 
 	err = pipe.Run(ctx)
@@ -57,3 +60,129 @@ func TestPipeline_RunBatchSize(t *testing.T) {
 		BatchSize: 1,
 	}, context.Background())
 }
+
+type failingStage struct {
+	failing string
+	batch   bool
+}
+
+func (s *failingStage) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (s *failingStage) Process(_ context.Context, messages []*stream.Message) ([]*stream.Message, error) {
+	if s.batch {
+		return nil, fmt.Errorf("stage failed")
+	}
+
+	processed := make([]*stream.Message, 0, len(messages))
+	errs := &stream.PipelineMessageErrors{}
+
+	for _, msg := range messages {
+		if msg.Body == s.failing {
+			errs.Add(msg, fmt.Errorf("can not process %s", msg.Body))
+			continue
+		}
+
+		processed = append(processed, msg)
+	}
+
+	if errs.Len() > 0 {
+		return processed, errs
+	}
+
+	return processed, nil
+}
+
+type acknowledgingInput struct {
+	*streamMocks.Input
+	acked *[][]*stream.Message
+}
+
+func (i *acknowledgingInput) Ack(msg *stream.Message) error {
+	return i.AckBatch([]*stream.Message{msg})
+}
+
+func (i *acknowledgingInput) AckBatch(msgs []*stream.Message) error {
+	*i.acked = append(*i.acked, msgs)
+	return nil
+}
+
+func runPipelineWithStages(t *testing.T, settings *stream.PipelineSettings, acked *[][]*stream.Message, stages ...stream.PipelineCallback) (output *stream.OutputMemory, deadLetter *stream.OutputMemory) {
+	data := make(chan *stream.Message, 4)
+	for _, body := range []string{"a", "b", "c", "d"} {
+		data <- stream.NewMessage(body)
+	}
+	close(data)
+
+	input := &acknowledgingInput{
+		Input: new(streamMocks.Input),
+		acked: acked,
+	}
+	input.On("Data").Return(data)
+	input.On("Run", mock.Anything).Return(nil)
+	input.On("Stop")
+
+	output = stream.NewOutputMemory()
+	deadLetter = stream.NewOutputMemory()
+
+	pipe := stream.NewPipeline("test", stages...)
+	err := pipe.BootWithInterfaces(mocks.NewLoggerMockedAll(), mocks.NewMetricWriterMockedAll(), input, output, deadLetter, settings)
+	assert.NoError(t, err, "the pipeline should boot without an error")
+
+	err = pipe.Run(context.Background())
+	assert.NoError(t, err, "the pipeline should run without an error")
+
+	return output, deadLetter
+}
+
+func TestPipeline_ErrorPolicies(t *testing.T) {
+	acked := make([][]*stream.Message, 0)
+
+	output, deadLetter := runPipelineWithStages(t, &stream.PipelineSettings{
+		Interval:    time.Hour,
+		BatchSize:   4,
+		ErrorPolicy: stream.PipelineErrorPolicyAbort,
+		Stages: []stream.PipelineStageSettings{
+			{Name: "drop", ErrorPolicy: stream.PipelineErrorPolicyDrop},
+			{Name: "dead-letter", ErrorPolicy: stream.PipelineErrorPolicyDeadLetter},
+		},
+	}, &acked, &failingStage{failing: "b"}, &failingStage{failing: "c"})
+
+	assert.Equal(t, 2, output.Size())
+	assert.True(t, output.ContainsBody("a"))
+	assert.True(t, output.ContainsBody("d"))
+
+	assert.Equal(t, 1, deadLetter.Size())
+	assert.True(t, deadLetter.ContainsBody("c"))
+
+	assert.Len(t, acked, 3, "the dropped, dead lettered and written messages should be acknowledged")
+	assert.Equal(t, "b", acked[0][0].Body)
+	assert.Equal(t, "c", acked[1][0].Body)
+	assert.Len(t, acked[2], 2)
+}
+
+func TestPipeline_ErrorPolicyAbort(t *testing.T) {
+	acked := make([][]*stream.Message, 0)
+
+	output, _ := runPipelineWithStages(t, &stream.PipelineSettings{
+		Interval:    time.Hour,
+		BatchSize:   4,
+		ErrorPolicy: stream.PipelineErrorPolicyAbort,
+	}, &acked, &failingStage{batch: true})
+
+	assert.Equal(t, 0, output.Size(), "an aborted batch should not be written")
+	assert.Len(t, acked, 0, "an aborted batch should not be acknowledged")
+}
+
+func TestPipeline_DeadLetterWithoutOutput(t *testing.T) {
+	pipe := stream.NewPipeline("test", &failingStage{})
+
+	err := pipe.BootWithInterfaces(mocks.NewLoggerMockedAll(), mocks.NewMetricWriterMockedAll(), new(streamMocks.Input), stream.NewOutputMemory(), nil, &stream.PipelineSettings{
+		Interval:    time.Hour,
+		BatchSize:   1,
+		ErrorPolicy: stream.PipelineErrorPolicyDeadLetter,
+	})
+
+	assert.EqualError(t, err, "stage 0 of pipeline test uses the error policy dead_letter, but there is no dead letter output")
+}
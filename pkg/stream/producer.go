@@ -9,9 +9,11 @@ import (
 )
 
 type ProducerSettings struct {
-	Output      string `cfg:"output"`
-	Encoding    string `cfg:"encoding"`
-	Compression string `cfg:"compression"`
+	Output      string                 `cfg:"output"`
+	Encoding    string                 `cfg:"encoding"`
+	Compression string                 `cfg:"compression"`
+	ClaimCheck  ClaimCheckSettings     `cfg:"claim_check"`
+	Daemon      ProducerDaemonSettings `cfg:"daemon"`
 }
 
 type Producer interface {
@@ -25,25 +27,35 @@ type producer struct {
 }
 
 func NewProducer(config cfg.Config, logger mon.Logger, name string, handlers ...EncodeHandler) *producer {
-	key := fmt.Sprintf("stream.producer.%s", name)
-
-	settings := &ProducerSettings{}
-	config.UnmarshalKey(key, settings)
-
-	if len(settings.Output) == 0 {
-		settings.Output = name
-	}
+	settings := readProducerSettings(config, name)
 
 	encodeHandlers := make([]EncodeHandler, 0, len(defaultEncodeHandlers)+len(handlers))
 	encodeHandlers = append(encodeHandlers, defaultEncodeHandlers...)
 	encodeHandlers = append(encodeHandlers, handlers...)
 
+	if settings.ClaimCheck.Enabled {
+		encodeHandlers = append(encodeHandlers, NewClaimCheckEncodeHandler(config, logger, &settings.ClaimCheck))
+	}
+
 	encoder := NewMessageEncoder(&MessageEncoderSettings{
 		Encoding:       settings.Encoding,
 		Compression:    settings.Compression,
 		EncodeHandlers: encodeHandlers,
 	})
-	output := NewConfigurableOutput(config, logger, settings.Output)
+
+	var output Output
+
+	if settings.Daemon.Enabled {
+		daemon := ProvideProducerDaemon(config, name)
+
+		if !isProducerDaemonModule(daemon) {
+			logger.Fatalf(fmt.Errorf("producer daemon %s is not running", name), "the daemon of producer %s is enabled, but not run by the kernel: the application has to use the producer daemon option", name)
+		}
+
+		output = daemon
+	} else {
+		output = NewConfigurableOutput(config, logger, settings.Output)
+	}
 
 	return NewProducerWithInterfaces(encoder, output)
 }
@@ -98,6 +110,19 @@ func (p *producer) Write(ctx context.Context, models interface{}, attributeSets
 	return nil
 }
 
+func readProducerSettings(config cfg.Config, name string) *ProducerSettings {
+	key := ConfigurableProducerKey(name)
+
+	settings := &ProducerSettings{}
+	config.UnmarshalKey(key, settings)
+
+	if len(settings.Output) == 0 {
+		settings.Output = name
+	}
+
+	return settings
+}
+
 func ConfigurableProducerKey(name string) string {
 	return fmt.Sprintf("stream.producer.%s", name)
 }
@@ -0,0 +1,323 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/exec"
+	"github.com/applike/gosoline/pkg/kernel"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/cenkalti/backoff"
+	"sync"
+	"time"
+)
+
+const (
+	MetricNameProducerDaemonBufferSize   = "ProducerDaemonBufferSize"
+	MetricNameProducerDaemonFlushLatency = "ProducerDaemonFlushLatency"
+)
+
+type ProducerDaemonSettings struct {
+	Enabled bool `cfg:"enabled" default:"false"`
+	// Interval after which the buffered messages are written even if the batch is not full yet
+	Interval time.Duration `cfg:"interval" default:"1s"`
+	// BufferSize is the number of messages which can be queued, writing to a full buffer blocks
+	BufferSize int `cfg:"buffer_size" default:"10000" validate:"min=1"`
+	// BatchSize is the maximum number of messages written to the output at once
+	BatchSize int `cfg:"batch_size" default:"10" validate:"min=1"`
+	// BatchMaxBytes is the maximum sum of the body sizes of a batch, 0 disables the limit
+	BatchMaxBytes int `cfg:"batch_max_bytes" default:"262144" validate:"min=0"`
+	// Backoff of the retries of a failed batch
+	Backoff exec.BackoffSettings `cfg:"backoff"`
+}
+
+// A producerDaemon decouples a producer from its output. Messages are queued in a buffer
+// and written in batches by the module, which lives in the essential stage of the kernel,
+// so it shuts down after the application modules stopped producing and drains the buffer
+// completely. Messages written after the daemon stopped are written to the output directly.
+type producerDaemon struct {
+	kernel.EssentialModule
+	kernel.EssentialStage
+
+	name     string
+	logger   mon.Logger
+	clock    clock.Clock
+	metric   mon.MetricWriter
+	output   Output
+	settings *ProducerDaemonSettings
+
+	lck     sync.RWMutex
+	buffer  chan *Message
+	stopped chan struct{}
+	batch   []*Message
+	bytes   int
+	aborted [][]*Message
+	module  bool
+}
+
+var producerDaemons = struct {
+	sync.Mutex
+	instances map[string]*producerDaemon
+}{
+	instances: map[string]*producerDaemon{},
+}
+
+// ProvideProducerDaemon returns the daemon of the producer with the given name, which is
+// shared by the producers writing to it and the kernel module running it.
+func ProvideProducerDaemon(config cfg.Config, name string) *producerDaemon {
+	producerDaemons.Lock()
+	defer producerDaemons.Unlock()
+
+	if daemon, ok := producerDaemons.instances[name]; ok {
+		return daemon
+	}
+
+	settings := readProducerSettings(config, name)
+	producerDaemons.instances[name] = NewProducerDaemon(name, &settings.Daemon)
+
+	return producerDaemons.instances[name]
+}
+
+// ProducerDaemonFactory creates the kernel modules of all producers with an enabled daemon.
+func ProducerDaemonFactory(config cfg.Config, _ mon.Logger) (map[string]kernel.Module, error) {
+	modules := make(map[string]kernel.Module)
+	producers := config.GetStringMap("stream.producer", map[string]interface{}{})
+
+	for name := range producers {
+		settings := readProducerSettings(config, name)
+
+		if !settings.Daemon.Enabled {
+			continue
+		}
+
+		daemon := ProvideProducerDaemon(config, name)
+		markProducerDaemonModule(daemon)
+
+		moduleName := fmt.Sprintf("producer-daemon-%s", name)
+		modules[moduleName] = daemon
+	}
+
+	return modules, nil
+}
+
+func markProducerDaemonModule(daemon *producerDaemon) {
+	producerDaemons.Lock()
+	defer producerDaemons.Unlock()
+
+	daemon.module = true
+}
+
+// isProducerDaemonModule reports whether the daemon is run by the kernel. A daemon which isn't run never
+// drains its buffer, so writing to it blocks forever once the buffer is full.
+func isProducerDaemonModule(daemon *producerDaemon) bool {
+	producerDaemons.Lock()
+	defer producerDaemons.Unlock()
+
+	return daemon.module
+}
+
+func NewProducerDaemon(name string, settings *ProducerDaemonSettings) *producerDaemon {
+	return &producerDaemon{
+		name:     name,
+		clock:    clock.NewRealClock(),
+		settings: settings,
+		buffer:   make(chan *Message, settings.BufferSize),
+		stopped:  make(chan struct{}),
+		batch:    make([]*Message, 0, settings.BatchSize),
+	}
+}
+
+func (d *producerDaemon) Boot(config cfg.Config, logger mon.Logger) error {
+	settings := readProducerSettings(config, d.name)
+
+	logger = logger.WithChannel("producer-daemon").WithFields(mon.Fields{
+		"producer": d.name,
+	})
+
+	defaults := getProducerDaemonDefaultMetrics(d.name)
+	metric := mon.NewMetricDaemonWriter(defaults...)
+	output := NewConfigurableOutput(config, logger, settings.Output)
+
+	return d.BootWithInterfaces(logger, clock.NewRealClock(), metric, output)
+}
+
+func (d *producerDaemon) BootWithInterfaces(logger mon.Logger, clock clock.Clock, metric mon.MetricWriter, output Output) error {
+	d.logger = logger
+	d.clock = clock
+	d.metric = metric
+	d.output = output
+
+	return nil
+}
+
+func (d *producerDaemon) Run(ctx context.Context) error {
+	tick := d.clock.After(d.settings.Interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.drain()
+			return nil
+
+		case msg := <-d.buffer:
+			d.add(ctx, msg)
+
+		case <-tick:
+			d.writeBufferSize()
+			d.flush(ctx)
+			tick = d.clock.After(d.settings.Interval)
+		}
+	}
+}
+
+func (d *producerDaemon) WriteOne(ctx context.Context, msg *Message) error {
+	return d.Write(ctx, []*Message{msg})
+}
+
+func (d *producerDaemon) Write(ctx context.Context, batch []*Message) error {
+	d.lck.RLock()
+	defer d.lck.RUnlock()
+
+	for i, msg := range batch {
+		select {
+		case <-d.stopped:
+			return d.output.Write(ctx, batch[i:])
+		default:
+		}
+
+		select {
+		case d.buffer <- msg:
+		case <-d.stopped:
+			return d.output.Write(ctx, batch[i:])
+		case <-ctx.Done():
+			return fmt.Errorf("can not queue %d messages of producer %s: %w", len(batch)-i, d.name, ctx.Err())
+		}
+	}
+
+	return nil
+}
+
+// drain stops accepting messages into the buffer and writes all queued messages, including the batches
+// whose retries were aborted by the shutdown. Writers blocked on a full buffer fall back to writing to the
+// output directly, so waiting for them can't deadlock.
+func (d *producerDaemon) drain() {
+	close(d.stopped)
+
+	d.lck.Lock()
+	defer d.lck.Unlock()
+
+	ctx := context.Background()
+	aborted := d.aborted
+	d.aborted = nil
+
+	for _, batch := range aborted {
+		d.writeBatch(ctx, batch)
+	}
+
+	for {
+		select {
+		case msg := <-d.buffer:
+			d.add(ctx, msg)
+		default:
+			d.flush(ctx)
+			return
+		}
+	}
+}
+
+func (d *producerDaemon) add(ctx context.Context, msg *Message) {
+	size := len(msg.Body)
+
+	if d.settings.BatchMaxBytes > 0 && len(d.batch) > 0 && d.bytes+size > d.settings.BatchMaxBytes {
+		d.flush(ctx)
+	}
+
+	d.batch = append(d.batch, msg)
+	d.bytes += size
+
+	if len(d.batch) >= d.settings.BatchSize {
+		d.flush(ctx)
+	}
+}
+
+func (d *producerDaemon) flush(ctx context.Context) {
+	if len(d.batch) == 0 {
+		return
+	}
+
+	batch := d.batch
+	d.batch = make([]*Message, 0, d.settings.BatchSize)
+	d.bytes = 0
+
+	d.writeBatch(ctx, batch)
+}
+
+func (d *producerDaemon) writeBatch(ctx context.Context, batch []*Message) {
+	start := d.clock.Now()
+	err := d.write(ctx, batch)
+
+	d.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: MetricNameProducerDaemonFlushLatency,
+		Dimensions: map[string]string{
+			"Producer": d.name,
+		},
+		Unit:  mon.UnitMilliseconds,
+		Value: float64(d.clock.Now().Sub(start).Milliseconds()),
+	})
+
+	if err != nil && ctx.Err() != nil {
+		d.aborted = append(d.aborted, batch)
+		return
+	}
+
+	if err != nil {
+		d.logger.Errorf(err, "can not write %d messages of producer %s to the output", len(batch), d.name)
+	}
+}
+
+func (d *producerDaemon) write(ctx context.Context, batch []*Message) error {
+	backoffConfig := backoff.WithContext(exec.NewExponentialBackOff(&d.settings.Backoff), ctx)
+
+	return backoff.RetryNotify(func() error {
+		return d.output.Write(ctx, batch)
+	}, backoffConfig, func(err error, duration time.Duration) {
+		d.logger.Warnf("can not write %d messages of producer %s, retrying in %s: %s", len(batch), d.name, duration, err.Error())
+	})
+}
+
+func (d *producerDaemon) writeBufferSize() {
+	d.metric.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: MetricNameProducerDaemonBufferSize,
+		Dimensions: map[string]string{
+			"Producer": d.name,
+		},
+		Unit:  mon.UnitCountAverage,
+		Value: float64(len(d.buffer) + len(d.batch)),
+	})
+}
+
+func getProducerDaemonDefaultMetrics(name string) mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNameProducerDaemonBufferSize,
+			Dimensions: map[string]string{
+				"Producer": name,
+			},
+			Unit:  mon.UnitCountAverage,
+			Value: 0.0,
+		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNameProducerDaemonFlushLatency,
+			Dimensions: map[string]string{
+				"Producer": name,
+			},
+			Unit:  mon.UnitMilliseconds,
+			Value: 0.0,
+		},
+	}
+}
@@ -0,0 +1,185 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/clock"
+	"github.com/applike/gosoline/pkg/exec"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+type producerDaemonTestOutput struct {
+	lck      sync.Mutex
+	failures int
+	attempts int
+	batches  [][]string
+}
+
+func (o *producerDaemonTestOutput) WriteOne(ctx context.Context, msg *stream.Message) error {
+	return o.Write(ctx, []*stream.Message{msg})
+}
+
+func (o *producerDaemonTestOutput) Write(_ context.Context, batch []*stream.Message) error {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	o.attempts++
+
+	if o.failures > 0 {
+		o.failures--
+		return fmt.Errorf("write failed")
+	}
+
+	bodies := make([]string, len(batch))
+
+	for i, msg := range batch {
+		bodies[i] = msg.Body
+	}
+
+	o.batches = append(o.batches, bodies)
+
+	return nil
+}
+
+func (o *producerDaemonTestOutput) getAttempts() int {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	return o.attempts
+}
+
+func (o *producerDaemonTestOutput) setFailures(failures int) {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	o.failures = failures
+}
+
+func (o *producerDaemonTestOutput) getBatches() [][]string {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	return o.batches
+}
+
+func newProducerDaemonTestDaemon(settings *stream.ProducerDaemonSettings, output stream.Output) stream.Output {
+	settings.Backoff = exec.BackoffSettings{
+		InitialInterval: time.Millisecond,
+		Multiplier:      1,
+		MaxInterval:     time.Millisecond,
+		MaxElapsedTime:  time.Second,
+	}
+
+	daemon := stream.NewProducerDaemon("test", settings)
+	_ = daemon.BootWithInterfaces(monMocks.NewLoggerMockedAll(), clock.NewRealClock(), monMocks.NewMetricWriterMockedAll(), output)
+
+	return daemon
+}
+
+func TestProducerDaemon_BatchSizeAndDrain(t *testing.T) {
+	output := &producerDaemonTestOutput{}
+	daemon := newProducerDaemonTestDaemon(&stream.ProducerDaemonSettings{
+		Interval:      time.Hour,
+		BufferSize:    10,
+		BatchSize:     3,
+		BatchMaxBytes: 5,
+	}, output)
+
+	err := daemon.Write(context.Background(), []*stream.Message{
+		stream.NewJsonMessage("a"),
+		stream.NewJsonMessage("b"),
+		stream.NewJsonMessage("cccc"),
+		stream.NewJsonMessage("dd"),
+		stream.NewJsonMessage("e"),
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- daemon.(interface{ Run(context.Context) error }).Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(output.getBatches()) == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// "cccc" and "dd" would both exceed the byte limit of the batch before, "dd" and "e" are drained on shutdown
+	assert.Equal(t, [][]string{{"a", "b"}, {"cccc"}, {"dd", "e"}}, output.getBatches())
+
+	err = daemon.WriteOne(context.Background(), stream.NewJsonMessage("late"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"late"}, output.getBatches()[3], "messages after the shutdown should be written directly")
+}
+
+func TestProducerDaemon_IntervalAndRetry(t *testing.T) {
+	output := &producerDaemonTestOutput{
+		failures: 2,
+	}
+	daemon := newProducerDaemonTestDaemon(&stream.ProducerDaemonSettings{
+		Interval:   10 * time.Millisecond,
+		BufferSize: 10,
+		BatchSize:  10,
+	}, output)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- daemon.(interface{ Run(context.Context) error }).Run(ctx)
+	}()
+
+	err := daemon.WriteOne(context.Background(), stream.NewJsonMessage("a"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(output.getBatches()) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, [][]string{{"a"}}, output.getBatches())
+}
+
+func TestProducerDaemon_RetryOnShutdown(t *testing.T) {
+	output := &producerDaemonTestOutput{
+		failures: math.MaxInt32,
+	}
+	daemon := newProducerDaemonTestDaemon(&stream.ProducerDaemonSettings{
+		Interval:   10 * time.Millisecond,
+		BufferSize: 10,
+		BatchSize:  10,
+	}, output)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- daemon.(interface{ Run(context.Context) error }).Run(ctx)
+	}()
+
+	err := daemon.WriteOne(context.Background(), stream.NewJsonMessage("a"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return output.getAttempts() > 2
+	}, time.Second, time.Millisecond)
+
+	// the retries of the batch are aborted by the shutdown, so the batch has to be written by the drain
+	cancel()
+	output.setFailures(0)
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, [][]string{{"a"}}, output.getBatches())
+}
@@ -0,0 +1,414 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/ddb"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/mdl"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/redis"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	baseRedis "github.com/go-redis/redis"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SchedulerBackendDdb   = "ddb"
+	SchedulerBackendRedis = "redis"
+)
+
+// A ScheduledMessage is a message which is stored by a scheduler until it is due.
+type ScheduledMessage struct {
+	Id        string
+	DeliverAt time.Time
+	Message   *Message
+	// shard is the hash key of the ddb item the message was read from
+	shard string
+}
+
+// A SchedulerStore persists the pending messages of a scheduler, so they survive restarts.
+//go:generate mockery -name SchedulerStore
+type SchedulerStore interface {
+	Add(ctx context.Context, msg *ScheduledMessage) error
+	// Due returns up to limit messages which are due until the given time, ordered by their delivery time
+	Due(ctx context.Context, until time.Time, limit int) ([]*ScheduledMessage, error)
+	// Claim claims the messages for the lease and returns the ones which weren't claimed by someone else before
+	Claim(ctx context.Context, msgs []*ScheduledMessage, now time.Time, lease time.Duration) ([]*ScheduledMessage, error)
+	Remove(ctx context.Context, msgs []*ScheduledMessage) error
+}
+
+type SchedulerStoreSettings struct {
+	Backend string `cfg:"backend" default:"redis" validate:"oneof=redis ddb"`
+	// Redis is the name of the redis client of the redis backend
+	Redis string `cfg:"redis" default:"default"`
+	// DdbShards is the number of hash keys the messages of the ddb backend are spread over
+	DdbShards int `cfg:"ddb_shards" default:"16" validate:"min=1"`
+}
+
+func NewSchedulerStore(config cfg.Config, logger mon.Logger, name string, settings *SchedulerStoreSettings) SchedulerStore {
+	if settings.Backend == SchedulerBackendDdb {
+		return NewSchedulerDdbStore(config, logger, name, settings.DdbShards)
+	}
+
+	return NewSchedulerRedisStore(config, logger, name, settings.Redis)
+}
+
+// schedulerRedisStore keeps the ids of the messages in a sorted set scored by their delivery time and the
+// messages themselves in a hash. A claim is a key per message expiring with the lease.
+type schedulerRedisStore struct {
+	client      redis.Client
+	setKey      string
+	messagesKey string
+}
+
+func NewSchedulerRedisStore(config cfg.Config, logger mon.Logger, name string, redisName string) SchedulerStore {
+	client := redis.ProvideClient(config, logger, redisName)
+	appId := cfg.GetAppIdFromConfig(config)
+
+	return NewSchedulerRedisStoreWithInterfaces(client, appId, name)
+}
+
+func NewSchedulerRedisStoreWithInterfaces(client redis.Client, appId cfg.AppId, name string) SchedulerStore {
+	key := redis.GetFullyQualifiedKey(appId, fmt.Sprintf("stream-scheduler-%s", name))
+
+	return &schedulerRedisStore{
+		client:      client,
+		setKey:      key,
+		messagesKey: fmt.Sprintf("%s-messages", key),
+	}
+}
+
+func (s *schedulerRedisStore) Add(_ context.Context, msg *ScheduledMessage) error {
+	body, err := json.Marshal(msg.Message)
+
+	if err != nil {
+		return fmt.Errorf("can not marshal the message: %w", err)
+	}
+
+	// the message and its id are stored in a transaction, so a failed write doesn't leave a message without an id
+	pipe := s.client.TxPipeline()
+	pipe.HSet(s.messagesKey, msg.Id, string(body))
+	pipe.ZAdd(s.setKey, baseRedis.Z{
+		Score:  float64(msg.DeliverAt.Unix()),
+		Member: msg.Id,
+	})
+
+	if _, err := pipe.Exec(); err != nil {
+		return fmt.Errorf("can not schedule the message %s: %w", msg.Id, err)
+	}
+
+	return nil
+}
+
+func (s *schedulerRedisStore) Due(_ context.Context, until time.Time, limit int) ([]*ScheduledMessage, error) {
+	ids, err := s.client.ZRangeByScore(s.setKey, "-inf", strconv.FormatInt(until.Unix(), 10), int64(limit))
+
+	if err != nil {
+		return nil, fmt.Errorf("can not get the due messages: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	bodies, err := s.client.HMGet(s.messagesKey, ids...)
+
+	if err != nil {
+		return nil, fmt.Errorf("can not get the due messages: %w", err)
+	}
+
+	msgs := make([]*ScheduledMessage, 0, len(ids))
+	orphans := make([]interface{}, 0)
+
+	for i, id := range ids {
+		body, ok := bodies[i].(string)
+
+		if !ok {
+			orphans = append(orphans, id)
+			continue
+		}
+
+		msg := &Message{}
+
+		if err := json.Unmarshal([]byte(body), msg); err != nil {
+			return nil, fmt.Errorf("can not unmarshal the message %s: %w", id, err)
+		}
+
+		deliverAt, _ := msg.GetDeliverAt()
+
+		msgs = append(msgs, &ScheduledMessage{
+			Id:        id,
+			DeliverAt: deliverAt,
+			Message:   msg,
+		})
+	}
+
+	// ids without a message were removed partially and would block the messages due after them
+	if len(orphans) > 0 {
+		if _, err := s.client.ZRem(s.setKey, orphans...); err != nil {
+			return nil, fmt.Errorf("can not unschedule the removed messages: %w", err)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (s *schedulerRedisStore) Claim(_ context.Context, msgs []*ScheduledMessage, _ time.Time, lease time.Duration) ([]*ScheduledMessage, error) {
+	claimed := make([]*ScheduledMessage, 0, len(msgs))
+
+	for _, msg := range msgs {
+		ok, err := s.client.SetNX(s.claimKey(msg.Id), "1", lease)
+
+		if err != nil {
+			return nil, fmt.Errorf("can not claim the message %s: %w", msg.Id, err)
+		}
+
+		if ok {
+			claimed = append(claimed, msg)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (s *schedulerRedisStore) Remove(_ context.Context, msgs []*ScheduledMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(msgs))
+	fields := make([]string, len(msgs))
+	claimKeys := make([]string, len(msgs))
+
+	for i, msg := range msgs {
+		members[i] = msg.Id
+		fields[i] = msg.Id
+		claimKeys[i] = s.claimKey(msg.Id)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.ZRem(s.setKey, members...)
+	pipe.HDel(s.messagesKey, fields...)
+	pipe.Del(claimKeys...)
+
+	if _, err := pipe.Exec(); err != nil {
+		return fmt.Errorf("can not delete the messages: %w", err)
+	}
+
+	return nil
+}
+
+func (s *schedulerRedisStore) claimKey(id string) string {
+	return fmt.Sprintf("%s-claim-%s", s.setKey, id)
+}
+
+// schedulerDdbItem is a pending message of a scheduler. The messages of a scheduler are spread over a number of
+// shards by their id, so a busy scheduler doesn't write to a single partition. The range key starts with the
+// zero padded delivery time, so the messages of a shard sort by their delivery time. ClaimedUntil is the unix time
+// the lease of the instance releasing the message ends.
+type schedulerDdbItem struct {
+	Scheduler    string `json:"scheduler" ddb:"key=hash"`
+	Key          string `json:"key" ddb:"key=range"`
+	Message      string `json:"message"`
+	ClaimedUntil int64  `json:"claimedUntil,omitempty"`
+}
+
+type schedulerDdbStore struct {
+	repository ddb.Repository
+	name       string
+	shards     int
+}
+
+func NewSchedulerDdbStore(config cfg.Config, logger mon.Logger, name string, shards int) SchedulerStore {
+	appId := cfg.GetAppIdFromConfig(config)
+
+	repository := ddb.NewRepository(config, logger, &ddb.Settings{
+		ModelId: mdl.ModelId{
+			Project:     appId.Project,
+			Environment: appId.Environment,
+			Family:      appId.Family,
+			Application: appId.Application,
+			Name:        "stream-scheduler",
+		},
+		Main: ddb.MainSettings{
+			Model:              schedulerDdbItem{},
+			ReadCapacityUnits:  5,
+			WriteCapacityUnits: 5,
+		},
+	})
+
+	return NewSchedulerDdbStoreWithInterfaces(repository, name, shards)
+}
+
+func NewSchedulerDdbStoreWithInterfaces(repository ddb.Repository, name string, shards int) SchedulerStore {
+	return &schedulerDdbStore{
+		repository: repository,
+		name:       name,
+		shards:     shards,
+	}
+}
+
+func (s *schedulerDdbStore) Add(ctx context.Context, msg *ScheduledMessage) error {
+	body, err := json.Marshal(msg.Message)
+
+	if err != nil {
+		return fmt.Errorf("can not marshal the message: %w", err)
+	}
+
+	item := &schedulerDdbItem{
+		Scheduler: s.shard(msg.Id),
+		Key:       schedulerDdbKey(msg.DeliverAt, msg.Id),
+		Message:   string(body),
+	}
+
+	if _, err := s.repository.PutItem(ctx, nil, item); err != nil {
+		return fmt.Errorf("can not store the message %s: %w", msg.Id, err)
+	}
+
+	return nil
+}
+
+// Due queries the due messages of every shard and returns the ones due first.
+func (s *schedulerDdbStore) Due(ctx context.Context, until time.Time, limit int) ([]*ScheduledMessage, error) {
+	items := make([]*schedulerDdbItem, 0)
+
+	for i := 0; i < s.shards; i++ {
+		shardItems := make([]*schedulerDdbItem, 0)
+
+		qb := s.repository.QueryBuilder().
+			WithHash(s.shardName(i)).
+			WithRangeLt(schedulerDdbKey(until.Add(time.Second), "")).
+			WithLimit(limit)
+
+		if _, err := s.repository.Query(ctx, qb, &shardItems); err != nil {
+			return nil, fmt.Errorf("can not get the due messages: %w", err)
+		}
+
+		items = append(items, shardItems...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	msgs := make([]*ScheduledMessage, 0, len(items))
+
+	for _, item := range items {
+		msg := &Message{}
+
+		if err := json.Unmarshal([]byte(item.Message), msg); err != nil {
+			return nil, fmt.Errorf("can not unmarshal the message %s: %w", item.Key, err)
+		}
+
+		seconds, id := parseSchedulerDdbKey(item.Key)
+
+		msgs = append(msgs, &ScheduledMessage{
+			Id:        id,
+			DeliverAt: time.Unix(seconds, 0),
+			Message:   msg,
+			shard:     item.Scheduler,
+		})
+	}
+
+	return msgs, nil
+}
+
+// Claim sets the end of the lease of every message which isn't claimed or whose lease ended already. The
+// condition fails for the messages claimed by someone else and the ones removed in the meantime.
+func (s *schedulerDdbStore) Claim(ctx context.Context, msgs []*ScheduledMessage, now time.Time, lease time.Duration) ([]*ScheduledMessage, error) {
+	claimed := make([]*ScheduledMessage, 0, len(msgs))
+
+	for _, msg := range msgs {
+		item := s.item(msg)
+
+		unclaimed := expression.Or(
+			expression.AttributeNotExists(expression.Name("claimedUntil")),
+			expression.Name("claimedUntil").LessThanEqual(expression.Value(now.Unix())),
+		)
+		cond := expression.AttributeExists(expression.Name("key")).And(unclaimed)
+
+		ub := s.repository.UpdateItemBuilder().
+			Set("claimedUntil", now.Add(lease).Unix()).
+			WithCondition(cond)
+
+		result, err := s.repository.UpdateItem(ctx, ub, item)
+
+		if err != nil {
+			return nil, fmt.Errorf("can not claim the message %s: %w", msg.Id, err)
+		}
+
+		if !result.ConditionalCheckFailed {
+			claimed = append(claimed, msg)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (s *schedulerDdbStore) Remove(ctx context.Context, msgs []*ScheduledMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	items := make([]*schedulerDdbItem, len(msgs))
+
+	for i, msg := range msgs {
+		items[i] = s.item(msg)
+	}
+
+	if _, err := s.repository.BatchDeleteItems(ctx, items); err != nil {
+		return fmt.Errorf("can not delete the messages: %w", err)
+	}
+
+	return nil
+}
+
+// item returns the ddb item of the message containing only its key.
+func (s *schedulerDdbStore) item(msg *ScheduledMessage) *schedulerDdbItem {
+	shard := msg.shard
+
+	if shard == "" {
+		shard = s.shard(msg.Id)
+	}
+
+	return &schedulerDdbItem{
+		Scheduler: shard,
+		Key:       schedulerDdbKey(msg.DeliverAt, msg.Id),
+	}
+}
+
+func (s *schedulerDdbStore) shard(id string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+
+	return s.shardName(int(hash.Sum32() % uint32(s.shards)))
+}
+
+func (s *schedulerDdbStore) shardName(shard int) string {
+	return fmt.Sprintf("%s-%d", s.name, shard)
+}
+
+func schedulerDdbKey(deliverAt time.Time, id string) string {
+	return fmt.Sprintf("%020d-%s", deliverAt.Unix(), id)
+}
+
+func parseSchedulerDdbKey(key string) (int64, string) {
+	parts := strings.SplitN(key, "-", 2)
+	seconds, _ := strconv.ParseInt(parts[0], 10, 64)
+
+	if len(parts) < 2 {
+		return seconds, ""
+	}
+
+	return seconds, parts[1]
+}
@@ -0,0 +1,172 @@
+package stream
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const AttributeSchemaId = "schemaId"
+
+type Schema struct {
+	// Id identifies the version of a schema, e.g. order/2
+	Id         string
+	Subject    string
+	Version    int
+	Definition string
+}
+
+// A SchemaSubject provides the subject its schema is registered with in the schema registry.
+// Types without a subject are looked up by their type name.
+type SchemaSubject interface {
+	GetSchemaSubject() string
+}
+
+//go:generate mockery -name SchemaRegistry
+type SchemaRegistry interface {
+	GetSchema(id string) (*Schema, error)
+	GetLatestSchema(subject string) (*Schema, error)
+}
+
+var schemaRegistry SchemaRegistry
+
+// SetSchemaRegistry sets the registry used by the schema aware message body encodings like avro and protobuf.
+func SetSchemaRegistry(registry SchemaRegistry) {
+	schemaRegistry = registry
+}
+
+func getSchemaRegistry() (SchemaRegistry, error) {
+	if schemaRegistry == nil {
+		return nil, fmt.Errorf("there is no schema registry, it has to be set with SetSchemaRegistry")
+	}
+
+	return schemaRegistry, nil
+}
+
+func getSchemaSubject(data interface{}) string {
+	if subject, ok := data.(SchemaSubject); ok {
+		return subject.GetSchemaSubject()
+	}
+
+	typ := reflect.TypeOf(data)
+
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == nil {
+		return ""
+	}
+
+	return typ.Name()
+}
+
+type fileSchemaRegistry struct {
+	path    string
+	schemas map[string]*Schema
+	latest  map[string]*Schema
+}
+
+// NewFileSchemaRegistry reads the schemas from a directory containing one directory per subject.
+// Every file in a subject directory is a version of the schema and is named after its version, e.g. order/2.avsc
+func NewFileSchemaRegistry(path string) (SchemaRegistry, error) {
+	registry := &fileSchemaRegistry{
+		path:    path,
+		schemas: make(map[string]*Schema),
+		latest:  make(map[string]*Schema),
+	}
+
+	if err := registry.load(); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+func (r *fileSchemaRegistry) load() error {
+	subjects, err := ioutil.ReadDir(r.path)
+
+	if err != nil {
+		return fmt.Errorf("can not read schema directory %s: %w", r.path, err)
+	}
+
+	for _, subject := range subjects {
+		if !subject.IsDir() {
+			continue
+		}
+
+		if err = r.loadSubject(subject.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *fileSchemaRegistry) loadSubject(subject string) error {
+	files, err := ioutil.ReadDir(filepath.Join(r.path, subject))
+
+	if err != nil {
+		return fmt.Errorf("can not read schemas of subject %s: %w", subject, err)
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		version, err := strconv.Atoi(name)
+
+		if err != nil {
+			return fmt.Errorf("the schema file %s of subject %s has to be named after its version: %w", file.Name(), subject, err)
+		}
+
+		definition, err := ioutil.ReadFile(filepath.Join(r.path, subject, file.Name()))
+
+		if err != nil {
+			return fmt.Errorf("can not read schema file %s of subject %s: %w", file.Name(), subject, err)
+		}
+
+		r.add(&Schema{
+			Id:         fmt.Sprintf("%s/%d", subject, version),
+			Subject:    subject,
+			Version:    version,
+			Definition: string(definition),
+		})
+	}
+
+	return nil
+}
+
+func (r *fileSchemaRegistry) add(schema *Schema) {
+	r.schemas[schema.Id] = schema
+
+	if latest, ok := r.latest[schema.Subject]; !ok || latest.Version < schema.Version {
+		r.latest[schema.Subject] = schema
+	}
+}
+
+func (r *fileSchemaRegistry) GetSchema(id string) (*Schema, error) {
+	schema, ok := r.schemas[id]
+
+	if !ok {
+		return nil, fmt.Errorf("there is no schema with id %s in %s: %w", id, r.path, os.ErrNotExist)
+	}
+
+	return schema, nil
+}
+
+func (r *fileSchemaRegistry) GetLatestSchema(subject string) (*Schema, error) {
+	schema, ok := r.latest[subject]
+
+	if !ok {
+		return nil, fmt.Errorf("there is no schema for subject %s in %s: %w", subject, r.path, os.ErrNotExist)
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,18 @@
+package tracing
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/mon"
+)
+
+// AddMetricExemplar attaches the trace id of the span in the context to the datum,
+// so the data point can be linked to an example trace.
+func AddMetricExemplar(ctx context.Context, datum *mon.MetricDatum) *mon.MetricDatum {
+	span := GetSpanFromContext(ctx)
+
+	if span == nil {
+		return datum
+	}
+
+	return datum.AddExemplar(span.GetTrace().GetTraceId())
+}